@@ -0,0 +1,48 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"github.com/crowdcompute/crowdengine/log"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	"github.com/crowdcompute/crowdengine/node"
+	"github.com/urfave/cli"
+)
+
+var bootnodeCommand = cli.Command{
+	Name:     "bootnode",
+	Usage:    "Run a bootnode, a well-known entry point of the network",
+	Category: "Network",
+	Description: `
+					Run only the libp2p host, the DHT and the circuit relay, so that other nodes can join the network
+					and the nodes behind NAT can be reached. A bootnode runs no jobs and doesn't need Docker,
+					and its RPC API only lists the peers and the stats of the network.`,
+	Action: bootnode,
+}
+
+func bootnode(ctx *cli.Context) error {
+	cfg := config.GetConfig(ctx)
+	// create and start the bootnode
+	if node, err := node.NewBootnode(cfg); err != nil {
+		log.Fatal(err)
+	} else {
+		node.Start(ctx)
+	}
+
+	return nil
+}
@@ -33,12 +33,13 @@ import (
 func DefaultConfig() *GlobalConfig {
 	return &GlobalConfig{
 		Global: Global{
-			LogLevel:     "TRACE",
-			DataDir:      DefaultDataDir(),
-			KeystoreDir:  filepath.Join(DefaultDataDir(), "keystore"),
-			UploadsDir:   filepath.Join(DefaultDataDir(), "uploads"),
-			DatabaseName: "gocc_db",
-			Availability: []string{},
+			LogLevel:           "TRACE",
+			DataDir:            DefaultDataDir(),
+			KeystoreDir:        filepath.Join(DefaultDataDir(), "keystore"),
+			UploadsDir:         filepath.Join(DefaultDataDir(), "uploads"),
+			DatabaseName:       "gocc_db",
+			Availability:       []string{},
+			AvailabilityAction: "none",
 		},
 		Host: Host{
 			MaxContainers:       20,
@@ -46,7 +47,17 @@ func DefaultConfig() *GlobalConfig {
 			GPUPerContainer:     2,
 			MemoryPerContainer:  1024,
 			StoragePerContainer: 2048,
-			DockerSwarm:         DockerSwarm{"127.0.0.1","0.0.0.0", 2377},
+			PidsPerContainer:    1024,
+			JobTimeout:          3600,
+			MaxJobTimeout:       86400,
+			StorageLimit:        "tmpfs",
+			Labels:              []string{},
+			DockerSwarm:         DockerSwarm{"127.0.0.1", "0.0.0.0", 2377},
+			Sandbox: Sandbox{
+				Profile:      "strict",
+				User:         "65534:65534",
+				MaxOpenFiles: 1024,
+			},
 		},
 		RPC: RPC{
 			Enabled:         false,
@@ -72,9 +83,22 @@ func DefaultConfig() *GlobalConfig {
 		P2P: P2P{
 			MaxPeers:           20,
 			ListenPort:         10209,
-			ListenAddress:      "localhost",
+			ListenAddress:      "0.0.0.0",
 			ConnectionTimeout:  40,
 			MinPeersThreashold: 2,
+			MessageFreshness:   120,
+			NonceCacheSize:     1024,
+			DiscoveryMode:      "flood",
+			NetworkName:        "crowdcompute",
+			NATPortMap:         true,
+			AutoRelay:          true,
+			Limits: Limits{
+				RequestRate:    10,
+				RequestBurst:   20,
+				MaxConcurrent:  8,
+				MaxMessageSize: 4 << 20,
+				MaxUploadSize:  4096,
+			},
 			Bootstraper: Bootstraper{
 				BootstrapPeriodic: 120,
 			},
@@ -84,7 +108,7 @@ func DefaultConfig() *GlobalConfig {
 
 // LoadTomlConfig loads the toml file into the config structure
 func LoadTomlConfig(ctx *cli.Context, cfg *GlobalConfig) {
-	conf := ctx.String("config")
+	conf := ctx.GlobalString("config")
 	if conf == "" {
 		log.Fatal("Configuration file not given")
 	}
@@ -113,12 +137,21 @@ func ApplyFlags(ctx *cli.Context, cfg *GlobalConfig) {
 	if ctx.GlobalIsSet(UploadsDirFlag.Name) {
 		cfg.Global.UploadsDir = ctx.GlobalString(UploadsDirFlag.Name)
 	}
+	if ctx.GlobalIsSet(NodeKeyFileFlag.Name) {
+		cfg.Global.NodeKeyFile = ctx.GlobalString(NodeKeyFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(NodeKeyPassphraseFileFlag.Name) {
+		cfg.Global.NodeKeyPassphraseFile = ctx.GlobalString(NodeKeyPassphraseFileFlag.Name)
+	}
 	if ctx.GlobalIsSet(DatabaseNameFlag.Name) {
 		cfg.Global.DatabaseName = ctx.GlobalString(DatabaseNameFlag.Name)
 	}
 	if ctx.GlobalIsSet(AvailabilityFlag.Name) {
 		cfg.Global.Availability = strings.Split(ctx.GlobalString(AvailabilityFlag.Name), ",")
 	}
+	if ctx.GlobalIsSet(AvailabilityActionFlag.Name) {
+		cfg.Global.AvailabilityAction = ctx.GlobalString(AvailabilityActionFlag.Name)
+	}
 
 	// Host
 	if ctx.GlobalIsSet(MaxContainersFlag.Name) {
@@ -136,7 +169,37 @@ func ApplyFlags(ctx *cli.Context, cfg *GlobalConfig) {
 	}
 
 	if ctx.GlobalIsSet(StoragePerContainerFlag.Name) {
-		cfg.Host.MemoryPerContainer = ctx.GlobalInt(StoragePerContainerFlag.Name)
+		cfg.Host.StoragePerContainer = ctx.GlobalInt(StoragePerContainerFlag.Name)
+	}
+	if ctx.GlobalIsSet(PidsPerContainerFlag.Name) {
+		cfg.Host.PidsPerContainer = ctx.GlobalInt(PidsPerContainerFlag.Name)
+	}
+	if ctx.GlobalIsSet(JobTimeoutFlag.Name) {
+		cfg.Host.JobTimeout = ctx.GlobalInt(JobTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxJobTimeoutFlag.Name) {
+		cfg.Host.MaxJobTimeout = ctx.GlobalInt(MaxJobTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(StorageLimitFlag.Name) {
+		cfg.Host.StorageLimit = ctx.GlobalString(StorageLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(SandboxProfileFlag.Name) {
+		cfg.Host.Sandbox.Profile = ctx.GlobalString(SandboxProfileFlag.Name)
+	}
+	if ctx.GlobalIsSet(SandboxUserFlag.Name) {
+		cfg.Host.Sandbox.User = ctx.GlobalString(SandboxUserFlag.Name)
+	}
+	if ctx.GlobalIsSet(SandboxNetworkFlag.Name) {
+		cfg.Host.Sandbox.Network = ctx.GlobalString(SandboxNetworkFlag.Name)
+	}
+	if ctx.GlobalIsSet(SeccompProfileFlag.Name) {
+		cfg.Host.Sandbox.SeccompProfile = ctx.GlobalString(SeccompProfileFlag.Name)
+	}
+	if ctx.GlobalIsSet(LabelsFlag.Name) {
+		cfg.Host.Labels = strings.Split(ctx.GlobalString(LabelsFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(PriceFlag.Name) {
+		cfg.Host.Price = ctx.GlobalUint64(PriceFlag.Name)
 	}
 	if ctx.GlobalIsSet(DockerSwarmAdvertiseAddrFlag.Name) {
 		cfg.Host.DockerSwarm.AdvertiseAddress = ctx.GlobalString(DockerSwarmAdvertiseAddrFlag.Name)
@@ -217,6 +280,14 @@ func ApplyFlags(ctx *cli.Context, cfg *GlobalConfig) {
 		cfg.P2P.ListenAddress = ctx.GlobalString(P2PListenAddrFlag.Name)
 	}
 
+	if ctx.GlobalIsSet(P2PListenAddrsFlag.Name) {
+		cfg.P2P.ListenAddrs = strings.Split(ctx.GlobalString(P2PListenAddrsFlag.Name), ",")
+	}
+
+	if ctx.GlobalIsSet(P2PAnnounceAddrsFlag.Name) {
+		cfg.P2P.AnnounceAddrs = strings.Split(ctx.GlobalString(P2PAnnounceAddrsFlag.Name), ",")
+	}
+
 	if ctx.GlobalIsSet(P2PTimeoutFlag.Name) {
 		cfg.P2P.ConnectionTimeout = ctx.GlobalInt(P2PTimeoutFlag.Name)
 	}
@@ -225,6 +296,70 @@ func ApplyFlags(ctx *cli.Context, cfg *GlobalConfig) {
 		cfg.P2P.MinPeersThreashold = ctx.GlobalInt(P2PMinPeerThreasholdFlag.Name)
 	}
 
+	if ctx.GlobalIsSet(P2PMessageFreshnessFlag.Name) {
+		cfg.P2P.MessageFreshness = ctx.GlobalInt(P2PMessageFreshnessFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PNonceCacheSizeFlag.Name) {
+		cfg.P2P.NonceCacheSize = ctx.GlobalInt(P2PNonceCacheSizeFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PDiscoveryModeFlag.Name) {
+		cfg.P2P.DiscoveryMode = ctx.GlobalString(P2PDiscoveryModeFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PMDNSFlag.Name) {
+		cfg.P2P.MDNS = ctx.GlobalBool(P2PMDNSFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PNetworkNameFlag.Name) {
+		cfg.P2P.NetworkName = ctx.GlobalString(P2PNetworkNameFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PSwarmKeyFileFlag.Name) {
+		cfg.P2P.SwarmKeyFile = ctx.GlobalString(P2PSwarmKeyFileFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PMembershipAdminFlag.Name) {
+		cfg.P2P.MembershipAdmin = ctx.GlobalString(P2PMembershipAdminFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PMembershipFileFlag.Name) {
+		cfg.P2P.MembershipFile = ctx.GlobalString(P2PMembershipFileFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PNATPortMapFlag.Name) {
+		cfg.P2P.NATPortMap = ctx.GlobalBool(P2PNATPortMapFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PAutoRelayFlag.Name) {
+		cfg.P2P.AutoRelay = ctx.GlobalBool(P2PAutoRelayFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PRelayServerFlag.Name) {
+		cfg.P2P.RelayServer = ctx.GlobalBool(P2PRelayServerFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PRequestRateFlag.Name) {
+		cfg.P2P.Limits.RequestRate = ctx.GlobalFloat64(P2PRequestRateFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PRequestBurstFlag.Name) {
+		cfg.P2P.Limits.RequestBurst = ctx.GlobalInt(P2PRequestBurstFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PMaxConcurrentFlag.Name) {
+		cfg.P2P.Limits.MaxConcurrent = ctx.GlobalInt(P2PMaxConcurrentFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PMaxMessageSizeFlag.Name) {
+		cfg.P2P.Limits.MaxMessageSize = ctx.GlobalInt(P2PMaxMessageSizeFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(P2PMaxUploadSizeFlag.Name) {
+		cfg.P2P.Limits.MaxUploadSize = ctx.GlobalInt(P2PMaxUploadSizeFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(P2PBootstraperFlag.Name) {
 		cfg.P2P.Bootstraper.Nodes = strings.Split(ctx.GlobalString(P2PBootstraperFlag.Name), ",")
 	}
@@ -240,7 +375,8 @@ func GetConfig(ctx *cli.Context) *GlobalConfig {
 	// create default config
 	cfg := DefaultConfig()
 	// if config file is given, load it
-	confFile := ctx.String("config")
+	// GlobalString so that the config file is found from subcommands as well
+	confFile := ctx.GlobalString("config")
 	if confFile != "" {
 		LoadTomlConfig(ctx, cfg)
 	}
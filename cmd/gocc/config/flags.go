@@ -48,6 +48,16 @@ var (
 		Value: filepath.Join(DefaultDataDir(), "uploads"),
 		Usage: "Uploads directory",
 	}
+	// NodeKeyFileFlag defines the node's identity key file
+	NodeKeyFileFlag = cli.StringFlag{
+		Name:  "nodekey",
+		Usage: "Node identity key file (default: <datadir>/nodekey)",
+	}
+	// NodeKeyPassphraseFileFlag defines the file with the passphrase of the node's identity key file
+	NodeKeyPassphraseFileFlag = cli.StringFlag{
+		Name:  "nodekeypass",
+		Usage: "File with the passphrase that encrypts the node identity key file",
+	}
 	// DatabaseNameFlag used to store user data
 	DatabaseNameFlag = cli.StringFlag{
 		Name:  "dbname",
@@ -60,6 +70,12 @@ var (
 		Usage: "Availability hours for processing",
 	}
 
+	// AvailabilityActionFlag defines what happens to running containers when an availability window closes
+	AvailabilityActionFlag = cli.StringFlag{
+		Name:  "availabilityaction",
+		Usage: "Action on running containers when an availability window closes (none, pause, stop)",
+	}
+
 	// MaxContainersFlag defines max number of containers
 	MaxContainersFlag = cli.IntFlag{
 		Name:  "maxcontainers",
@@ -90,6 +106,66 @@ var (
 		Usage: "Amount of storage available to a container",
 	}
 
+	// PidsPerContainerFlag defines the maximum number of processes of a container
+	PidsPerContainerFlag = cli.IntFlag{
+		Name:  "containerpids",
+		Usage: "Maximum number of processes of a container",
+	}
+
+	// JobTimeoutFlag defines the default wall-clock time of a job
+	JobTimeoutFlag = cli.IntFlag{
+		Name:  "jobtimeout",
+		Usage: "Wall-clock time of a job in seconds, unless the job asks for another one",
+	}
+
+	// MaxJobTimeoutFlag defines the maximum wall-clock time of a job
+	MaxJobTimeoutFlag = cli.IntFlag{
+		Name:  "maxjobtimeout",
+		Usage: "Maximum wall-clock time a job can ask for in seconds, 0 for no maximum",
+	}
+
+	// StorageLimitFlag defines how the storage of a container is limited
+	StorageLimitFlag = cli.StringFlag{
+		Name:  "storagelimit",
+		Usage: "How the storage of a container is limited: size (needs a storage driver with quotas), tmpfs or none",
+	}
+
+	// SandboxProfileFlag defines the security profile of the containers
+	SandboxProfileFlag = cli.StringFlag{
+		Name:  "sandbox",
+		Usage: "Security profile of the containers of jobs: none, standard or strict",
+	}
+
+	// SandboxUserFlag defines the non-root user of the containers
+	SandboxUserFlag = cli.StringFlag{
+		Name:  "sandboxuser",
+		Usage: "Non-root user[:group] the jobs run as under the strict sandbox profile",
+	}
+
+	// SandboxNetworkFlag defines the Docker network of the containers
+	SandboxNetworkFlag = cli.StringFlag{
+		Name:  "sandboxnetwork",
+		Usage: "Docker network of the containers of jobs, none to disable the network",
+	}
+
+	// SeccompProfileFlag defines the seccomp profile of the containers
+	SeccompProfileFlag = cli.StringFlag{
+		Name:  "seccompprofile",
+		Usage: "Seccomp profile file of the containers of jobs, Docker's default profile if not given",
+	}
+
+	// LabelsFlag defines the labels of the node
+	LabelsFlag = cli.StringFlag{
+		Name:  "labels",
+		Usage: "Comma separated key=value labels advertised to the network",
+	}
+
+	// PriceFlag defines the price of a container
+	PriceFlag = cli.Uint64Flag{
+		Name:  "price",
+		Usage: "Price of a container per hour",
+	}
+
 	// DockerSwarmAdvertiseAddrFlag defines the docker swarm's advertise address
 	DockerSwarmAdvertiseAddrFlag = cli.StringFlag{
 		Name:  "swarmadvertiseaddr",
@@ -204,6 +280,18 @@ var (
 		Usage: "P2P listening interface",
 	}
 
+	// P2PListenAddrsFlag p2p listen multiaddrs
+	P2PListenAddrsFlag = cli.StringFlag{
+		Name:  "listen",
+		Usage: "P2P listen multiaddrs separated by commas, e.g. /ip4/0.0.0.0/tcp/10209,/ip6/::/tcp/10209,/ip4/0.0.0.0/tcp/10210/ws. Overrides --addr and --port",
+	}
+
+	// P2PAnnounceAddrsFlag p2p announce multiaddrs
+	P2PAnnounceAddrsFlag = cli.StringFlag{
+		Name:  "announce",
+		Usage: "P2P multiaddrs announced to other nodes instead of the listen addresses, separated by commas",
+	}
+
 	// P2PTimeoutFlag p2p connection timeout
 	P2PTimeoutFlag = cli.IntFlag{
 		Name:  "timeout",
@@ -216,6 +304,102 @@ var (
 		Usage: "Minimum number of peers to start periodic bootstraper",
 	}
 
+	// P2PMessageFreshnessFlag p2p message freshness window
+	P2PMessageFreshnessFlag = cli.IntFlag{
+		Name:  "msgfreshness",
+		Usage: "Maximum age of received p2p messages in seconds",
+	}
+
+	// P2PNonceCacheSizeFlag p2p seen message IDs per peer
+	P2PNonceCacheSizeFlag = cli.IntFlag{
+		Name:  "noncecache",
+		Usage: "Number of seen p2p message IDs to remember per peer",
+	}
+
+	// P2PDiscoveryModeFlag the way discovery requests are spread
+	P2PDiscoveryModeFlag = cli.StringFlag{
+		Name:  "discoverymode",
+		Usage: "How discovery requests are spread to the network (flood, gossipsub)",
+	}
+
+	// P2PMDNSFlag enables the local network discovery
+	P2PMDNSFlag = cli.BoolFlag{
+		Name:  "mdns",
+		Usage: "Discover the nodes of the local network through mDNS",
+	}
+
+	// P2PNetworkNameFlag the network the node belongs to
+	P2PNetworkNameFlag = cli.StringFlag{
+		Name:  "networkname",
+		Usage: "Name of the network in letters, digits and hyphens, only nodes of the same network are discovered through mDNS",
+	}
+
+	// P2PSwarmKeyFileFlag the pre-shared key of a private network
+	P2PSwarmKeyFileFlag = cli.StringFlag{
+		Name:  "swarmkey",
+		Usage: "Swarm key file of a private network. Only nodes with the same key can connect",
+	}
+
+	// P2PMembershipAdminFlag the admin of the membership list
+	P2PMembershipAdminFlag = cli.StringFlag{
+		Name:  "membershipadmin",
+		Usage: "Peer ID of the admin signing the membership list. Only members can connect if set",
+	}
+
+	// P2PMembershipFileFlag the signed membership list
+	P2PMembershipFileFlag = cli.StringFlag{
+		Name:  "membership",
+		Usage: "Signed membership list file (default: the \"members\" file of the data directory)",
+	}
+
+	// P2PNATPortMapFlag maps the listen port on the router
+	P2PNATPortMapFlag = cli.BoolTFlag{
+		Name:  "natportmap",
+		Usage: "Map the listen port on the router through UPnP or NAT-PMP",
+	}
+
+	// P2PAutoRelayFlag advertises relay addresses when behind NAT
+	P2PAutoRelayFlag = cli.BoolTFlag{
+		Name:  "autorelay",
+		Usage: "Advertise the addresses of relays when AutoNAT finds the node behind NAT",
+	}
+
+	// P2PRelayServerFlag relays the connections of other nodes
+	P2PRelayServerFlag = cli.BoolFlag{
+		Name:  "relayserver",
+		Usage: "Relay the connections of nodes behind NAT and answer their AutoNAT requests",
+	}
+
+	// P2PRequestRateFlag the messages per second a peer can send
+	P2PRequestRateFlag = cli.Float64Flag{
+		Name:  "requestrate",
+		Usage: "Messages per second a peer can send to each protocol",
+	}
+
+	// P2PRequestBurstFlag the messages a peer can send at once
+	P2PRequestBurstFlag = cli.IntFlag{
+		Name:  "requestburst",
+		Usage: "Messages a peer can send at once to each protocol over the request rate",
+	}
+
+	// P2PMaxConcurrentFlag the messages of a peer handled at the same time
+	P2PMaxConcurrentFlag = cli.IntFlag{
+		Name:  "maxconcurrent",
+		Usage: "Messages of a peer each protocol handles at the same time",
+	}
+
+	// P2PMaxMessageSizeFlag the maximum size of a message
+	P2PMaxMessageSizeFlag = cli.IntFlag{
+		Name:  "maxmsgsize",
+		Usage: "Maximum size of a p2p message in bytes",
+	}
+
+	// P2PMaxUploadSizeFlag the maximum size of an uploaded image
+	P2PMaxUploadSizeFlag = cli.IntFlag{
+		Name:  "maxuploadsize",
+		Usage: "Maximum size of an image uploaded by a peer in MB",
+	}
+
 	// P2PBootstraperFlag nodes to bootstrap
 	P2PBootstraperFlag = cli.StringFlag{
 		Name:  "bootstrapnodes",
@@ -238,13 +422,26 @@ var GOCCAppFlags = []cli.Flag{
 	LogLevelFlag,
 	DataDirFlag,
 	KeystoreDirFlag,
+	NodeKeyFileFlag,
+	NodeKeyPassphraseFileFlag,
 	DatabaseNameFlag,
 	AvailabilityFlag,
+	AvailabilityActionFlag,
 	MaxContainersFlag,
 	CPUPerContainerFlag,
 	GPUPerContainerFlag,
 	MemoryPerContainerFlag,
 	StoragePerContainerFlag,
+	PidsPerContainerFlag,
+	JobTimeoutFlag,
+	MaxJobTimeoutFlag,
+	StorageLimitFlag,
+	SandboxProfileFlag,
+	SandboxUserFlag,
+	SandboxNetworkFlag,
+	SeccompProfileFlag,
+	LabelsFlag,
+	PriceFlag,
 	RPCFlag,
 	RPCServicesFlag,
 	RPCWhitelistFlag,
@@ -261,8 +458,26 @@ var GOCCAppFlags = []cli.Flag{
 	MaxPeersFlag,
 	P2PListenPortFlag,
 	P2PListenAddrFlag,
+	P2PListenAddrsFlag,
+	P2PAnnounceAddrsFlag,
 	P2PTimeoutFlag,
 	P2PMinPeerThreasholdFlag,
+	P2PMessageFreshnessFlag,
+	P2PNonceCacheSizeFlag,
+	P2PDiscoveryModeFlag,
+	P2PMDNSFlag,
+	P2PNetworkNameFlag,
+	P2PSwarmKeyFileFlag,
+	P2PMembershipAdminFlag,
+	P2PMembershipFileFlag,
+	P2PNATPortMapFlag,
+	P2PAutoRelayFlag,
+	P2PRelayServerFlag,
+	P2PRequestRateFlag,
+	P2PRequestBurstFlag,
+	P2PMaxConcurrentFlag,
+	P2PMaxMessageSizeFlag,
+	P2PMaxUploadSizeFlag,
 	P2PBootstraperFlag,
 	P2PPeriodicFlag,
 	DockerSwarmAdvertiseAddrFlag,
@@ -24,6 +24,14 @@ type Global struct {
 	UploadsDir   string
 	DatabaseName string
 	Availability []string
+	// AvailabilityAction is the action on running containers when an availability window closes.
+	// One of "none", "pause" or "stop". Paused containers are resumed when the next window opens.
+	AvailabilityAction string
+	// NodeKeyFile is the node's identity key file. Defaults to the "nodekey" file of the DataDir.
+	NodeKeyFile string
+	// NodeKeyPassphraseFile is a file with the passphrase that encrypts the NodeKeyFile.
+	// The NodeKeyFile is stored unencrypted if it's not given.
+	NodeKeyPassphraseFile string
 }
 
 // Host related configuration
@@ -33,13 +41,40 @@ type Host struct {
 	GPUPerContainer     int
 	MemoryPerContainer  int
 	StoragePerContainer int
+	PidsPerContainer    int      // Maximum number of processes of a container
+	JobTimeout          int      // Wall-clock time of a job in seconds, unless it asks for another one
+	MaxJobTimeout       int      // Maximum wall-clock time a job can ask for in seconds, 0 for no maximum
+	Labels              []string // key=value labels advertised in the node's capability record
+	Price               uint64   // Price of a container per hour
 	DockerSwarm         DockerSwarm
+	// StorageLimit is how the storage of a container is limited: "size" limits its writable layer, which needs
+	// a storage driver with quotas (e.g. overlay2 on xfs with pquota), "tmpfs" mounts a tmpfs of that size
+	// at /tmp instead, "none" doesn't limit it.
+	StorageLimit string
+	Sandbox      Sandbox
 
 	Network struct {
 		IP string
 	}
 }
 
+// Sandbox configures how the containers of jobs are isolated from the node
+type Sandbox struct {
+	// Profile is the security profile of the containers: "none" keeps the Docker defaults, "standard" drops all
+	// capabilities, forbids gaining privileges and applies seccomp and ulimits, "strict" also makes the root
+	// filesystem read-only with a writable tmpfs at /tmp, runs the jobs as a non-root user and disables the network.
+	Profile string
+	// User is the non-root user[:group] the jobs run as under the strict profile, unless a job asks for another one
+	User string
+	// Network is the Docker network of the containers. "none" disables the network, a dedicated network
+	// can allow egress to some hosts only. The strict profile disables the network if it's empty.
+	Network string
+	// SeccompProfile is a seccomp profile file. Docker's default seccomp profile applies if it's empty.
+	SeccompProfile string
+	// MaxOpenFiles is the open files limit of the containers under the standard and strict profiles
+	MaxOpenFiles int
+}
+
 // DockerSwarm configuration.
 type DockerSwarm struct {
 	AdvertiseAddress string
@@ -66,7 +101,43 @@ type P2P struct {
 	ListenAddress      string
 	ConnectionTimeout  int
 	MinPeersThreashold int
-	Bootstraper        Bootstraper
+	MessageFreshness   int // in seconds
+	NonceCacheSize     int
+	DiscoveryMode      string // "flood" or "gossipsub"
+	MDNS               bool   // Discover the nodes of the local network through multicast DNS
+	NetworkName        string // Only nodes of the same network find each other through mDNS
+	// SwarmKeyFile is the pre-shared key of a private network. Only nodes with the same key can connect.
+	SwarmKeyFile string
+	// MembershipAdmin is the peer ID of the admin that signs the membership list of a private network.
+	// If it's set, only the members of the list can connect.
+	MembershipAdmin string
+	// MembershipFile is the signed membership list. Defaults to the "members" file of the DataDir.
+	MembershipFile string
+	// ListenAddrs are the multiaddrs the node listens on, e.g. /ip6/::/tcp/10209 or /ip4/0.0.0.0/tcp/10210/ws.
+	// If it's empty, the node listens on ListenAddress and ListenPort over TCP.
+	ListenAddrs []string
+	// AnnounceAddrs are the multiaddrs announced to the other nodes instead of the listen addresses,
+	// e.g. the public address of a port forward.
+	AnnounceAddrs []string
+	// NATPortMap maps the listen port on the router through UPnP or NAT-PMP, so that nodes behind NAT can be dialed.
+	NATPortMap bool
+	// AutoRelay finds out through AutoNAT if the node is behind NAT and, if so, advertises the addresses of relays found in the DHT.
+	AutoRelay bool
+	// RelayServer relays the connections of nodes behind NAT and answers their AutoNAT requests.
+	// Only well-connected nodes with a public address should enable it.
+	RelayServer bool
+	Limits      Limits
+	Bootstraper Bootstraper
+}
+
+// Limits bound the work a single peer can make the node do.
+// The rate and concurrency limits apply to each protocol separately.
+type Limits struct {
+	RequestRate    float64 // Messages per second a peer can send
+	RequestBurst   int     // Messages a peer can send at once over the RequestRate
+	MaxConcurrent  int     // Messages of a peer handled at the same time
+	MaxMessageSize int     // in bytes
+	MaxUploadSize  int     // in MB
 }
 
 // DomainSocket unix/pipe socket file
@@ -0,0 +1,138 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	"github.com/crowdcompute/crowdengine/p2p"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/urfave/cli"
+)
+
+var (
+	// exportPassphraseFileFlag defines the file with the passphrase that encrypts an exported identity
+	exportPassphraseFileFlag = cli.StringFlag{
+		Name:  "exportpass",
+		Usage: "File with the passphrase that encrypts the exported key. The key is exported unencrypted if not given",
+	}
+
+	identityCommand = cli.Command{
+		Name:     "identity",
+		Usage:    "Manage the node's p2p identity",
+		Category: "Identity",
+		Description: `
+					Manage the node's p2p identity key, stored under the data directory.
+					The identity is the node's peer ID, so it remains the same across restarts.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "show",
+				Usage:  "Show the node's peer ID and public key",
+				Action: showIdentity,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the node's identity key to a file",
+				ArgsUsage: "<file>",
+				Action:    exportIdentity,
+				Flags:     []cli.Flag{exportPassphraseFileFlag},
+			},
+			{
+				Name:   "rotate",
+				Usage:  "Replace the node's identity key with a new one. Takes effect on the next start",
+				Action: rotateIdentity,
+			},
+		},
+	}
+)
+
+// identityFileAndPassphrase returns the node's identity file and its passphrase as configured
+func identityFileAndPassphrase(ctx *cli.Context) (string, string, error) {
+	cfg := config.GetConfig(ctx)
+	keyFile := p2p.IdentityFile(cfg.Global.NodeKeyFile, cfg.Global.DataDir)
+	if keyFile == "" {
+		return "", "", fmt.Errorf("No node identity file or data directory given")
+	}
+	passphrase, err := p2p.ReadPassphraseFile(cfg.Global.NodeKeyPassphraseFile)
+	return keyFile, passphrase, err
+}
+
+func showIdentity(ctx *cli.Context) error {
+	keyFile, passphrase, err := identityFileAndPassphrase(ctx)
+	if err != nil {
+		return err
+	}
+	priv, err := p2p.LoadIdentity(keyFile, passphrase)
+	if err != nil {
+		return err
+	}
+	return printIdentity(keyFile, priv)
+}
+
+func exportIdentity(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("Please give the file to export the identity to")
+	}
+	keyFile, passphrase, err := identityFileAndPassphrase(ctx)
+	if err != nil {
+		return err
+	}
+	priv, err := p2p.LoadIdentity(keyFile, passphrase)
+	if err != nil {
+		return err
+	}
+	exportPassphrase, err := p2p.ReadPassphraseFile(ctx.String(exportPassphraseFileFlag.Name))
+	if err != nil {
+		return err
+	}
+	if err := p2p.StoreIdentity(ctx.Args().First(), priv, exportPassphrase); err != nil {
+		return err
+	}
+	fmt.Println("Identity exported to: ", ctx.Args().First())
+	return nil
+}
+
+func rotateIdentity(ctx *cli.Context) error {
+	keyFile, passphrase, err := identityFileAndPassphrase(ctx)
+	if err != nil {
+		return err
+	}
+	priv, backup, err := p2p.RotateIdentity(keyFile, passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Previous identity backed up to: ", backup)
+	return printIdentity(keyFile, priv)
+}
+
+func printIdentity(keyFile string, priv crypto.PrivKey) error {
+	id, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		return err
+	}
+	pubBytes, err := priv.GetPublic().Bytes()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Identity file: ", keyFile)
+	fmt.Println("Peer ID:       ", id.Pretty())
+	fmt.Println("Public key:    ", hex.EncodeToString(pubBytes))
+	return nil
+}
@@ -45,6 +45,12 @@ func init() {
 	App.Action = gocc
 	App.Version = Version
 	App.Flags = config.GOCCAppFlags
+	App.Commands = []cli.Command{
+		bootnodeCommand,
+		identityCommand,
+		membersCommand,
+		swarmKeyCommand,
+	}
 	sort.Sort(cli.CommandsByName(App.Commands))
 	App.After = func(ctx *cli.Context) error {
 		// debug.Exit()
@@ -0,0 +1,180 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	"github.com/crowdcompute/crowdengine/p2p"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/urfave/cli"
+)
+
+var (
+	membersCommand = cli.Command{
+		Name:     "members",
+		Usage:    "Manage the members of a private network",
+		Category: "Private network",
+		Description: `
+					Manage the membership list of a private network, signed by the network's admin.
+					Only the node whose identity is the configured membership admin can add or remove members.
+					Changes take effect on the next start, running nodes are updated through the membership RPC API.`,
+		Subcommands: []cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List the members of the network",
+				Action: listMembers,
+			},
+			{
+				Name:      "add",
+				Usage:     "Add peers to the members of the network",
+				ArgsUsage: "<peerID> [<peerID>...]",
+				Action:    addMembers,
+			},
+			{
+				Name:      "remove",
+				Usage:     "Remove peers from the members of the network",
+				ArgsUsage: "<peerID> [<peerID>...]",
+				Action:    removeMembers,
+			},
+		},
+	}
+
+	swarmKeyCommand = cli.Command{
+		Name:      "swarmkey",
+		Usage:     "Generate the pre-shared key of a new private network",
+		Category:  "Private network",
+		ArgsUsage: "<file>",
+		Description: `
+					Generate a new swarm key file. Nodes started with the same swarm key form a private network
+					that no other node can connect to.`,
+		Action: generateSwarmKey,
+	}
+)
+
+// membershipFileAndAdmin returns the membership list file and the admin's peer ID as configured
+func membershipFileAndAdmin(ctx *cli.Context) (string, peer.ID, error) {
+	cfg := config.GetConfig(ctx)
+	if cfg.P2P.MembershipAdmin == "" {
+		return "", "", fmt.Errorf("No membership admin given")
+	}
+	admin, err := peer.IDB58Decode(cfg.P2P.MembershipAdmin)
+	if err != nil {
+		return "", "", err
+	}
+	file := p2p.MembershipFile(cfg.P2P.MembershipFile, cfg.Global.DataDir)
+	if file == "" {
+		return "", "", fmt.Errorf("No membership file or data directory given")
+	}
+	return file, admin, nil
+}
+
+// loadMembershipList loads and verifies the membership list. A missing file is an empty list.
+func loadMembershipList(file string, admin peer.ID) (*api.MembershipList, error) {
+	list, err := p2p.LoadMembershipList(file)
+	if os.IsNotExist(err) {
+		return &api.MembershipList{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return list, p2p.VerifyMembershipList(list, admin)
+}
+
+func listMembers(ctx *cli.Context) error {
+	file, admin, err := membershipFileAndAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	list, err := loadMembershipList(file, admin)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Admin: ", admin.Pretty())
+	for _, member := range list.Members {
+		fmt.Println(member)
+	}
+	return nil
+}
+
+func addMembers(ctx *cli.Context) error {
+	return editMembers(ctx, true)
+}
+
+func removeMembers(ctx *cli.Context) error {
+	return editMembers(ctx, false)
+}
+
+func editMembers(ctx *cli.Context, add bool) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("Please give the peer IDs of the members")
+	}
+	peers := make([]peer.ID, 0, ctx.NArg())
+	for _, arg := range ctx.Args() {
+		pID, err := peer.IDB58Decode(arg)
+		if err != nil {
+			return err
+		}
+		peers = append(peers, pID)
+	}
+	file, admin, err := membershipFileAndAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	list, err := loadMembershipList(file, admin)
+	if err != nil {
+		return err
+	}
+	keyFile, passphrase, err := identityFileAndPassphrase(ctx)
+	if err != nil {
+		return err
+	}
+	priv, err := p2p.LoadIdentity(keyFile, passphrase)
+	if err != nil {
+		return err
+	}
+	if add {
+		list, err = p2p.EditMembershipList(list, priv, admin, peers, nil)
+	} else {
+		list, err = p2p.EditMembershipList(list, priv, admin, nil, peers)
+	}
+	if err != nil {
+		return err
+	}
+	if err := p2p.StoreMembershipList(file, list); err != nil {
+		return err
+	}
+	fmt.Printf("The network has %d members. Membership list stored to: %s\n", len(list.Members), file)
+	return nil
+}
+
+func generateSwarmKey(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("Please give the file to store the swarm key to")
+	}
+	if _, err := os.Stat(ctx.Args().First()); err == nil {
+		return fmt.Errorf("The file %s already exists", ctx.Args().First())
+	}
+	if err := p2p.GenerateSwarmKey(ctx.Args().First()); err != nil {
+		return err
+	}
+	fmt.Println("Swarm key stored to: ", ctx.Args().First())
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Availability represents the daily time windows a node is available for processing.
+// A node with no windows is always available.
+type Availability struct {
+	windows []dailyWindow
+}
+
+// dailyWindow is a window of a day given as offsets from midnight.
+// An end before the start means that the window spans midnight.
+type dailyWindow struct {
+	start, end time.Duration
+}
+
+// ParseAvailability parses ranges of the "HH:MM-HH:MM" format into an Availability.
+// Empty ranges are ignored. "24:00" is only accepted as the end of a range.
+func ParseAvailability(ranges []string) (*Availability, error) {
+	a := &Availability{}
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		parts := strings.Split(r, "-")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Wrong availability range: %s. Expected HH:MM-HH:MM", r)
+		}
+		start, err := parseClock(parts[0])
+		if err != nil || start == 24*time.Hour {
+			return nil, fmt.Errorf("Wrong start of availability range: %s", r)
+		}
+		end, err := parseClock(parts[1])
+		if err != nil || end == start {
+			return nil, fmt.Errorf("Wrong end of availability range: %s", r)
+		}
+		a.windows = append(a.windows, dailyWindow{start: start, end: end})
+	}
+	return a, nil
+}
+
+// parseClock parses a "HH:MM" clock time into an offset from midnight
+func parseClock(clock string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(clock), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("Wrong clock time: %s", clock)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	if hours < 0 || hours > 24 || minutes < 0 || minutes > 59 || (hours == 24 && minutes != 0) {
+		return 0, fmt.Errorf("Wrong clock time: %s", clock)
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+// Always returns true if the node has no windows, so it's always available
+func (a *Availability) Always() bool {
+	return len(a.windows) == 0
+}
+
+// Available checks whether t falls in one of the windows
+func (a *Availability) Available(t time.Time) bool {
+	return a.AvailableFor(t, 0)
+}
+
+// AvailableFor checks whether t falls in one of the windows and the window stays open for at least d
+func (a *Availability) AvailableFor(t time.Time, d time.Duration) bool {
+	if a.Always() {
+		return true
+	}
+	start, end, _ := a.Window(t)
+	return !start.After(t) && !end.Before(t.Add(d))
+}
+
+// Window returns the window that contains t or, if there is none, the next window to open after t.
+// ok is false when the node is always available.
+func (a *Availability) Window(t time.Time) (start, end time.Time, ok bool) {
+	if a.Always() {
+		return time.Time{}, time.Time{}, false
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	// Windows spanning midnight may have started the day before
+	for day := -1; day <= 1; day++ {
+		dayStart := midnight.AddDate(0, 0, day)
+		for _, w := range a.windows {
+			s := dayStart.Add(w.start)
+			e := dayStart.Add(w.end)
+			if w.end < w.start {
+				e = e.Add(24 * time.Hour)
+			}
+			if !e.After(t) {
+				continue
+			}
+			// An open window always wins over one that opens later
+			if !s.After(t) {
+				return s, e, true
+			}
+			if !ok || s.Before(start) {
+				start, end, ok = s, e, true
+			}
+		}
+	}
+	return start, end, ok
+}
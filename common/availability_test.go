@@ -0,0 +1,79 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func clockAt(hour, min int) time.Time {
+	return time.Date(2019, time.May, 10, hour, min, 0, 0, time.UTC)
+}
+
+// TestParseAvailability checks that only valid ranges get parsed
+func TestParseAvailability(t *testing.T) {
+	_, err := ParseAvailability([]string{"10:00-19:00", " 20:00-24:00", ""})
+	assert.Nil(t, err)
+	for _, wrong := range []string{"10:00", "10-19", "25:00-26:00", "10:60-11:00", "24:00-02:00", "10:00-10:00"} {
+		_, err := ParseAvailability([]string{wrong})
+		assert.NotNil(t, err, wrong)
+	}
+}
+
+// TestAvailable checks whether times fall in the availability windows
+func TestAvailable(t *testing.T) {
+	always, _ := ParseAvailability([]string{})
+	assert.True(t, always.Available(clockAt(3, 0)))
+
+	a, _ := ParseAvailability([]string{"10:00-19:00", "22:00-02:00"})
+	assert.True(t, a.Available(clockAt(10, 0)))
+	assert.True(t, a.Available(clockAt(18, 59)))
+	assert.False(t, a.Available(clockAt(19, 0)))
+	assert.True(t, a.Available(clockAt(23, 0)))
+	assert.True(t, a.Available(clockAt(1, 0)))
+	assert.False(t, a.Available(clockAt(5, 0)))
+
+	assert.True(t, a.AvailableFor(clockAt(17, 0), 2*time.Hour))
+	assert.False(t, a.AvailableFor(clockAt(17, 0), 3*time.Hour))
+}
+
+// TestWindow checks the current and the next availability windows
+func TestWindow(t *testing.T) {
+	a, _ := ParseAvailability([]string{"10:00-19:00", "22:00-02:00"})
+
+	start, end, ok := a.Window(clockAt(12, 0))
+	assert.True(t, ok)
+	assert.Equal(t, clockAt(10, 0), start)
+	assert.Equal(t, clockAt(19, 0), end)
+
+	// The window opened the day before
+	start, end, _ = a.Window(clockAt(1, 0))
+	assert.Equal(t, clockAt(22, 0).AddDate(0, 0, -1), start)
+	assert.Equal(t, clockAt(2, 0), end)
+
+	// Outside of the windows the next one gets returned
+	start, end, _ = a.Window(clockAt(20, 0))
+	assert.Equal(t, clockAt(22, 0), start)
+	assert.Equal(t, clockAt(2, 0).AddDate(0, 0, 1), end)
+
+	always, _ := ParseAvailability(nil)
+	_, _, ok = always.Window(clockAt(12, 0))
+	assert.False(t, ok)
+}
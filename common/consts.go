@@ -34,6 +34,9 @@ const TTLmsg time.Duration = time.Second * 15
 // ':' it's an illegal character for file names under windows and linux
 const FillChar string = ":"
 
+// RequestIDLength represents the total length of the buffer whenever sending a request ID to a peer
+const RequestIDLength int = 36
+
 // FileSizeLength represents the total length of the buffer whenever sending the file size to a peer
 const FileSizeLength int = 10
 
@@ -54,6 +57,30 @@ const RemoveImagesInterval time.Duration = time.Second * 10
 // ContainerCheckInterval represents the time interval to check whether a container has finished running
 const ContainerCheckInterval time.Duration = time.Second * 3
 
+// AvailabilityCheckInterval represents the time interval to check whether an availability window opened or closed
+const AvailabilityCheckInterval time.Duration = time.Second * 30
+
+// ContainerStopTimeout represents the time to wait for a container to stop before killing it
+const ContainerStopTimeout time.Duration = time.Second * 10
+
+// RequestTimeout represents the time to wait for the response of a request sent to a peer
+const RequestTimeout time.Duration = time.Minute
+
+// CapabilityRecordTTL represents the time a capability record published to the DHT stays valid
+const CapabilityRecordTTL time.Duration = time.Hour
+
+// CapabilityRefreshInterval represents the time interval to republish the node's capability record
+const CapabilityRefreshInterval time.Duration = time.Minute * 20
+
+// KnownPeerExpiry represents the time a peer the node hasn't been connected to is kept in the datastore
+const KnownPeerExpiry time.Duration = time.Hour * 24 * 7
+
+// PersistNetworkInterval represents the time interval to store the connected peers and prune the datastore
+const PersistNetworkInterval time.Duration = time.Minute * 5
+
+// ImageLoadTimeout represents the time to wait for a peer to load an uploaded image
+const ImageLoadTimeout time.Duration = time.Minute * 10
+
 // DiscoveryTimeout represents the time to wait for
 const DiscoveryTimeout time.Duration = time.Second * 10
 
@@ -16,7 +16,11 @@
 
 package database
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 // GetImageAccountFromDB returns an ImageAccount if exists in the database
 func GetImageAccountFromDB(hash string) (*ImageAccount, error) {
@@ -56,3 +60,68 @@ func StoreImageToDB(imageID string, hash string, signature string) error {
 	// And because the image ID is the same all the values in DB will be updated with the new ones
 	return GetDB().Model(image).Put([]byte(imageID))
 }
+
+// GetPeerReputationFromDB returns the PeerReputation of a peer if exists in the database
+func GetPeerReputationFromDB(peerID string) (*PeerReputation, error) {
+	rep := &PeerReputation{}
+	r, err := GetDB().Model(rep).Get([]byte(peerID))
+	if err != nil {
+		return nil, err
+	}
+	return r.(*PeerReputation), nil
+}
+
+// StorePeerReputationToDB stores the PeerReputation of a peer to our level DB
+func StorePeerReputationToDB(peerID string, rep *PeerReputation) error {
+	return GetDB().Model(rep).Put([]byte(peerID))
+}
+
+// GetPeerReputationsFromDB returns the PeerReputations of all peers in the database by peer ID
+func GetPeerReputationsFromDB() (map[string]*PeerReputation, error) {
+	model := GetDB().Model(&PeerReputation{})
+	data, err := model.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	reps := make(map[string]*PeerReputation, len(data))
+	for key, value := range data {
+		rep := &PeerReputation{}
+		if err := json.Unmarshal([]byte(value), rep); err != nil {
+			return nil, err
+		}
+		reps[strings.TrimPrefix(key, model.tableName)] = rep
+	}
+	return reps, nil
+}
+
+// GetJobFromDB returns the Job jobID if exists in the database
+func GetJobFromDB(jobID string) (*Job, error) {
+	job := &Job{}
+	j, err := GetDB().Model(job).Get([]byte(jobID))
+	if err != nil {
+		return nil, err
+	}
+	return j.(*Job), nil
+}
+
+// StoreJobToDB stores the Job to our level DB under its ID
+func StoreJobToDB(job *Job) error {
+	return GetDB().Model(job).Put([]byte(job.ID))
+}
+
+// GetJobsFromDB returns all the Jobs in the database
+func GetJobsFromDB() ([]*Job, error) {
+	data, err := GetDB().Model(&Job{}).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(data))
+	for _, value := range data {
+		job := &Job{}
+		if err := json.Unmarshal([]byte(value), job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
@@ -59,3 +59,46 @@ func TestDelete(t *testing.T) {
 		t.Errorf("Got a deleted image")
 	}
 }
+
+func TestPeerReputations(t *testing.T) {
+	assert.NoError(t, StorePeerReputationToDB("peer1", &PeerReputation{JobsSucceeded: 2}))
+	assert.NoError(t, StorePeerReputationToDB("peer2", &PeerReputation{AuthFailures: 1}))
+	rep, err := GetPeerReputationFromDB("peer1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), rep.JobsSucceeded)
+
+	reps, err := GetPeerReputationsFromDB()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), reps["peer2"].AuthFailures)
+	assert.Len(t, reps, 2)
+}
+
+func TestJobStates(t *testing.T) {
+	job := &Job{ID: "job1", State: JobQueued}
+	now := time.Now()
+	assert.Equal(t, ErrInvalidTransition, job.MoveTo(JobSucceeded, now))
+	assert.NoError(t, job.MoveTo(JobLoading, now))
+	assert.NoError(t, job.MoveTo(JobRunning, now))
+	assert.Equal(t, now.Unix(), job.StartedTime)
+	assert.Zero(t, job.FinishedTime)
+	assert.NoError(t, job.MoveTo(JobExpired, now))
+	assert.Equal(t, now.Unix(), job.FinishedTime)
+
+	// Terminal states can't move
+	assert.True(t, job.State.Terminal())
+	assert.Equal(t, ErrInvalidTransition, job.MoveTo(JobRunning, now))
+	assert.False(t, JobRunning.Terminal())
+}
+
+func TestJobs(t *testing.T) {
+	assert.NoError(t, StoreJobToDB(&Job{ID: "job1", Owner: "owner1", State: JobQueued}))
+	assert.NoError(t, StoreJobToDB(&Job{ID: "job2", Owner: "owner2", State: JobRunning}))
+	job, err := GetJobFromDB("job2")
+	assert.NoError(t, err)
+	assert.Equal(t, "owner2", job.Owner)
+	assert.Equal(t, JobRunning, job.State)
+
+	jobs, err := GetJobsFromDB()
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package database
+
+import (
+	"errors"
+	"time"
+)
+
+// JobState is the state of a job in its lifecycle
+type JobState string
+
+const (
+	// JobQueued is the state of a job waiting to run
+	JobQueued JobState = "queued"
+	// JobLoading is the state of a job whose image is pulled or loaded into its container
+	JobLoading JobState = "loading"
+	// JobRunning is the state of a job whose container runs
+	JobRunning JobState = "running"
+	// JobSucceeded is the state of a job whose container exited with code 0
+	JobSucceeded JobState = "succeeded"
+	// JobFailed is the state of a job that couldn't run, or whose container exited with an error
+	JobFailed JobState = "failed"
+	// JobCancelled is the state of a job stopped by its owner
+	JobCancelled JobState = "cancelled"
+	// JobExpired is the state of a job stopped because it ran out of time
+	JobExpired JobState = "expired"
+)
+
+// ErrInvalidTransition is returned when a job can't move from its state to another one
+var ErrInvalidTransition = errors.New("The job can't move to this state")
+
+// jobTransitions are the states each state of a job can move to. Terminal states can't move.
+var jobTransitions = map[JobState][]JobState{
+	JobQueued:  {JobLoading, JobRunning, JobFailed, JobCancelled, JobExpired},
+	JobLoading: {JobRunning, JobFailed, JobCancelled, JobExpired},
+	JobRunning: {JobSucceeded, JobFailed, JobCancelled, JobExpired},
+}
+
+// Terminal returns true if the job can't move from the state s
+func (s JobState) Terminal() bool {
+	_, ok := jobTransitions[s]
+	return !ok
+}
+
+// CanMoveTo returns true if a job can move from the state s to the state to
+func (s JobState) CanMoveTo(to JobState) bool {
+	for _, state := range jobTransitions[s] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
+
+// MoveTo moves the job to the state at the time now, and keeps the times it started and finished
+func (j *Job) MoveTo(state JobState, now time.Time) error {
+	if !j.State.CanMoveTo(state) {
+		return ErrInvalidTransition
+	}
+	j.State = state
+	j.UpdatedTime = now.Unix()
+	if state == JobRunning {
+		j.StartedTime = j.UpdatedTime
+	}
+	if state.Terminal() {
+		j.FinishedTime = j.UpdatedTime
+	}
+	return nil
+}
@@ -19,6 +19,7 @@ package database
 import (
 	"sync"
 
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
 	"github.com/syndtr/goleveldb/leveldb"
 )
 
@@ -65,6 +66,24 @@ type ImageLoadDocker struct {
 	CreatedTime int64    `json:"createdtime"` // The time the image was loaded into the current node's docker engine
 }
 
+// PeerReputation represents the Peer Reputation Model. Keeps track of the outcomes of the interactions with a peer
+// Usage: Every protocol records whether the peer served its requests, so that the node can rank and ban peers
+type PeerReputation struct {
+	JobsSucceeded      uint64  `json:"jobssucceeded"`      // Jobs the peer ran
+	JobsFailed         uint64  `json:"jobsfailed"`         // Jobs the peer failed to run
+	RequestsSucceeded  uint64  `json:"requestssucceeded"`  // Other requests the peer served
+	RequestsFailed     uint64  `json:"requestsfailed"`     // Other requests the peer failed to serve
+	Timeouts           uint64  `json:"timeouts"`           // Requests the peer didn't respond to in time
+	InvalidResponses   uint64  `json:"invalidresponses"`   // Responses that couldn't be decoded
+	AuthFailures       uint64  `json:"authfailures"`       // Messages with invalid signatures
+	DiscoveryResponses uint64  `json:"discoveryresponses"` // Discovery responses of the peer
+	UnusedResponses    uint64  `json:"unusedresponses"`    // Discovery responses that never led to work
+	LimitViolations    uint64  `json:"limitviolations"`    // Messages over the rate, concurrency or size limits
+	LatencyMs          float64 `json:"latencyms"`          // Moving average of the response latency in milliseconds
+	BannedUntil        int64   `json:"banneduntil"`        // Unix time the ban of the peer ends
+	UpdatedTime        int64   `json:"updatedtime"`        // The time of the last recorded outcome
+}
+
 // ImageAccount represents the Image Account Model. Keeps track of the files uploaded via the Fileserver
 // Usage: Dev nodes store this information about the user who uploaded the image
 // TODO: name to be changed
@@ -73,3 +92,25 @@ type ImageAccount struct {
 	Path        string `json:"path"`        // Physical path of the location of the image
 	CreatedTime int64  `json:"createdtime"` // The time the image was loaded into the current node's docker engine
 }
+
+// Job represents the Job Model. Keeps track of a job from the request to run it until it ends
+// Usage: Both the requester and the worker of a job store it under the ID of the run request,
+//        and update its state as the job runs, so that the jobs survive restarts of the node
+type Job struct {
+	ID           string       `json:"id"`           // Unique ID of the job, the ID of the request that ran it
+	Owner        string       `json:"owner"`        // The public key of the account that ran the job
+	Requester    string       `json:"requester"`    // The peer that requested the job
+	Worker       string       `json:"worker"`       // The peer that runs the job
+	Image        string       `json:"image"`        // The image the job runs
+	Spec         *api.JobSpec `json:"spec"`         // How the image runs, nil for the defaults of the image
+	Sandbox      string       `json:"sandbox"`      // The sandbox profile the container runs in
+	ContainerID  string       `json:"containerid"`  // The container of the job, once it's created
+	State        JobState     `json:"state"`        // The state of the job
+	Error        string       `json:"error"`        // Why the job failed, was cancelled or expired
+	ExitCode     int          `json:"exitcode"`     // The exit code of the container, once it exits
+	Timeout      int64        `json:"timeout"`      // The wall-clock time of the job in seconds, 0 for no timeout
+	CreatedTime  int64        `json:"createdtime"`  // The time the job was requested
+	StartedTime  int64        `json:"startedtime"`  // The time the container of the job started
+	FinishedTime int64        `json:"finishedtime"` // The time the job reached a terminal state
+	UpdatedTime  int64        `json:"updatedtime"`  // The time of the last change of the state
+}
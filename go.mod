@@ -1,6 +1,9 @@
 module github.com/crowdcompute/crowdengine
 
+go 1.12
+
 require (
+	github.com/Microsoft/go-winio v0.4.11 // indirect
 	github.com/aristanetworks/goarista v0.0.0-20190502180301-283422fc1708 // indirect
 	github.com/bshuster-repo/logrus-logstash-hook v0.4.1
 	github.com/crowdcompute/cc-go-sdk v1.0.2
@@ -9,21 +12,32 @@ require (
 	github.com/docker/distribution v2.7.1+incompatible // indirect
 	github.com/docker/docker v1.13.1
 	github.com/docker/go-connections v0.4.0 // indirect
-	github.com/docker/go-units v0.4.0 // indirect
+	github.com/docker/go-units v0.4.0
 	github.com/ethereum/go-ethereum v1.8.27
 	github.com/gogo/protobuf v1.2.1
 	github.com/golang/protobuf v1.3.1
+	github.com/ipfs/go-cid v0.0.1
 	github.com/ipfs/go-datastore v0.0.5
 	github.com/libp2p/go-libp2p v0.0.23
+	github.com/libp2p/go-libp2p-autonat v0.0.4
+	github.com/libp2p/go-libp2p-circuit v0.0.6
 	github.com/libp2p/go-libp2p-crypto v0.0.2
 	github.com/libp2p/go-libp2p-host v0.0.3
+	github.com/libp2p/go-libp2p-interface-pnet v0.0.1
 	github.com/libp2p/go-libp2p-kad-dht v0.0.10
 	github.com/libp2p/go-libp2p-net v0.0.2
 	github.com/libp2p/go-libp2p-peer v0.1.1
 	github.com/libp2p/go-libp2p-peerstore v0.0.5
 	github.com/libp2p/go-libp2p-protocol v0.0.1
+	github.com/libp2p/go-libp2p-pubsub v0.0.3
+	github.com/libp2p/go-libp2p-record v0.0.1
+	github.com/libp2p/go-libp2p-routing v0.0.1
+	github.com/libp2p/go-msgio v0.0.2
+	github.com/miekg/dns v1.1.4 // indirect
 	github.com/multiformats/go-multiaddr v0.0.2
+	github.com/multiformats/go-multiaddr-net v0.0.1
 	github.com/multiformats/go-multicodec v0.1.6
+	github.com/multiformats/go-multihash v0.0.5
 	github.com/naoina/go-stringutil v0.1.0 // indirect
 	github.com/naoina/toml v0.1.1
 	github.com/opencontainers/go-digest v1.0.0-rc1 // indirect
@@ -35,5 +49,8 @@ require (
 	github.com/stretchr/testify v1.3.0
 	github.com/syndtr/goleveldb v1.0.0
 	github.com/urfave/cli v1.20.0
+	github.com/whyrusleeping/mdns v0.0.0-20180901202407-ef14215e6b30 // indirect
 	golang.org/x/crypto v0.0.0-20190513172903-22d7a77e9e5f
+	golang.org/x/net v0.0.0-20190404232315-eb5bcb51f2a3 // indirect
+	gopkg.in/natefinch/npipe.v2 v2.0.0-20160621034901-c1b8fa8bdcce // indirect
 )
@@ -23,10 +23,12 @@ import (
 	"os"
 	"regexp"
 	"sync"
+	"time"
 	"fmt"
 
 	"github.com/crowdcompute/crowdengine/common"
 	"github.com/crowdcompute/crowdengine/log"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -185,16 +187,41 @@ func newVolumeMount(src, dst string) mount.Mount {
 }
 
 // CreateContainer the manager
+// The container runs as the job spec describes, or with the defaults of the image if spec is nil,
+// and gets the resources the spec requests within the container limits, in the sandbox of the node
 // TODO: persist containerid into levelDB
-func (m *DockerManager) CreateContainer(imageID string) (container.ContainerCreateCreatedBody, error) {
+func (m *DockerManager) CreateContainer(imageID string, spec *api.JobSpec) (container.ContainerCreateCreatedBody, error) {
+	return m.createContainer(imageID, spec, nil)
+}
+
+// createContainer creates the container of CreateContainer, with the labels of the node on top of the labels of the spec
+func (m *DockerManager) createContainer(imageID string, spec *api.JobSpec, labels map[string]string) (container.ContainerCreateCreatedBody, error) {
 	ctx := context.Background()
-	hostconfig := new(container.HostConfig)
+	config, err := containerConfig(imageID, spec)
+	if err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
+	for key, value := range labels {
+		if config.Labels == nil {
+			config.Labels = make(map[string]string)
+		}
+		config.Labels[key] = value
+	}
+	resources, err := containerResources(spec.GetRequirements())
+	if err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
+	hostconfig := &container.HostConfig{Resources: resources}
+	if err := applyStorageLimit(hostconfig, spec.GetRequirements()); err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
+	if err := applySandbox(config, hostconfig); err != nil {
+		return container.ContainerCreateCreatedBody{}, err
+	}
 	hostconfig.Mounts = make([]mount.Mount, 0)
 	hostconfig.Mounts = append(hostconfig.Mounts, newVolumeMount(imageID, common.DockerMountDest)) // imageID will be the name of the volume
 	// TODO: Give permissions to edit the /home folder
-	resp, err := m.client.ContainerCreate(ctx, &container.Config{
-		Image: imageID,
-	}, hostconfig, nil, "")
+	resp, err := m.client.ContainerCreate(ctx, config, hostconfig, nil, "")
 
 	if err != nil {
 		return container.ContainerCreateCreatedBody{}, err
@@ -212,9 +239,14 @@ func (m *DockerManager) RunContainer(containerid string) (bool, error) {
 	return true, nil
 }
 
-// CreateRunContainer creates and runs a container from an image ID
-func (m *DockerManager) CreateRunContainer(imageID string) (string, error) {
-	container, err := m.CreateContainer(imageID)
+// CreateRunContainer creates and runs a container from an image ID, as the job spec describes
+func (m *DockerManager) CreateRunContainer(imageID string, spec *api.JobSpec) (string, error) {
+	return m.createRunContainer(imageID, spec, nil)
+}
+
+// createRunContainer creates and runs the container of CreateRunContainer, with the labels of the node
+func (m *DockerManager) createRunContainer(imageID string, spec *api.JobSpec, labels map[string]string) (string, error) {
+	container, err := m.createContainer(imageID, spec, labels)
 	if err != nil {
 		return "", fmt.Errorf("Error creating container form this image ID: %s. Image ID could be wrong. Error: %s", imageID, err)
 	}
@@ -241,6 +273,21 @@ func (m *DockerManager) InspectContainerRaw(containerid string, getSize bool) (t
 	return inspection, raw, nil
 }
 
+// PauseContainer pauses all processes of a running container
+func (m *DockerManager) PauseContainer(containerid string) error {
+	return m.client.ContainerPause(context.Background(), containerid)
+}
+
+// UnpauseContainer resumes a paused container
+func (m *DockerManager) UnpauseContainer(containerid string) error {
+	return m.client.ContainerUnpause(context.Background(), containerid)
+}
+
+// StopContainer stops a running container, killing it if it doesn't exit within the timeout
+func (m *DockerManager) StopContainer(containerid string, timeout time.Duration) error {
+	return m.client.ContainerStop(context.Background(), containerid, &timeout)
+}
+
 // RemoveContainer removes a container
 func (m *DockerManager) RemoveContainer(containerid string, options types.ContainerRemoveOptions) error {
 	err := m.client.ContainerRemove(context.Background(), containerid, options)
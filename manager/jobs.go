@@ -0,0 +1,217 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/common"
+	"github.com/crowdcompute/crowdengine/database"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+// JobLabel is the label of a container that records the ID of its job
+const JobLabel = "crowdcompute.job"
+
+var (
+	// ErrJobExists is returned when creating a job with the ID of another job
+	ErrJobExists = errors.New("A job with this ID already exists")
+	// errNotStarted is the error of a job whose node stopped before its container started
+	errNotStarted = errors.New("The node stopped before the container of the job started")
+	// errContainerGone is the error of a job whose container was removed before it ended
+	errContainerGone = errors.New("The container of the job no longer exists")
+	// ErrJobStopped is returned when a job got cancelled or expired before its container ran
+	ErrJobStopped = errors.New("The job was stopped before its container ran")
+)
+
+var jobsMu sync.Mutex // Serializes the updates of the jobs in the database
+
+// CreateJob stores the job as queued
+func CreateJob(job *database.Job) error {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	if _, err := database.GetJobFromDB(job.ID); err == nil {
+		return ErrJobExists
+	}
+	now := time.Now().Unix()
+	job.State = database.JobQueued
+	job.CreatedTime = now
+	job.UpdatedTime = now
+	return database.StoreJobToDB(job)
+}
+
+// UpdateJob moves the job jobID to the state, changes it with change if it's not nil, and stores it
+func UpdateJob(jobID string, state database.JobState, change func(*database.Job)) (*database.Job, error) {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	job, err := database.GetJobFromDB(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if err := job.MoveTo(state, time.Now()); err != nil {
+		return nil, err
+	}
+	if change != nil {
+		change(job)
+	}
+	return job, database.StoreJobToDB(job)
+}
+
+// FailJob moves the job jobID to the failed state because of err
+func FailJob(jobID string, err error) (*database.Job, error) {
+	return UpdateJob(jobID, database.JobFailed, func(job *database.Job) {
+		job.Error = err.Error()
+	})
+}
+
+// RunJob creates and runs the container of the queued job jobID, and records the progress of the job
+func (m *DockerManager) RunJob(jobID string) (string, error) {
+	job, err := UpdateJob(jobID, database.JobLoading, func(job *database.Job) {
+		job.Sandbox = SandboxProfile()
+	})
+	if err != nil {
+		return "", err
+	}
+	containerID, err := m.createRunContainer(job.Image, job.Spec, map[string]string{JobLabel: jobID})
+	if err != nil {
+		FailJob(jobID, err)
+		return "", err
+	}
+	_, err = UpdateJob(jobID, database.JobRunning, func(job *database.Job) {
+		job.ContainerID = containerID
+	})
+	if err == database.ErrInvalidTransition {
+		// The job got cancelled or expired while its container was created
+		m.StopContainer(containerID, common.ContainerStopTimeout)
+		return "", ErrJobStopped
+	}
+	return containerID, err
+}
+
+// StopJob moves the job jobID to the terminal state because of reason, and stops its container if it runs.
+// The container gets common.ContainerStopTimeout to exit gracefully before it's killed.
+func (m *DockerManager) StopJob(jobID string, state database.JobState, reason error) (*database.Job, error) {
+	job, err := UpdateJob(jobID, state, func(job *database.Job) {
+		job.Error = reason.Error()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job.ContainerID != "" {
+		if err := m.StopContainer(job.ContainerID, common.ContainerStopTimeout); err != nil && !client.IsErrNotFound(err) {
+			return job, err
+		}
+	}
+	return job, nil
+}
+
+// FinishJob records the end of the running job jobID, as the state of its container says
+func (m *DockerManager) FinishJob(jobID string) (*database.Job, error) {
+	job, err := database.GetJobFromDB(jobID)
+	if err != nil {
+		return nil, err
+	}
+	inspection, err := m.InspectContainer(job.ContainerID)
+	if err != nil {
+		return FailJob(jobID, err)
+	}
+	return finishJob(jobID, inspection.State)
+}
+
+// ReconcileJobs updates the jobs of the worker workerID that didn't end while the node was stopped
+// with the state of their containers. It returns the jobs whose containers still run.
+func (m *DockerManager) ReconcileJobs(workerID string) ([]*database.Job, error) {
+	jobs, err := database.GetJobsFromDB()
+	if err != nil {
+		return nil, err
+	}
+	running := make([]*database.Job, 0)
+	for _, job := range jobs {
+		if job.Worker != workerID || job.State.Terminal() {
+			continue
+		}
+		if job.ContainerID == "" {
+			if job.ContainerID, err = m.jobContainer(job.ID); err != nil {
+				return nil, err
+			}
+		}
+		if job.ContainerID == "" {
+			FailJob(job.ID, errNotStarted)
+			continue
+		}
+		inspection, err := m.InspectContainer(job.ContainerID)
+		if client.IsErrNotFound(err) {
+			FailJob(job.ID, errContainerGone)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if !inspection.State.Running {
+			finishJob(job.ID, inspection.State)
+			continue
+		}
+		if job.State != database.JobRunning {
+			containerID := job.ContainerID
+			if job, err = UpdateJob(job.ID, database.JobRunning, func(job *database.Job) {
+				job.ContainerID = containerID
+			}); err != nil {
+				return nil, err
+			}
+		}
+		running = append(running, job)
+	}
+	return running, nil
+}
+
+// jobContainer returns the ID of the container of the job jobID, or an empty ID if it has none
+func (m *DockerManager) jobContainer(jobID string) (string, error) {
+	containers, err := m.ListContainers()
+	if err != nil {
+		return "", err
+	}
+	for _, container := range containers {
+		if container.Labels[JobLabel] == jobID {
+			return container.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// finishJob moves the job jobID to the state its exited container says:
+// succeeded if the container exited with code 0, failed otherwise
+func finishJob(jobID string, state *types.ContainerState) (*database.Job, error) {
+	if state == nil || state.Status == "created" {
+		return FailJob(jobID, errNotStarted)
+	}
+	finalState := database.JobSucceeded
+	reason := ""
+	switch {
+	case state.OOMKilled:
+		finalState, reason = database.JobFailed, "The container of the job ran out of memory"
+	case state.ExitCode != 0:
+		finalState, reason = database.JobFailed, fmt.Sprintf("The container of the job exited with code %d", state.ExitCode)
+	}
+	return UpdateJob(jobID, finalState, func(job *database.Job) {
+		job.ExitCode = state.ExitCode
+		job.Error = reason
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/crowdcompute/crowdengine/database"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// deleteJobs deletes the jobs of a previous run of the tests
+func deleteJobs(ids ...string) {
+	for _, id := range ids {
+		database.GetDB().Model(&database.Job{}).Delete([]byte(id))
+	}
+}
+
+func TestJobLifecycle(t *testing.T) {
+	deleteJobs("lifecycle")
+	job := &database.Job{ID: "lifecycle", Owner: "owner", Image: "image"}
+	assert.NoError(t, CreateJob(job))
+	assert.Equal(t, ErrJobExists, CreateJob(&database.Job{ID: "lifecycle"}))
+
+	stored, err := database.GetJobFromDB("lifecycle")
+	assert.NoError(t, err)
+	assert.Equal(t, database.JobQueued, stored.State)
+	assert.NotZero(t, stored.CreatedTime)
+
+	_, err = UpdateJob("lifecycle", database.JobRunning, func(job *database.Job) {
+		job.ContainerID = "container"
+	})
+	assert.NoError(t, err)
+	_, err = UpdateJob("lifecycle", database.JobLoading, nil)
+	assert.Equal(t, database.ErrInvalidTransition, err)
+
+	stored, err = finishJob("lifecycle", &types.ContainerState{Status: "exited", ExitCode: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, database.JobFailed, stored.State)
+	assert.Equal(t, 2, stored.ExitCode)
+	assert.Equal(t, "container", stored.ContainerID)
+	assert.Equal(t, "The container of the job exited with code 2", stored.Error)
+}
+
+func TestFinishJob(t *testing.T) {
+	deleteJobs("succeeded", "oom", "not-started")
+	for id, state := range map[string]*types.ContainerState{
+		"succeeded":   {Status: "exited"},
+		"oom":         {Status: "exited", ExitCode: 137, OOMKilled: true},
+		"not-started": {Status: "created"},
+	} {
+		assert.NoError(t, CreateJob(&database.Job{ID: id}))
+		_, err := UpdateJob(id, database.JobRunning, nil)
+		assert.NoError(t, err)
+		finishJob(id, state)
+	}
+	job, _ := database.GetJobFromDB("succeeded")
+	assert.Equal(t, database.JobSucceeded, job.State)
+	assert.Empty(t, job.Error)
+	job, _ = database.GetJobFromDB("oom")
+	assert.Equal(t, database.JobFailed, job.State)
+	assert.Equal(t, "The container of the job ran out of memory", job.Error)
+	job, _ = database.GetJobFromDB("not-started")
+	assert.Equal(t, errNotStarted.Error(), job.Error)
+
+	// A failed job can't fail again
+	_, err := FailJob("not-started", errors.New("again"))
+	assert.Equal(t, database.ErrInvalidTransition, err)
+}
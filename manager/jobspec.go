@@ -0,0 +1,94 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"errors"
+	"strings"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+)
+
+var (
+	// ErrInvalidEnv is returned when an environment variable of a job spec isn't in KEY=value format
+	ErrInvalidEnv = errors.New("The environment variables of the job must be in KEY=value format")
+	// ErrInvalidLabel is returned when a label of a job spec isn't in key=value format
+	ErrInvalidLabel = errors.New("The labels of the job must be in key=value format")
+)
+
+// ValidateJobSpec checks that the environment variables and the labels of the job spec are well formed.
+// A nil spec is valid, the image runs with its defaults.
+func ValidateJobSpec(spec *api.JobSpec) error {
+	if spec == nil {
+		return nil
+	}
+	for _, env := range spec.Env {
+		if _, _, ok := splitKeyValue(env); !ok {
+			return ErrInvalidEnv
+		}
+	}
+	for _, label := range spec.Labels {
+		key, _, ok := splitKeyValue(label)
+		if !ok {
+			return ErrInvalidLabel
+		}
+		if strings.HasPrefix(key, reservedLabelPrefix) {
+			return ErrReservedLabel
+		}
+	}
+	return nil
+}
+
+// containerConfig returns the config of a container that runs the image imageID as the job spec describes
+func containerConfig(imageID string, spec *api.JobSpec) (*container.Config, error) {
+	cfg := &container.Config{Image: imageID}
+	if spec == nil {
+		return cfg, nil
+	}
+	if err := ValidateJobSpec(spec); err != nil {
+		return nil, err
+	}
+	if len(spec.Command) > 0 {
+		cfg.Entrypoint = strslice.StrSlice(spec.Command)
+	}
+	if len(spec.Args) > 0 {
+		cfg.Cmd = strslice.StrSlice(spec.Args)
+	}
+	cfg.Env = spec.Env
+	cfg.WorkingDir = spec.WorkDir
+	cfg.User = spec.User
+	if len(spec.Labels) > 0 {
+		cfg.Labels = make(map[string]string, len(spec.Labels))
+		for _, label := range spec.Labels {
+			key, value, _ := splitKeyValue(label)
+			cfg.Labels[key] = value
+		}
+	}
+	return cfg, nil
+}
+
+// splitKeyValue splits a key=value pair. The key can't be empty, the value can.
+func splitKeyValue(pair string) (string, string, bool) {
+	i := strings.Index(pair, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	return pair[:i], pair[i+1:], true
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"testing"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerConfig(t *testing.T) {
+	// Without a spec the image runs with its defaults
+	cfg, err := containerConfig("image", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "image", cfg.Image)
+	assert.Nil(t, cfg.Entrypoint)
+	assert.Nil(t, cfg.Cmd)
+
+	spec := &api.JobSpec{
+		Command: []string{"python", "sweep.py"},
+		Args:    []string{"--lr", "0.01"},
+		Env:     []string{"SEED=42", "EMPTY="},
+		WorkDir: "/work",
+		User:    "1000:1000",
+		Labels:  []string{"sweep=lr", "run=3=a"},
+	}
+	cfg, err = containerConfig("image", spec)
+	assert.NoError(t, err)
+	assert.Equal(t, strslice.StrSlice{"python", "sweep.py"}, cfg.Entrypoint)
+	assert.Equal(t, strslice.StrSlice{"--lr", "0.01"}, cfg.Cmd)
+	assert.Equal(t, []string{"SEED=42", "EMPTY="}, cfg.Env)
+	assert.Equal(t, "/work", cfg.WorkingDir)
+	assert.Equal(t, "1000:1000", cfg.User)
+	assert.Equal(t, map[string]string{"sweep": "lr", "run": "3=a"}, cfg.Labels)
+
+	// Only the arguments of the image's entrypoint are overridden
+	cfg, err = containerConfig("image", &api.JobSpec{Args: []string{"--help"}})
+	assert.NoError(t, err)
+	assert.Nil(t, cfg.Entrypoint)
+	assert.Equal(t, strslice.StrSlice{"--help"}, cfg.Cmd)
+}
+
+func TestValidateJobSpec(t *testing.T) {
+	assert.NoError(t, ValidateJobSpec(nil))
+	assert.Equal(t, ErrInvalidEnv, ValidateJobSpec(&api.JobSpec{Env: []string{"SEED"}}))
+	assert.Equal(t, ErrInvalidEnv, ValidateJobSpec(&api.JobSpec{Env: []string{"=42"}}))
+	assert.Equal(t, ErrInvalidLabel, ValidateJobSpec(&api.JobSpec{Labels: []string{"sweep"}}))
+}
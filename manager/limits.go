@@ -0,0 +1,145 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	// StorageLimitSize limits the writable layer of a container through the storage driver
+	StorageLimitSize = "size"
+	// StorageLimitTmpfs limits a tmpfs mounted at tmpfsDest
+	StorageLimitTmpfs = "tmpfs"
+	// StorageLimitNone doesn't limit the storage of a container
+	StorageLimitNone = "none"
+
+	// tmpfsDest is where the tmpfs of the StorageLimitTmpfs mode is mounted
+	tmpfsDest = "/tmp"
+)
+
+var (
+	// ErrUnknownStorageLimit is returned when the storage limit of the config is not one of the known modes
+	ErrUnknownStorageLimit = errors.New("The storage limit must be one of size, tmpfs or none")
+	// ErrExceedsLimits is returned when a job spec requests more resources than a container of the node has
+	ErrExceedsLimits = errors.New("The job requests more resources than a container of this node has")
+	// ErrTimeoutTooLong is returned when a job spec asks for more time than the node allows a job
+	ErrTimeoutTooLong = errors.New("The job asks for more time than this node allows")
+	// ErrDefaultTimeoutTooLong is returned when the job timeout of the config is over its maximum job timeout
+	ErrDefaultTimeoutTooLong = errors.New("The job timeout must be set and not over the maximum job timeout")
+)
+
+var (
+	limits   = &config.Host{} // The limits of every container, nothing is limited until they are set
+	limitsMu sync.RWMutex
+)
+
+// SetContainerLimits sets the resources every container gets at most: the CPUs, the memory,
+// the storage and the processes per container of the hostCfg, and the time every job gets.
+// A zero limit means that the resource is not limited.
+func SetContainerLimits(hostCfg *config.Host) error {
+	switch hostCfg.StorageLimit {
+	case StorageLimitSize, StorageLimitTmpfs, StorageLimitNone, "":
+	default:
+		return ErrUnknownStorageLimit
+	}
+	// Jobs that ask for no time get the job timeout, which must be a time they could ask for
+	if hostCfg.MaxJobTimeout > 0 && (hostCfg.JobTimeout <= 0 || hostCfg.JobTimeout > hostCfg.MaxJobTimeout) {
+		return ErrDefaultTimeoutTooLong
+	}
+	limitsMu.Lock()
+	defer limitsMu.Unlock()
+	limits = hostCfg
+	return nil
+}
+
+// containerResources returns the limits of a container of the job that requests the resources reqs.
+// A job can request less than the limits of the node, but never more.
+func containerResources(reqs *api.JobRequirements) (container.Resources, error) {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	cpu, err := requested(uint64(reqs.GetCpu()), limits.CPUPerContainer)
+	if err != nil {
+		return container.Resources{}, err
+	}
+	memory, err := requested(reqs.GetMemory(), limits.MemoryPerContainer)
+	if err != nil {
+		return container.Resources{}, err
+	}
+	res := container.Resources{
+		NanoCPUs:  int64(cpu) * 1e9,
+		Memory:    int64(memory) << 20,
+		PidsLimit: int64(limits.PidsPerContainer),
+	}
+	// The container can't swap beyond its memory limit
+	res.MemorySwap = res.Memory
+	return res, nil
+}
+
+// applyStorageLimit limits the storage of the container of hostConfig to the storage the job requests,
+// as the configured storage limit mode says
+func applyStorageLimit(hostConfig *container.HostConfig, reqs *api.JobRequirements) error {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	storage, err := requested(reqs.GetStorage(), limits.StoragePerContainer)
+	if err != nil || storage == 0 {
+		return err
+	}
+	switch limits.StorageLimit {
+	case StorageLimitSize:
+		hostConfig.StorageOpt = map[string]string{"size": fmt.Sprintf("%dM", storage)}
+	case StorageLimitTmpfs:
+		hostConfig.Tmpfs = map[string]string{tmpfsDest: fmt.Sprintf("rw,size=%dm", storage)}
+	}
+	return nil
+}
+
+// JobTimeout returns the wall-clock time of the job spec: the maximum runtime its requirements ask for,
+// which discovery matched against the availability of the node, or the default of the node if they
+// ask for none. A zero timeout means that the job has no timeout.
+func JobTimeout(spec *api.JobSpec) (time.Duration, error) {
+	limitsMu.RLock()
+	defer limitsMu.RUnlock()
+	timeout := spec.GetRequirements().GetMaxRuntime()
+	if limits.MaxJobTimeout > 0 && timeout > uint64(limits.MaxJobTimeout) {
+		return 0, ErrTimeoutTooLong
+	}
+	if timeout == 0 {
+		timeout = uint64(limits.JobTimeout)
+	}
+	return time.Duration(timeout) * time.Second, nil
+}
+
+// requested returns the amount of a resource a job gets: the amount it requests, or the limit if
+// it requests none. A zero limit means that the resource is not limited.
+func requested(request uint64, limit int) (uint64, error) {
+	if limit > 0 && request > uint64(limit) {
+		return 0, ErrExceedsLimits
+	}
+	if request > 0 {
+		return request, nil
+	}
+	return uint64(limit), nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContainerResources(t *testing.T) {
+	defer SetContainerLimits(&config.Host{})
+	assert.Equal(t, ErrUnknownStorageLimit, SetContainerLimits(&config.Host{StorageLimit: "quota"}))
+	assert.NoError(t, SetContainerLimits(&config.Host{CPUPerContainer: 2, MemoryPerContainer: 1024, PidsPerContainer: 512}))
+
+	// Without requests a job gets the limits
+	res, err := containerResources(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2e9), res.NanoCPUs)
+	assert.Equal(t, int64(1024<<20), res.Memory)
+	assert.Equal(t, res.Memory, res.MemorySwap)
+	assert.Equal(t, int64(512), res.PidsLimit)
+
+	// A job can request less, but never more
+	res, err = containerResources(&api.JobRequirements{Cpu: 1, Memory: 256})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1e9), res.NanoCPUs)
+	assert.Equal(t, int64(256<<20), res.Memory)
+	_, err = containerResources(&api.JobRequirements{Memory: 2048})
+	assert.Equal(t, ErrExceedsLimits, err)
+
+	// Zero limits don't limit
+	assert.NoError(t, SetContainerLimits(&config.Host{}))
+	res, err = containerResources(&api.JobRequirements{Cpu: 64})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(64e9), res.NanoCPUs)
+	assert.Zero(t, res.Memory)
+}
+
+func TestApplyStorageLimit(t *testing.T) {
+	defer SetContainerLimits(&config.Host{})
+	assert.NoError(t, SetContainerLimits(&config.Host{StoragePerContainer: 2048, StorageLimit: StorageLimitTmpfs}))
+	hostConfig := &container.HostConfig{}
+	assert.NoError(t, applyStorageLimit(hostConfig, &api.JobRequirements{Storage: 512}))
+	assert.Equal(t, map[string]string{"/tmp": "rw,size=512m"}, hostConfig.Tmpfs)
+	assert.Nil(t, hostConfig.StorageOpt)
+	assert.Equal(t, ErrExceedsLimits, applyStorageLimit(hostConfig, &api.JobRequirements{Storage: 4096}))
+
+	assert.NoError(t, SetContainerLimits(&config.Host{StoragePerContainer: 2048, StorageLimit: StorageLimitSize}))
+	hostConfig = &container.HostConfig{}
+	assert.NoError(t, applyStorageLimit(hostConfig, nil))
+	assert.Equal(t, map[string]string{"size": "2048M"}, hostConfig.StorageOpt)
+
+	assert.NoError(t, SetContainerLimits(&config.Host{StoragePerContainer: 2048, StorageLimit: StorageLimitNone}))
+	hostConfig = &container.HostConfig{}
+	assert.NoError(t, applyStorageLimit(hostConfig, nil))
+	assert.Nil(t, hostConfig.StorageOpt)
+	assert.Nil(t, hostConfig.Tmpfs)
+}
+
+func TestJobTimeout(t *testing.T) {
+	defer SetContainerLimits(&config.Host{})
+	assert.NoError(t, SetContainerLimits(&config.Host{JobTimeout: 60, MaxJobTimeout: 600}))
+
+	// A job that asks for no time can't get more than the maximum either
+	assert.Equal(t, ErrDefaultTimeoutTooLong, SetContainerLimits(&config.Host{JobTimeout: 601, MaxJobTimeout: 600}))
+	assert.Equal(t, ErrDefaultTimeoutTooLong, SetContainerLimits(&config.Host{MaxJobTimeout: 600}))
+
+	// Without a maximum runtime a job gets the default of the node
+	timeout, err := JobTimeout(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, timeout)
+	timeout, err = JobTimeout(&api.JobSpec{Requirements: &api.JobRequirements{MaxRuntime: 600}})
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, timeout)
+	_, err = JobTimeout(&api.JobSpec{Requirements: &api.JobRequirements{MaxRuntime: 601}})
+	assert.Equal(t, ErrTimeoutTooLong, err)
+
+	// Without a maximum a job can ask for any time
+	assert.NoError(t, SetContainerLimits(&config.Host{}))
+	timeout, err = JobTimeout(&api.JobSpec{Requirements: &api.JobRequirements{MaxRuntime: 1e6}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1e6*time.Second, timeout)
+	timeout, err = JobTimeout(nil)
+	assert.NoError(t, err)
+	assert.Zero(t, timeout)
+}
@@ -0,0 +1,183 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/docker/docker/api/types/container"
+	units "github.com/docker/go-units"
+)
+
+const (
+	// SandboxNone keeps the Docker defaults
+	SandboxNone = "none"
+	// SandboxStandard drops all capabilities, forbids gaining privileges and applies seccomp and ulimits
+	SandboxStandard = "standard"
+	// SandboxStrict is SandboxStandard with a read-only root filesystem, a non-root user and no network,
+	// unless a network is configured
+	SandboxStrict = "strict"
+
+	// SandboxLabel is the label of a container that records the sandbox profile applied to it
+	SandboxLabel = "crowdcompute.sandbox"
+	// reservedLabelPrefix is the prefix of the labels the node sets, which jobs can't set
+	reservedLabelPrefix = "crowdcompute."
+
+	// nobodyUser is the user of the strict profile if none is configured
+	nobodyUser = "65534:65534"
+	// networkNone is the Docker network mode without network
+	networkNone = "none"
+)
+
+var (
+	// ErrUnknownSandbox is returned when the sandbox profile of the config is not one of the known profiles
+	ErrUnknownSandbox = errors.New("The sandbox profile must be one of none, standard or strict")
+	// ErrRootUser is returned when a job asks to run as root under the strict profile
+	ErrRootUser = errors.New("The sandbox of this node doesn't allow jobs to run as root")
+	// ErrReservedLabel is returned when a job spec sets a label with the prefix of the labels of the node
+	ErrReservedLabel = errors.New("The labels of the job can't start with " + reservedLabelPrefix)
+)
+
+var (
+	sandbox   = &config.Sandbox{} // The sandbox of every container, Docker defaults until it's set
+	seccomp   string              // The seccomp profile of the sandbox, compacted to fit a security option
+	sandboxMu sync.RWMutex
+)
+
+// SetSandbox sets the sandbox profile every container runs with, as the node policy cfg says.
+// It reads the seccomp profile file of the policy, if any.
+func SetSandbox(cfg *config.Sandbox) error {
+	switch cfg.Profile {
+	case SandboxNone, SandboxStandard, SandboxStrict, "":
+	default:
+		return ErrUnknownSandbox
+	}
+	profile := ""
+	if cfg.SeccompProfile != "" {
+		data, err := ioutil.ReadFile(cfg.SeccompProfile)
+		if err != nil {
+			return err
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, data); err != nil {
+			return err
+		}
+		profile = compact.String()
+	}
+	sandboxMu.Lock()
+	defer sandboxMu.Unlock()
+	sandbox = cfg
+	seccomp = profile
+	return nil
+}
+
+// SandboxProfile returns the sandbox profile the containers run with
+func SandboxProfile() string {
+	sandboxMu.RLock()
+	defer sandboxMu.RUnlock()
+	if sandbox.Profile == "" {
+		return SandboxNone
+	}
+	return sandbox.Profile
+}
+
+// CheckSandbox checks that the sandbox of this node allows the job spec to run.
+// A requester can't tell, so only the node that runs the job checks it.
+func CheckSandbox(spec *api.JobSpec) error {
+	if SandboxProfile() == SandboxStrict && isRoot(spec.GetUser()) {
+		return ErrRootUser
+	}
+	return nil
+}
+
+// applySandbox applies the sandbox profile to the container of cfg and hostConfig,
+// and records the profile in the labels of the container
+func applySandbox(cfg *container.Config, hostConfig *container.HostConfig) error {
+	profile := SandboxProfile()
+	sandboxMu.RLock()
+	defer sandboxMu.RUnlock()
+
+	if cfg.Labels == nil {
+		cfg.Labels = make(map[string]string)
+	}
+	cfg.Labels[SandboxLabel] = profile
+	network := sandbox.Network
+	if profile == SandboxNone {
+		applyNetwork(cfg, hostConfig, network)
+		return nil
+	}
+
+	hostConfig.CapDrop = []string{"ALL"}
+	hostConfig.SecurityOpt = []string{"no-new-privileges"}
+	if seccomp != "" {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "seccomp="+seccomp)
+	}
+	hostConfig.Ulimits = []*units.Ulimit{{Name: "core", Soft: 0, Hard: 0}}
+	if sandbox.MaxOpenFiles > 0 {
+		files := int64(sandbox.MaxOpenFiles)
+		hostConfig.Ulimits = append(hostConfig.Ulimits, &units.Ulimit{Name: "nofile", Soft: files, Hard: files})
+	}
+
+	if profile == SandboxStrict {
+		hostConfig.ReadonlyRootfs = true
+		// The job can still write to a tmpfs, and to the volume of the image
+		if _, ok := hostConfig.Tmpfs[tmpfsDest]; !ok {
+			if hostConfig.Tmpfs == nil {
+				hostConfig.Tmpfs = make(map[string]string)
+			}
+			hostConfig.Tmpfs[tmpfsDest] = "rw"
+		}
+		if cfg.User == "" {
+			cfg.User = sandbox.User
+			if cfg.User == "" {
+				cfg.User = nobodyUser
+			}
+		}
+		if isRoot(cfg.User) {
+			return ErrRootUser
+		}
+		if network == "" {
+			network = networkNone
+		}
+	}
+	applyNetwork(cfg, hostConfig, network)
+	return nil
+}
+
+// applyNetwork connects the container to the Docker network, or disables its network if it's "none".
+// An empty network keeps the default network of Docker.
+func applyNetwork(cfg *container.Config, hostConfig *container.HostConfig, network string) {
+	if network == "" {
+		return
+	}
+	hostConfig.NetworkMode = container.NetworkMode(network)
+	cfg.NetworkDisabled = network == networkNone
+}
+
+// isRoot returns true if user[:group] is the root user, by name or by ID
+func isRoot(user string) bool {
+	name := strings.SplitN(user, ":", 2)[0]
+	return name == "root" || name == "0"
+}
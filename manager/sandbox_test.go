@@ -0,0 +1,107 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package manager
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSandbox(t *testing.T) {
+	defer SetSandbox(&config.Sandbox{})
+	assert.Equal(t, ErrUnknownSandbox, SetSandbox(&config.Sandbox{Profile: "paranoid"}))
+	assert.Error(t, SetSandbox(&config.Sandbox{Profile: SandboxStandard, SeccompProfile: "/nonexistent/seccomp.json"}))
+	assert.NoError(t, SetSandbox(&config.Sandbox{}))
+	assert.Equal(t, SandboxNone, SandboxProfile())
+}
+
+func TestApplySandboxNone(t *testing.T) {
+	defer SetSandbox(&config.Sandbox{})
+	assert.NoError(t, SetSandbox(&config.Sandbox{Profile: SandboxNone, Network: "jobs"}))
+
+	cfg, hostConfig := &container.Config{}, &container.HostConfig{}
+	assert.NoError(t, applySandbox(cfg, hostConfig))
+	assert.Equal(t, SandboxNone, cfg.Labels[SandboxLabel])
+	assert.Empty(t, hostConfig.CapDrop)
+	assert.False(t, hostConfig.ReadonlyRootfs)
+	assert.Equal(t, container.NetworkMode("jobs"), hostConfig.NetworkMode)
+}
+
+func TestApplySandboxStandard(t *testing.T) {
+	defer SetSandbox(&config.Sandbox{})
+	file, err := ioutil.TempFile("", "seccomp")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("{\n  \"defaultAction\": \"SCMP_ACT_ERRNO\"\n}\n")
+	file.Close()
+	assert.NoError(t, SetSandbox(&config.Sandbox{Profile: SandboxStandard, SeccompProfile: file.Name(), MaxOpenFiles: 512}))
+
+	cfg, hostConfig := &container.Config{User: "root"}, &container.HostConfig{}
+	assert.NoError(t, applySandbox(cfg, hostConfig))
+	assert.Equal(t, SandboxStandard, cfg.Labels[SandboxLabel])
+	assert.Equal(t, []string{"ALL"}, []string(hostConfig.CapDrop))
+	assert.Equal(t, []string{"no-new-privileges", `seccomp={"defaultAction":"SCMP_ACT_ERRNO"}`}, hostConfig.SecurityOpt)
+	assert.Len(t, hostConfig.Ulimits, 2)
+	assert.Equal(t, "nofile", hostConfig.Ulimits[1].Name)
+	assert.Equal(t, int64(512), hostConfig.Ulimits[1].Hard)
+	// The standard profile doesn't change the filesystem, the user and the network
+	assert.False(t, hostConfig.ReadonlyRootfs)
+	assert.Equal(t, "root", cfg.User)
+	assert.Empty(t, hostConfig.NetworkMode)
+}
+
+func TestApplySandboxStrict(t *testing.T) {
+	defer SetSandbox(&config.Sandbox{})
+	assert.NoError(t, SetSandbox(&config.Sandbox{Profile: SandboxStrict}))
+
+	cfg, hostConfig := &container.Config{Labels: map[string]string{"team": "a"}}, &container.HostConfig{}
+	assert.NoError(t, applySandbox(cfg, hostConfig))
+	assert.Equal(t, map[string]string{"team": "a", SandboxLabel: SandboxStrict}, cfg.Labels)
+	assert.True(t, hostConfig.ReadonlyRootfs)
+	assert.Equal(t, "rw", hostConfig.Tmpfs[tmpfsDest])
+	assert.Equal(t, nobodyUser, cfg.User)
+	assert.Equal(t, container.NetworkMode("none"), hostConfig.NetworkMode)
+	assert.True(t, cfg.NetworkDisabled)
+
+	// A sized tmpfs is kept, and a dedicated network replaces no network
+	assert.NoError(t, SetSandbox(&config.Sandbox{Profile: SandboxStrict, User: "1000", Network: "egress"}))
+	cfg, hostConfig = &container.Config{}, &container.HostConfig{Tmpfs: map[string]string{tmpfsDest: "rw,size=64m"}}
+	assert.NoError(t, applySandbox(cfg, hostConfig))
+	assert.Equal(t, "rw,size=64m", hostConfig.Tmpfs[tmpfsDest])
+	assert.Equal(t, "1000", cfg.User)
+	assert.Equal(t, container.NetworkMode("egress"), hostConfig.NetworkMode)
+	assert.False(t, cfg.NetworkDisabled)
+
+	assert.Equal(t, ErrRootUser, applySandbox(&container.Config{User: "0:0"}, &container.HostConfig{}))
+	assert.Equal(t, ErrRootUser, CheckSandbox(&api.JobSpec{User: "root"}))
+	assert.NoError(t, CheckSandbox(&api.JobSpec{User: "1000:1000"}))
+	assert.NoError(t, CheckSandbox(nil))
+	// The requester only checks the format, as the sandbox is the worker's
+	assert.NoError(t, ValidateJobSpec(&api.JobSpec{User: "root"}))
+}
+
+func TestReservedLabels(t *testing.T) {
+	assert.Equal(t, ErrReservedLabel, ValidateJobSpec(&api.JobSpec{Labels: []string{SandboxLabel + "=none"}}))
+	assert.NoError(t, ValidateJobSpec(&api.JobSpec{Labels: []string{"crowdcompute=yes"}}))
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
 	"github.com/crowdcompute/crowdengine/common"
 	"github.com/crowdcompute/crowdengine/database"
+	"github.com/crowdcompute/crowdengine/manager"
 	"github.com/crowdcompute/crowdengine/p2p"
 	ccrpc "github.com/crowdcompute/crowdengine/rpc"
 	"github.com/urfave/cli"
@@ -44,6 +45,7 @@ type Node struct {
 	host            *p2p.Host
 	cfg             *config.GlobalConfig
 	ks              *keystore.KeyStore
+	bootnode        bool // Only the network parts run, see NewBootnode
 }
 
 // NewNode returns new Node instance
@@ -53,18 +55,55 @@ func NewNode(cfg *config.GlobalConfig) (*Node, error) {
 		quit: make(chan struct{}),
 		ks:   keystore.NewKeyStore(cfg.Global.KeystoreDir),
 	}
+	if err := checkAvailabilityAction(cfg.Global.AvailabilityAction); err != nil {
+		return nil, err
+	}
+	// Every container gets at most the resources of the Host config
+	if err := manager.SetContainerLimits(&cfg.Host); err != nil {
+		return nil, err
+	}
+	if err := manager.SetSandbox(&cfg.Host.Sandbox); err != nil {
+		return nil, err
+	}
 	database.SetLvlDBPath(cfg.Global.DataDir)
 	host, err := p2p.NewHost(cfg)
 	n.host = host
 	return n, err
 }
 
+// NewBootnode returns a Node that only runs the DHT and the relay of the network and serves
+// the network RPC APIs. It doesn't need Docker.
+func NewBootnode(cfg *config.GlobalConfig) (*Node, error) {
+	n := &Node{
+		cfg:      cfg,
+		quit:     make(chan struct{}),
+		ks:       keystore.NewKeyStore(cfg.Global.KeystoreDir),
+		bootnode: true,
+	}
+	database.SetLvlDBPath(cfg.Global.DataDir)
+	host, err := p2p.NewBootnodeHost(cfg)
+	n.host = host
+	return n, err
+}
+
 // Start starts a node instance & listens to RPC calls if the flag is set
 func (n *Node) Start(ctx *cli.Context) error {
 	n.startOnce.Do(func() {
 		// TODO: Only if worker node run these two
-		go n.host.DeleteDiscoveryMsgs(n.quit)
-		go PruneImages(n.quit)
+		if !n.bootnode {
+			if err := n.host.ReconcileJobs(); err != nil {
+				log.Errorf("Error reconciling the jobs with Docker. Error: %s", err)
+			}
+			go n.host.DeleteDiscoveryMsgs(n.quit)
+			go PruneImages(n.quit)
+			go EnforceAvailability(n.host, n.cfg.Global.AvailabilityAction, n.quit)
+			go n.host.PublishCapabilities(n.quit)
+		}
+		go n.host.PersistNetwork(n.quit)
+		go n.host.ConnManager.Run(n.quit)
+		if n.host.MDNS != nil {
+			go n.host.MDNS.Run(n.quit)
+		}
 	})
 
 	if n.cfg.RPC.Enabled {
@@ -83,12 +122,18 @@ func (n *Node) Start(ctx *cli.Context) error {
 func (n *Node) Stop() error {
 	n.store.Close()
 	close(n.quit)
+	if err := n.host.Close(); err != nil {
+		log.Println("Error closing the p2p datastore: ", err)
+	}
 	log.Println("Node stopped")
 	return nil
 }
 
 // apis returns the collection of RPC descriptors this node offers.
 func (n *Node) apis() []ccrpc.API {
+	if n.bootnode {
+		return n.bootnodeAPIs()
+	}
 	return []ccrpc.API{
 		{
 			Namespace:    "discovery",
@@ -97,12 +142,40 @@ func (n *Node) apis() []ccrpc.API {
 			Public:       true,
 			AuthRequired: "",
 		},
+		{
+			Namespace:    "capacity",
+			Version:      "1.0",
+			Service:      ccrpc.NewCapacityAPI(n.host),
+			Public:       true,
+			AuthRequired: "",
+		},
+		{
+			Namespace:    "network",
+			Version:      "1.0",
+			Service:      ccrpc.NewNetworkAPI(n.host),
+			Public:       true,
+			AuthRequired: "",
+		},
+		{
+			Namespace:    "membership",
+			Version:      "1.0",
+			Service:      ccrpc.NewMembershipAPI(n.host),
+			Public:       true,
+			AuthRequired: "AddMember,RemoveMember,ImportList",
+		},
 		{
 			Namespace:    "imagemanager",
 			Version:      "1.0",
 			Service:      ccrpc.NewImageManagerAPI(n.host),
 			Public:       true,
-			AuthRequired: "ListImages,ListContainers",
+			AuthRequired: "ListImages,ListContainers,RunJob",
+		},
+		{
+			Namespace:    "job",
+			Version:      "1.0",
+			Service:      ccrpc.NewJobAPI(n.host),
+			Public:       true,
+			AuthRequired: "*",
 		},
 		{
 			Namespace:    "service",
@@ -156,11 +229,33 @@ func (n *Node) apis() []ccrpc.API {
 	}
 }
 
+// bootnodeAPIs returns the RPC descriptors a bootnode offers: the peers and the stats of the network
+func (n *Node) bootnodeAPIs() []ccrpc.API {
+	return []ccrpc.API{
+		{
+			Namespace:    "network",
+			Version:      "1.0",
+			Service:      ccrpc.NewNetworkAPI(n.host),
+			Public:       true,
+			AuthRequired: "",
+		},
+		{
+			Namespace:    "bootnodes",
+			Version:      "1.0",
+			Service:      ccrpc.NewBootnodesAPI(n.host),
+			Public:       true,
+			AuthRequired: "",
+		},
+	}
+}
+
 // StartHTTP starts a http server
 func (n *Node) StartHTTP() {
 	serveMux := http.NewServeMux()
 	serveMux.Handle("/", ccrpc.ServeHTTP(n.apis(), n.ks))
-	serveMux.HandleFunc("/upload", ccrpc.ServeFilesHTTP(n.ks, n.cfg.Global.UploadsDir))
+	if !n.bootnode {
+		serveMux.HandleFunc("/upload", ccrpc.ServeFilesHTTP(n.ks, n.cfg.Global.UploadsDir))
+	}
 
 	port := n.cfg.RPC.HTTP.ListenPort
 	log.Println("RPC listening to the port: ", port)
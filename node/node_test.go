@@ -190,3 +190,33 @@ func TestMethodNotGiven(t *testing.T) {
 	resp := serveRequest(testNode.ks, req)
 	assert.True(t, resp.Code == http.StatusBadRequest)
 }
+
+// Sends a JSON RPC request to a bootnode, which only serves the network APIs
+func TestBootnodeAPIs(t *testing.T) {
+	bootnode, err := NewBootnode(&config.GlobalConfig{
+		P2P:    config.P2P{ListenPort: 10391, ListenAddress: listenAddress},
+		Global: config.Global{KeystoreDir: tempDir},
+	})
+	assert.NoError(t, err)
+	namespaces := []string{}
+	for _, api := range bootnode.apis() {
+		namespaces = append(namespaces, api.Namespace)
+	}
+	assert.Equal(t, []string{"network", "bootnodes"}, namespaces)
+
+	var jsonStr = []byte(`{"jsonrpc":"2.0","id":"1","method":"network_getStats","params":[]}`)
+	rr := httptest.NewRecorder()
+	ccrpc.ServeHTTP(bootnode.apis(), bootnode.ks).ServeHTTP(rr, createPOSTreq(jsonStr))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"Bootnode":true`)
+}
+
+// A node doesn't start with an unknown availability action
+func TestUnknownAvailabilityAction(t *testing.T) {
+	_, err := NewNode(&config.GlobalConfig{
+		Global: config.Global{KeystoreDir: tempDir, AvailabilityAction: "hibernate"},
+	})
+	assert.Equal(t, ErrUnknownAvailabilityAction, err)
+	assert.NoError(t, checkAvailabilityAction(availabilityActionStop))
+	assert.NoError(t, checkAvailabilityAction(""))
+}
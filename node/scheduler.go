@@ -17,6 +17,7 @@
 package node
 
 import (
+	"errors"
 	"strings"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 	"github.com/crowdcompute/crowdengine/database"
 	"github.com/crowdcompute/crowdengine/log"
 	"github.com/crowdcompute/crowdengine/manager"
+	"github.com/crowdcompute/crowdengine/p2p"
 	"github.com/docker/docker/api/types"
 )
 
@@ -44,6 +46,106 @@ func PruneImages(quit <-chan struct{}) {
 	}
 }
 
+// The actions on running containers when an availability window closes
+const (
+	availabilityActionNone  = "none"
+	availabilityActionPause = "pause"
+	availabilityActionStop  = "stop"
+)
+
+// ErrUnknownAvailabilityAction is returned when the availability action of the config is not one of the known actions
+var ErrUnknownAvailabilityAction = errors.New("The availability action must be one of none, pause or stop")
+
+// errNoJobLabel is returned when a container has no job label
+var errNoJobLabel = errors.New("The container has no job label")
+
+// checkAvailabilityAction checks that action is a known availability action
+func checkAvailabilityAction(action string) error {
+	switch action {
+	case availabilityActionNone, availabilityActionPause, availabilityActionStop, "":
+		return nil
+	}
+	return ErrUnknownAvailabilityAction
+}
+
+// EnforceAvailability pauses or stops the running jobs of the host when an availability window closes,
+// and resumes the paused ones when the next window opens.
+// Running for ever, or until node dies
+func EnforceAvailability(h *p2p.Host, action string, quit <-chan struct{}) {
+	if h.Availability.Always() || action == availabilityActionNone || action == "" {
+		return
+	}
+	ticker := time.NewTicker(common.AvailabilityCheckInterval)
+	defer ticker.Stop()
+
+	paused := make(map[string]struct{})
+	for {
+		select {
+		case <-ticker.C:
+			if h.Availability.Available(time.Now()) {
+				resumeContainers(paused)
+			} else {
+				suspendContainers(h.Capacity.Containers(), action, paused)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// suspendContainers pauses or stops the given containers, storing the paused ones to paused
+func suspendContainers(containerIDs []string, action string, paused map[string]struct{}) {
+	for _, containerID := range containerIDs {
+		if _, ok := paused[containerID]; ok {
+			continue
+		}
+		if action == availabilityActionPause {
+			if err := manager.GetInstance().PauseContainer(containerID); err != nil {
+				log.Println("Couldn't pause container. ID: ", containerID, ". Error: ", err)
+				continue
+			}
+			log.Println("Availability window closed. Paused container: ", containerID)
+			paused[containerID] = struct{}{}
+			continue
+		}
+		// The job ends like an expired one, and the capacity gets released as soon as it's seen as finished
+		jobID, err := containerJob(containerID)
+		if err != nil {
+			log.Println("Couldn't find the job of container. ID: ", containerID, ". Error: ", err)
+			continue
+		}
+		if _, err := manager.GetInstance().StopJob(jobID, database.JobExpired, p2p.ErrAvailabilityClosed); err != nil {
+			log.Println("Couldn't stop job. ID: ", jobID, ". Error: ", err)
+			continue
+		}
+		log.Println("Availability window closed. Stopped job: ", jobID)
+	}
+}
+
+// containerJob returns the ID of the job of the container containerID, as its job label records it
+func containerJob(containerID string) (string, error) {
+	inspection, err := manager.GetInstance().InspectContainer(containerID)
+	if err != nil {
+		return "", err
+	}
+	if inspection.Config == nil || inspection.Config.Labels[manager.JobLabel] == "" {
+		return "", errNoJobLabel
+	}
+	return inspection.Config.Labels[manager.JobLabel], nil
+}
+
+// resumeContainers resumes all paused containers and removes them from paused
+func resumeContainers(paused map[string]struct{}) {
+	for containerID := range paused {
+		if err := manager.GetInstance().UnpauseContainer(containerID); err != nil {
+			log.Println("Couldn't resume container. ID: ", containerID, ". Error: ", err)
+		} else {
+			log.Println("Availability window opened. Resumed container: ", containerID)
+		}
+		delete(paused, containerID)
+	}
+}
+
 // RemoveImages removes all images that got expired
 // This is a goroutine
 func RemoveImages() {
@@ -0,0 +1,128 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+
+	circuit "github.com/libp2p/go-libp2p-circuit"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+// listenAddrs returns the multiaddrs of the configured ListenAddrs.
+// Without ListenAddrs the node listens over TCP on the ListenAddress, which can be an IP or a host name, and the ListenPort.
+func listenAddrs(cfg *config.P2P) ([]ma.Multiaddr, error) {
+	if len(cfg.ListenAddrs) > 0 {
+		return parseAddrs(cfg.ListenAddrs)
+	}
+	ip := net.IPv4zero
+	if cfg.ListenAddress != "" {
+		ipAddr, err := net.ResolveIPAddr("ip", cfg.ListenAddress)
+		if err != nil {
+			return nil, err
+		}
+		ip = ipAddr.IP
+	}
+	addr, err := manet.FromIP(ip)
+	if err != nil {
+		return nil, err
+	}
+	tcp, err := ma.NewMultiaddr(fmt.Sprintf("/tcp/%d", cfg.ListenPort))
+	if err != nil {
+		return nil, err
+	}
+	return []ma.Multiaddr{addr.Encapsulate(tcp)}, nil
+}
+
+// parseAddrs parses the multiaddrs addrs
+func parseAddrs(addrs []string) ([]ma.Multiaddr, error) {
+	result := make([]ma.Multiaddr, 0, len(addrs))
+	for _, a := range addrs {
+		addr, err := ma.NewMultiaddr(a)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, addr)
+	}
+	return result, nil
+}
+
+// FullAddrs returns every address the node can be dialed at, along with its ID.
+// These are the announce addresses if any are configured, otherwise the addresses of all transports,
+// network interfaces and relays in use.
+func (h *Host) FullAddrs() []string {
+	addrs := dialableAddrs(h.P2PHost.Addrs())
+	result := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		result = append(result, p2pAddr(addr, h.P2PHost.ID()))
+	}
+	return result
+}
+
+// FullAddr returns the address the other nodes are best to dial the node at.
+// It's a public address if the node has one, otherwise a relay address if the node is behind NAT
+// and has found relays, otherwise a private address.
+func (h *Host) FullAddr() string {
+	return fullAddr(h.P2PHost.ID(), h.P2PHost.Addrs())
+}
+
+// fullAddr picks the best address of the node ID out of its addresses addrs
+func fullAddr(ID peer.ID, addrs []ma.Multiaddr) string {
+	var relayAddr, privateAddr ma.Multiaddr
+	for _, addr := range dialableAddrs(addrs) {
+		_, err := addr.ValueForProtocol(circuit.P_CIRCUIT)
+		switch {
+		case err == nil:
+			if relayAddr == nil {
+				relayAddr = addr
+			}
+		case manet.IsPublicAddr(addr):
+			return p2pAddr(addr, ID)
+		case privateAddr == nil:
+			privateAddr = addr
+		}
+	}
+	if relayAddr != nil {
+		return p2pAddr(relayAddr, ID)
+	}
+	if privateAddr != nil {
+		return p2pAddr(privateAddr, ID)
+	}
+	return "/ipfs/" + ID.Pretty()
+}
+
+// dialableAddrs returns the addresses out of addrs that have a transport port.
+// The bare /p2p-circuit listen address and the observed addresses of a node behind NAT can't be dialed.
+func dialableAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	result := make([]ma.Multiaddr, 0, len(addrs))
+	for _, addr := range addrs {
+		if _, err := addr.ValueForProtocol(ma.P_TCP); err == nil {
+			result = append(result, addr)
+		}
+	}
+	return result
+}
+
+// p2pAddr appends the ID to the address addr
+func p2pAddr(addr ma.Multiaddr, ID peer.ID) string {
+	return fmt.Sprintf("%s/ipfs/%s", addr, ID.Pretty())
+}
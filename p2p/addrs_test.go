@@ -0,0 +1,94 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	addrsTestHost1, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenAddrs: []string{"/ip4/127.0.0.1/tcp/10371", "/ip6/::1/tcp/10371", "/ip4/127.0.0.1/tcp/10372/ws"}},
+	})
+	addrsTestHost2, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10373, ListenAddress: "127.0.0.1", AnnounceAddrs: []string{"/ip4/8.8.8.8/tcp/4001"}},
+	})
+)
+
+func TestListenAddrs(t *testing.T) {
+	addrs, err := listenAddrs(&config.P2P{ListenAddress: "::", ListenPort: 10209})
+	assert.NoError(t, err)
+	assert.Equal(t, "/ip6/::/tcp/10209", addrs[0].String())
+
+	addrs, err = listenAddrs(&config.P2P{ListenPort: 10209})
+	assert.NoError(t, err)
+	assert.Equal(t, "/ip4/0.0.0.0/tcp/10209", addrs[0].String())
+
+	_, err = listenAddrs(&config.P2P{ListenAddrs: []string{"/ip4/0.0.0.0/tcp"}})
+	assert.Error(t, err)
+}
+
+func TestFullAddrs(t *testing.T) {
+	ID := addrsTestHost1.P2PHost.ID().Pretty()
+	assert.ElementsMatch(t, []string{
+		"/ip4/127.0.0.1/tcp/10371/ipfs/" + ID,
+		"/ip6/::1/tcp/10371/ipfs/" + ID,
+		"/ip4/127.0.0.1/tcp/10372/ws/ipfs/" + ID,
+	}, addrsTestHost1.FullAddrs())
+
+	// The announce addresses replace the listen addresses
+	ID = addrsTestHost2.P2PHost.ID().Pretty()
+	assert.Equal(t, []string{"/ip4/8.8.8.8/tcp/4001/ipfs/" + ID}, addrsTestHost2.FullAddrs())
+	assert.Equal(t, "/ip4/8.8.8.8/tcp/4001/ipfs/"+ID, addrsTestHost2.FullAddr())
+}
+
+func TestWebSocketTransport(t *testing.T) {
+	ws, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/10372/ws")
+	err := addrsTestHost2.P2PHost.Connect(context.Background(), ps.PeerInfo{ID: addrsTestHost1.P2PHost.ID(), Addrs: []ma.Multiaddr{ws}})
+	assert.NoError(t, err)
+	conns := addrsTestHost2.P2PHost.Network().ConnsToPeer(addrsTestHost1.P2PHost.ID())
+	if assert.Len(t, conns, 1) {
+		assert.Equal(t, ws, conns[0].RemoteMultiaddr())
+	}
+}
+
+func TestFullAddr(t *testing.T) {
+	ID := natTestClient.P2PHost.ID()
+	private, _ := ma.NewMultiaddr("/ip4/192.168.1.2/tcp/10362")
+	observed, _ := ma.NewMultiaddr("/ip4/8.8.8.8")
+	public, _ := ma.NewMultiaddr("/ip4/8.8.8.8/tcp/10362")
+	circuitListen, _ := ma.NewMultiaddr("/p2p-circuit")
+	relay, _ := ma.NewMultiaddr(fmt.Sprintf("/ip4/8.8.4.4/tcp/4001/ipfs/%s/p2p-circuit", natTestRelay.P2PHost.ID().Pretty()))
+
+	// Without a public address a private address is used
+	assert.Equal(t, private.String()+"/ipfs/"+ID.Pretty(),
+		fullAddr(ID, []ma.Multiaddr{circuitListen, private, observed}))
+	// Behind NAT the relay address is advertised
+	assert.Equal(t, relay.String()+"/ipfs/"+ID.Pretty(),
+		fullAddr(ID, []ma.Multiaddr{circuitListen, private, observed, relay}))
+	// A public address is preferred over a relay address
+	assert.Equal(t, public.String()+"/ipfs/"+ID.Pretty(),
+		fullAddr(ID, []ma.Multiaddr{relay, public}))
+}
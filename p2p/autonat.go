@@ -0,0 +1,197 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/log"
+
+	ggio "github.com/gogo/protobuf/io"
+	libp2p "github.com/libp2p/go-libp2p"
+	autonat "github.com/libp2p/go-libp2p-autonat"
+	pb "github.com/libp2p/go-libp2p-autonat/pb"
+	circuit "github.com/libp2p/go-libp2p-circuit"
+	host "github.com/libp2p/go-libp2p-host"
+	pnet "github.com/libp2p/go-libp2p-interface-pnet"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// autonatDialTimeout is how long the service tries to dial back a peer
+	autonatDialTimeout = 15 * time.Second
+	// autonatMaxDials is the maximum number of peers dialed back at the same time
+	autonatMaxDials = 16
+)
+
+var (
+	errDialRefused = errors.New("The dial back was refused")
+	errBadRequest  = errors.New("The dial back request is malformed")
+)
+
+// AutoNATService answers the AutoNAT requests of other nodes, so that they find out if they are behind NAT.
+// A peer is dialed back from a separate host, so that the connection it made to the node isn't reused,
+// and only at the addresses with the IP the peer connected from, so that the service can't be used
+// to make the node dial third parties.
+type AutoNATService struct {
+	host   host.Host
+	dialer host.Host
+	mu     sync.Mutex
+	// dialing holds the peers being dialed back, each peer can have a single dial back at a time
+	dialing map[peer.ID]struct{}
+}
+
+// NewAutoNATService creates an AutoNATService for the p2pHost.
+// protector is the protector of the private network the host is in, or nil.
+func NewAutoNATService(ctx context.Context, p2pHost host.Host, protector pnet.Protector) (*AutoNATService, error) {
+	opts := []libp2p.Option{libp2p.NoListenAddrs, libp2p.DisableRelay()}
+	if protector != nil {
+		opts = append(opts, libp2p.PrivateNetwork(protector))
+	}
+	dialer, err := libp2p.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	as := &AutoNATService{host: p2pHost, dialer: dialer, dialing: make(map[peer.ID]struct{})}
+	p2pHost.SetStreamHandler(autonat.AutoNATProto, as.handleStream)
+	return as, nil
+}
+
+// Close stops answering AutoNAT requests and closes the dialer host
+func (as *AutoNATService) Close() error {
+	as.host.RemoveStreamHandler(autonat.AutoNATProto)
+	return as.dialer.Close()
+}
+
+// handleStream answers the dial back request of a peer
+func (as *AutoNATService) handleStream(s inet.Stream) {
+	defer s.Close()
+	r := ggio.NewDelimitedReader(s, inet.MessageSizeMax)
+	w := ggio.NewDelimitedWriter(s)
+
+	var req pb.Message
+	if err := r.ReadMsg(&req); err != nil || req.GetType() != pb.Message_DIAL {
+		s.Reset()
+		return
+	}
+	resp := as.dialBack(s.Conn().RemotePeer(), s.Conn().RemoteMultiaddr(), req.GetDial().GetPeer())
+	res := &pb.Message{Type: pb.Message_DIAL_RESPONSE.Enum(), DialResponse: resp}
+	if err := w.WriteMsg(res); err != nil {
+		log.Println("Couldn't send the AutoNAT response. Error: ", err)
+		s.Reset()
+	}
+}
+
+// dialBack dials the peer pID, which connected from the address observed, at the addresses of info
+func (as *AutoNATService) dialBack(pID peer.ID, observed ma.Multiaddr, info *pb.Message_PeerInfo) *pb.Message_DialResponse {
+	if info == nil || peer.ID(info.GetId()) != pID {
+		return dialResponse(pb.Message_E_BAD_REQUEST, errBadRequest, nil)
+	}
+	addrs := dialBackAddrs(observed, info.GetAddrs())
+	if len(addrs) == 0 {
+		return dialResponse(pb.Message_E_DIAL_ERROR, errDialRefused, nil)
+	}
+	if !as.startDial(pID) {
+		return dialResponse(pb.Message_E_DIAL_REFUSED, errDialRefused, nil)
+	}
+	defer as.endDial(pID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), autonatDialTimeout)
+	defer cancel()
+	// Only the addresses of the request are dialed, not the ones the dialer learned earlier
+	as.dialer.Peerstore().ClearAddrs(pID)
+	if err := as.dialer.Connect(ctx, ps.PeerInfo{ID: pID, Addrs: addrs}); err != nil {
+		return dialResponse(pb.Message_E_DIAL_ERROR, err, nil)
+	}
+	defer as.dialer.Network().ClosePeer(pID)
+	conns := as.dialer.Network().ConnsToPeer(pID)
+	if len(conns) == 0 {
+		return dialResponse(pb.Message_E_INTERNAL_ERROR, errDialRefused, nil)
+	}
+	return dialResponse(pb.Message_OK, nil, conns[0].RemoteMultiaddr())
+}
+
+// startDial marks pID as being dialed back. It returns false if pID is already being dialed
+// or too many peers are being dialed.
+func (as *AutoNATService) startDial(pID peer.ID) bool {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if _, ok := as.dialing[pID]; ok || len(as.dialing) >= autonatMaxDials {
+		return false
+	}
+	as.dialing[pID] = struct{}{}
+	return true
+}
+
+// endDial marks the dial back of pID as done
+func (as *AutoNATService) endDial(pID peer.ID) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	delete(as.dialing, pID)
+}
+
+// dialBackAddrs returns the addresses out of addrs that have the same IP as the observed address,
+// along with the observed address itself. Relay addresses are skipped, they would always be dialable.
+func dialBackAddrs(observed ma.Multiaddr, addrs [][]byte) []ma.Multiaddr {
+	ip := addrIP(observed)
+	if ip == "" {
+		return nil
+	}
+	result := []ma.Multiaddr{observed}
+	for _, b := range addrs {
+		addr, err := ma.NewMultiaddrBytes(b)
+		if err != nil {
+			continue
+		}
+		if _, err := addr.ValueForProtocol(circuit.P_CIRCUIT); err == nil {
+			continue
+		}
+		if addrIP(addr) != ip || addr.Equal(observed) {
+			continue
+		}
+		result = append(result, addr)
+	}
+	return result
+}
+
+// addrIP returns the IPv4 or IPv6 address of addr, or an empty string if it has none
+func addrIP(addr ma.Multiaddr) string {
+	if ip, err := addr.ValueForProtocol(ma.P_IP4); err == nil {
+		return ip
+	}
+	ip, _ := addr.ValueForProtocol(ma.P_IP6)
+	return ip
+}
+
+// dialResponse creates a dial back response of the status, the error err and the dialed address addr
+func dialResponse(status pb.Message_ResponseStatus, err error, addr ma.Multiaddr) *pb.Message_DialResponse {
+	resp := &pb.Message_DialResponse{Status: status.Enum()}
+	if err != nil {
+		text := err.Error()
+		resp.StatusText = &text
+	}
+	if addr != nil {
+		resp.Addr = addr.Bytes()
+	}
+	return resp
+}
@@ -0,0 +1,270 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+
+	"github.com/crowdcompute/crowdengine/database"
+	"github.com/crowdcompute/crowdengine/log"
+	"github.com/crowdcompute/crowdengine/manager"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	uuid "github.com/satori/go.uuid"
+)
+
+// pattern: /protocol-name/request-or-response-message, which gets a /version suffix from protocolVersions
+const cancelRequest = "/task/cancelreq"
+const cancelResponse = "/task/cancelresp"
+const jobStatus = "/task/jobstatus"
+
+var (
+	// ErrJobNotFound is returned when the node runs no job with the ID of a cancel request
+	ErrJobNotFound = errors.New("The job was not found")
+	// ErrNotJobOwner is returned when a peer or an account cancels a job it doesn't own
+	ErrNotJobOwner = errors.New("The job doesn't belong to the requester")
+	// ErrJobEnded is returned when cancelling a job that already ended
+	ErrJobEnded = errors.New("The job already ended")
+	// ErrJobCancelled is why a job cancelled by its owner ended
+	ErrJobCancelled = errors.New("The job was cancelled by its owner")
+	// ErrJobExpired is why a job that ran out of time ended
+	ErrJobExpired = errors.New("The job ran out of time")
+	// ErrAvailabilityClosed is why a job ended when the availability window of its node closed
+	ErrAvailabilityClosed = errors.New("The availability window of the node closed")
+)
+
+// CancelFuture is the pending response of a cancel job request
+type CancelFuture struct {
+	*Future
+}
+
+// Wait waits for the worker to cancel the job
+func (f *CancelFuture) Wait(ctx context.Context) error {
+	_, err := f.Future.Wait(ctx)
+	return err
+}
+
+// CancelJob asks the hostID node to cancel the job jobID of the account owner.
+// The worker confirms the cancellation to the returned future.
+func (p *TaskProtocol) CancelJob(hostID peer.ID, owner, jobID string) (*CancelFuture, error) {
+	log.Printf("%s: Asking to cancel job %s. Sending request to: %s....", p.p2pHost.ID(), jobID, hostID)
+	req := &api.CancelRequest{RunImageMsgData: NewRunImageMsgData(uuid.Must(uuid.NewV4(), nil).String(), true, p.p2pHost),
+		JobID: jobID,
+		Owner: owner}
+
+	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
+	req.RunImageMsgData.MessageData.Sign = signProtoMsg(req, key)
+
+	future := p.cancels.add(req.RunImageMsgData.MessageData.Id, hostID)
+	if !sendMsg(p.p2pHost, hostID, req, protocol.ID(cancelRequest)) {
+		future.Cancel()
+		return nil, ErrRequestNotSent
+	}
+	return &CancelFuture{future}, nil
+}
+
+// CancelLocalJob cancels the job jobID this node runs
+func (p *TaskProtocol) CancelLocalJob(jobID string) error {
+	job, err := database.GetJobFromDB(jobID)
+	if err != nil || job.Worker != p.p2pHost.ID().Pretty() {
+		return ErrJobNotFound
+	}
+	return p.cancel(job)
+}
+
+// cancel stops the job, which waitForJobToFinish then records and notifies to the requester
+func (p *TaskProtocol) cancel(job *database.Job) error {
+	if job.State.Terminal() {
+		return ErrJobEnded
+	}
+	_, err := manager.GetInstance().StopJob(job.ID, database.JobCancelled, ErrJobCancelled)
+	if err == database.ErrInvalidTransition {
+		return ErrJobEnded
+	}
+	return err
+}
+
+// remote peer requests handler
+func (p *TaskProtocol) onCancelRequest(s inet.Stream) {
+	from := s.Conn().RemotePeer()
+	log.Printf("%s: Received cancel job request from %s.", s.Conn().LocalPeer(), from)
+	// Requests over the limits are rejected without being handled, so that the requester can retry them later
+	data := &api.CancelRequest{}
+	release, err := p.limiter.Acquire(from, cancelRequest)
+	if err != nil {
+		log.Println("Rejecting cancel job request. Error: ", err)
+		if p.limiter.Decode(from, data, s) == nil && data.RunImageMsgData != nil {
+			p.sendCancelResponse(from, data.RunImageMsgData.MessageData.Id, data.JobID, newProtoError(api.ErrorCode_ResourceExhausted, true, err))
+		}
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(from, data, s); err != nil || data.RunImageMsgData == nil {
+		log.Println("Failed to decode cancel job request")
+		return
+	}
+	requestID := data.RunImageMsgData.MessageData.Id
+
+	if valid := authenticatePeerMsg(from, data, data.RunImageMsgData.MessageData, p.replay, p.reputation); !valid {
+		log.Println("Failed to authenticate message")
+		p.sendCancelResponse(from, requestID, data.JobID, newProtoError(api.ErrorCode_Unauthenticated, false, ErrNotAuthenticated))
+		return
+	}
+	job, err := database.GetJobFromDB(data.JobID)
+	if err != nil || job.Worker != p.p2pHost.ID().Pretty() {
+		p.sendCancelResponse(from, requestID, data.JobID, newProtoError(api.ErrorCode_NotFound, false, ErrJobNotFound))
+		return
+	}
+	// Only the account that owns the job can cancel it, through the peer that requested it
+	if job.Requester != from.Pretty() || job.Owner != data.Owner {
+		log.Printf("Rejecting cancel job request. %s doesn't own job %s", from, job.ID)
+		p.sendCancelResponse(from, requestID, data.JobID, newProtoError(api.ErrorCode_Unauthenticated, false, ErrNotJobOwner))
+		return
+	}
+	if err := p.cancel(job); err == ErrJobEnded {
+		p.sendCancelResponse(from, requestID, data.JobID, newProtoError(api.ErrorCode_InvalidRequest, false, err))
+		return
+	} else if err != nil {
+		log.Errorf("Error cancelling job %s. Error: %s", job.ID, err)
+		p.sendCancelResponse(from, requestID, data.JobID, newProtoError(api.ErrorCode_Internal, false, err))
+		return
+	}
+	log.Printf("Job %s was cancelled by its owner", job.ID)
+	p.sendCancelResponse(from, requestID, data.JobID, nil)
+}
+
+// sendCancelResponse responds to the cancel request requestID of the toPeer node
+// respErr is set if the job wasn't cancelled
+func (p *TaskProtocol) sendCancelResponse(toPeer peer.ID, requestID, jobID string, respErr *api.Error) bool {
+	resp := &api.CancelResponse{RunImageMsgData: NewRunImageMsgData(requestID, false, p.p2pHost),
+		JobID: jobID,
+		Error: respErr}
+
+	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
+	resp.RunImageMsgData.MessageData.Sign = signProtoMsg(resp, key)
+	return sendMsg(p.p2pHost, toPeer, resp, protocol.ID(cancelResponse))
+}
+
+// remote cancel response handler
+func (p *TaskProtocol) onCancelResponse(s inet.Stream) {
+	from := s.Conn().RemotePeer()
+	data := &api.CancelResponse{}
+	release, err := p.limiter.Acquire(from, cancelResponse)
+	if err != nil {
+		log.Println("Dropping cancel job response. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(from, data, s); err != nil || data.RunImageMsgData == nil {
+		log.Println("Failed to decode cancel job response")
+		p.reputation.Record(from, InvalidResponse)
+		return
+	}
+	if valid := authenticatePeerMsg(from, data, data.RunImageMsgData.MessageData, p.replay, p.reputation); !valid {
+		log.Println("Failed to authenticate message")
+		return
+	}
+	if !p.cancels.resolve(data.RunImageMsgData.MessageData.Id, from, data) {
+		log.Println("No pending cancel request for this response. Dropping it...")
+		return
+	}
+	if data.Error == nil {
+		p.recordEnd(from, data.JobID, database.JobCancelled, ErrJobCancelled.Error(), 0)
+	}
+}
+
+// notifyRequester notifies the requester of the job that the job ended
+func (p *TaskProtocol) notifyRequester(job *database.Job) {
+	if job == nil || job.Requester == p.p2pHost.ID().Pretty() {
+		return
+	}
+	requester, err := peer.IDB58Decode(job.Requester)
+	if err != nil {
+		log.Errorf("Error decoding the requester of job %s. Error: %s", job.ID, err)
+		return
+	}
+	msg := &api.JobStatus{RunImageMsgData: NewRunImageMsgData(uuid.Must(uuid.NewV4(), nil).String(), false, p.p2pHost),
+		JobID:    job.ID,
+		State:    string(job.State),
+		Error:    job.Error,
+		ExitCode: int32(job.ExitCode)}
+
+	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
+	msg.RunImageMsgData.MessageData.Sign = signProtoMsg(msg, key)
+	if sendMsg(p.p2pHost, requester, msg, protocol.ID(jobStatus)) {
+		log.Printf("%s: Notified %s that job %s is %s", p.p2pHost.ID(), requester, job.ID, job.State)
+	}
+}
+
+// remote job status handler
+func (p *TaskProtocol) onJobStatus(s inet.Stream) {
+	from := s.Conn().RemotePeer()
+	data := &api.JobStatus{}
+	release, err := p.limiter.Acquire(from, jobStatus)
+	if err != nil {
+		log.Println("Dropping job status. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(from, data, s); err != nil || data.RunImageMsgData == nil {
+		log.Println("Failed to decode job status")
+		p.reputation.Record(from, InvalidResponse)
+		return
+	}
+	if valid := authenticatePeerMsg(from, data, data.RunImageMsgData.MessageData, p.replay, p.reputation); !valid {
+		log.Println("Failed to authenticate message")
+		return
+	}
+	state := database.JobState(data.State)
+	if !state.Terminal() {
+		log.Printf("Dropping job status of job %s. %s is not a terminal state", data.JobID, state)
+		return
+	}
+	p.recordEnd(from, data.JobID, state, data.Error, int(data.ExitCode))
+}
+
+// recordEnd records that the job jobID its worker runs ended in the terminal state
+func (p *TaskProtocol) recordEnd(worker peer.ID, jobID string, state database.JobState, reason string, exitCode int) {
+	job, err := database.GetJobFromDB(jobID)
+	if err != nil || job.Worker != worker.Pretty() {
+		log.Println("No job was requested from this peer with this ID")
+		return
+	}
+	if job.State.Terminal() {
+		return
+	}
+	_, err = manager.UpdateJob(jobID, state, func(job *database.Job) {
+		job.Error = reason
+		job.ExitCode = exitCode
+	})
+	if err != nil {
+		log.Errorf("Error recording the end of job %s. Error: %s", jobID, err)
+		return
+	}
+	log.Printf("Job %s that %s ran is %s", jobID, worker, state)
+	// Cancelled and expired jobs were stopped on purpose, so only the failures are the worker's
+	switch state {
+	case database.JobSucceeded:
+		p.reputation.Record(worker, JobSucceeded)
+	case database.JobFailed:
+		p.reputation.Record(worker, JobFailed)
+	}
+}
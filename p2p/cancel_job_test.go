@@ -0,0 +1,149 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	"github.com/crowdcompute/crowdengine/database"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	cancelTestRequester, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10394, ListenAddress: "127.0.0.1"},
+	})
+	cancelTestWorker, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10395, ListenAddress: "127.0.0.1"},
+	})
+)
+
+// storeTestJob stores a job the worker runs for the requester
+func storeTestJob(t *testing.T, id, owner string, state database.JobState) {
+	assert.NoError(t, database.StoreJobToDB(&database.Job{ID: id,
+		Owner:     owner,
+		Requester: cancelTestRequester.P2PHost.ID().Pretty(),
+		Worker:    cancelTestWorker.P2PHost.ID().Pretty(),
+		State:     state}))
+}
+
+// cancelTestJob asks the worker to cancel the job id of the owner
+func cancelTestJob(t *testing.T, owner, id string) error {
+	future, err := cancelTestRequester.CancelJob(cancelTestWorker.P2PHost.ID(), owner, id)
+	if !assert.NoError(t, err) {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return future.Wait(ctx)
+}
+
+// assertResponseError asserts that err is the error response with code and message
+func assertResponseError(t *testing.T, code api.ErrorCode, message error, err error) {
+	if assert.IsType(t, &ResponseError{}, err) {
+		assert.Equal(t, code, err.(*ResponseError).Code)
+		assert.Equal(t, message.Error(), err.(*ResponseError).Message)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	err := cancelTestRequester.P2PHost.Connect(context.Background(), peerInfo(cancelTestWorker))
+	assert.NoError(t, err)
+	storeTestJob(t, "cancel-queued", "alice", database.JobQueued)
+	storeTestJob(t, "cancel-done", "alice", database.JobSucceeded)
+
+	// Only the owner of the job can cancel it
+	assertResponseError(t, api.ErrorCode_Unauthenticated, ErrNotJobOwner, cancelTestJob(t, "mallory", "cancel-queued"))
+	assertResponseError(t, api.ErrorCode_NotFound, ErrJobNotFound, cancelTestJob(t, "alice", "cancel-unknown"))
+	assertResponseError(t, api.ErrorCode_InvalidRequest, ErrJobEnded, cancelTestJob(t, "alice", "cancel-done"))
+
+	assert.NoError(t, cancelTestJob(t, "alice", "cancel-queued"))
+	job, err := database.GetJobFromDB("cancel-queued")
+	if assert.NoError(t, err) {
+		assert.Equal(t, database.JobCancelled, job.State)
+		assert.Equal(t, ErrJobCancelled.Error(), job.Error)
+		assert.NotZero(t, job.FinishedTime)
+	}
+}
+
+func TestCancelJobOverLimits(t *testing.T) {
+	err := cancelTestRequester.P2PHost.Connect(context.Background(), peerInfo(cancelTestWorker))
+	assert.NoError(t, err)
+	storeTestJob(t, "cancel-limited", "alice", database.JobQueued)
+	for i := 0; i < defaultMaxConcurrent; i++ {
+		release, err := cancelTestWorker.Limiter.Acquire(cancelTestRequester.P2PHost.ID(), cancelRequest)
+		assert.NoError(t, err)
+		defer release()
+	}
+
+	// The request is rejected before it's handled, so the job is still queued
+	err = cancelTestJob(t, "alice", "cancel-limited")
+	assertResponseError(t, api.ErrorCode_ResourceExhausted, ErrTooManyConcurrent, err)
+	assert.True(t, err.(*ResponseError).Retryable)
+	job, err := database.GetJobFromDB("cancel-limited")
+	if assert.NoError(t, err) {
+		assert.Equal(t, database.JobQueued, job.State)
+	}
+}
+
+func TestNotifyRequester(t *testing.T) {
+	err := cancelTestWorker.P2PHost.Connect(context.Background(), peerInfo(cancelTestRequester))
+	assert.NoError(t, err)
+	storeTestJob(t, "notify", "alice", database.JobRunning)
+	worker := cancelTestWorker.P2PHost.ID()
+	failed := cancelTestRequester.Reputation.Peer(worker).Record.JobsFailed
+	cancelTestWorker.notifyRequester(&database.Job{ID: "notify",
+		Requester: cancelTestRequester.P2PHost.ID().Pretty(),
+		State:     database.JobFailed,
+		Error:     "The container of the job exited with code 3",
+		ExitCode:  3})
+
+	// The requester records the terminal state the worker notified, and the job outcome of the worker
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if cancelTestRequester.Reputation.Peer(worker).Record.JobsFailed > failed {
+			break
+		}
+	}
+	assert.Equal(t, failed+1, cancelTestRequester.Reputation.Peer(worker).Record.JobsFailed)
+	job, err := database.GetJobFromDB("notify")
+	if assert.NoError(t, err) {
+		assert.Equal(t, database.JobFailed, job.State)
+		assert.Equal(t, 3, job.ExitCode)
+	}
+}
+
+func TestJobExpires(t *testing.T) {
+	self := cancelTestWorker.P2PHost.ID().Pretty()
+	assert.NoError(t, database.StoreJobToDB(&database.Job{ID: "expire", Requester: self, Worker: self, State: database.JobRunning, Timeout: 1}))
+
+	// The job is past its deadline, so it expires before its container is checked
+	cancelTestWorker.waitForJobToFinish("expire", "container", time.Now())
+	job, err := database.GetJobFromDB("expire")
+	if assert.NoError(t, err) {
+		assert.Equal(t, database.JobExpired, job.State)
+		assert.Equal(t, ErrJobExpired.Error(), job.Error)
+	}
+	assert.Equal(t, time.Unix(100, 0).Add(time.Second), jobDeadline(job, time.Unix(100, 0)))
+	job.Timeout = 0
+	assert.True(t, jobDeadline(job, time.Now()).IsZero())
+}
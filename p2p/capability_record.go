@@ -0,0 +1,267 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/common"
+	"github.com/crowdcompute/crowdengine/log"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/gogo/protobuf/proto"
+	cid "github.com/ipfs/go-cid"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	record "github.com/libp2p/go-libp2p-record"
+	mh "github.com/multiformats/go-multihash"
+)
+
+const (
+	// capabilityNamespace is the DHT namespace of the nodes' capability records
+	capabilityNamespace = "ccnode"
+	// capabilityClassPrefix prefixes the well-known DHT keys of the capability classes
+	capabilityClassPrefix = "/crowdcompute/capability/"
+	// minMemoryClass is the smallest memory class in MB
+	minMemoryClass = 256
+	// minStorageClass is the smallest storage class in MB
+	minStorageClass = 1024
+)
+
+var (
+	// ErrCapabilityWrongKey is returned when a capability record is stored under another node's key
+	ErrCapabilityWrongKey = errors.New("The capability record doesn't belong to the node of its key")
+	// ErrCapabilitySignature is returned when a capability record isn't signed by its node
+	ErrCapabilitySignature = errors.New("The capability record has an invalid signature")
+	// ErrCapabilityExpired is returned when a capability record expired
+	ErrCapabilityExpired = errors.New("The capability record expired")
+)
+
+// CapabilityRecordKey returns the DHT key of the capability record of the node nodeID
+func CapabilityRecordKey(nodeID peer.ID) string {
+	return "/" + capabilityNamespace + "/" + string(nodeID)
+}
+
+// CapabilityClasses returns the capability classes the record's node belongs to.
+// A class is a key like "arch/amd64", "cpu/4", "memory/2048", "storage/4096", "label/gpu=nvidia" or "price/8".
+// Resources are classed in powers of two and a node belongs to every class up to its resources,
+// e.g. a node with 4 CPUs to cpu/1, cpu/2 and cpu/4. A node belongs only to the price class of the
+// smallest power of two not below its price.
+func CapabilityClasses(rec *api.CapabilityRecord) []string {
+	classes := make([]string, 0)
+	if rec.Arch != "" {
+		classes = append(classes, "arch/"+rec.Arch)
+	}
+	for c := uint64(1); c <= uint64(rec.Cpu); c *= 2 {
+		classes = append(classes, fmt.Sprintf("cpu/%d", c))
+	}
+	for m := uint64(minMemoryClass); m <= rec.Memory; m *= 2 {
+		classes = append(classes, fmt.Sprintf("memory/%d", m))
+	}
+	for s := uint64(minStorageClass); s <= rec.Storage; s *= 2 {
+		classes = append(classes, fmt.Sprintf("storage/%d", s))
+	}
+	for _, label := range rec.Labels {
+		classes = append(classes, "label/"+label)
+	}
+	priceClass := uint64(0)
+	if rec.Price > 0 {
+		priceClass = 1
+		for priceClass < rec.Price {
+			priceClass *= 2
+		}
+	}
+	return append(classes, fmt.Sprintf("price/%d", priceClass))
+}
+
+// capabilityClassCid returns the content ID the providers of the capability class are found under
+func capabilityClassCid(class string) (cid.Cid, error) {
+	hash, err := mh.Sum([]byte(capabilityClassPrefix+class), mh.SHA2_256, -1)
+	if err != nil {
+		return cid.Cid{}, err
+	}
+	return cid.NewCidV1(cid.Raw, hash), nil
+}
+
+// CapabilityValidator validates the capability records stored in the DHT
+type CapabilityValidator struct{}
+
+// Validate checks that the capability record value is signed by the node of the key and hasn't expired
+func (v CapabilityValidator) Validate(key string, value []byte) error {
+	ns, nodeID, err := record.SplitKey(key)
+	if err != nil {
+		return err
+	}
+	if ns != capabilityNamespace {
+		return record.ErrInvalidRecordType
+	}
+	rec := &api.CapabilityRecord{}
+	if err := proto.Unmarshal(value, rec); err != nil {
+		return err
+	}
+	if rec.NodeId != peer.ID(nodeID).Pretty() {
+		return ErrCapabilityWrongKey
+	}
+	if !verifyCapabilityRecord(rec) {
+		return ErrCapabilitySignature
+	}
+	if rec.Expiry < time.Now().Unix() {
+		return ErrCapabilityExpired
+	}
+	return nil
+}
+
+// Select selects the most recently published capability record
+func (v CapabilityValidator) Select(key string, values [][]byte) (int, error) {
+	best, bestTimestamp := -1, int64(0)
+	for i, value := range values {
+		rec := &api.CapabilityRecord{}
+		if err := proto.Unmarshal(value, rec); err != nil {
+			continue
+		}
+		if best == -1 || rec.Timestamp > bestTimestamp {
+			best, bestTimestamp = i, rec.Timestamp
+		}
+	}
+	if best == -1 {
+		return 0, errors.New("No valid capability record to select from")
+	}
+	return best, nil
+}
+
+// verifyCapabilityRecord verifies that the record rec is signed by its node
+func verifyCapabilityRecord(rec *api.CapabilityRecord) bool {
+	sign := rec.Sign
+	rec.Sign = nil
+	bin, err := proto.Marshal(rec)
+	rec.Sign = sign
+	if err != nil {
+		return false
+	}
+	peerID, err := peer.IDB58Decode(rec.NodeId)
+	if err != nil {
+		return false
+	}
+	return verifyData(bin, sign, peerID, rec.NodePubKey)
+}
+
+// capabilityRecord returns the node's signed capability record as of now
+func (h *Host) capabilityRecord(now time.Time) (*api.CapabilityRecord, error) {
+	pID := h.P2PHost.ID()
+	nodePubKey, err := h.P2PHost.Peerstore().PubKey(pID).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	cpu := h.Cfg.Host.CPUPerContainer
+	if cpu <= 0 {
+		cpu = runtime.NumCPU()
+	}
+	rec := &api.CapabilityRecord{
+		NodeId:     pID.Pretty(),
+		NodePubKey: nodePubKey,
+		Cpu:        uint32(cpu),
+		Memory:     uint64(h.Cfg.Host.MemoryPerContainer),
+		Storage:    uint64(h.Cfg.Host.StoragePerContainer),
+		Arch:       runtime.GOARCH,
+		Labels:     h.Cfg.Host.Labels,
+		Price:      h.Cfg.Host.Price,
+		Timestamp:  now.UnixNano(),
+		Expiry:     now.Add(common.CapabilityRecordTTL).Unix(),
+	}
+	rec.Sign = signProtoMsg(rec, h.P2PHost.Peerstore().PrivKey(pID))
+	return rec, nil
+}
+
+// publishCapabilities stores the node's capability record in the DHT and
+// announces the node as a provider of all its capability classes
+func (h *Host) publishCapabilities(ctx context.Context) error {
+	rec, err := h.capabilityRecord(time.Now())
+	if err != nil {
+		return err
+	}
+	value, err := proto.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := h.dht.PutValue(ctx, CapabilityRecordKey(h.P2PHost.ID()), value); err != nil {
+		return err
+	}
+	for _, class := range CapabilityClasses(rec) {
+		classCid, err := capabilityClassCid(class)
+		if err != nil {
+			return err
+		}
+		if err := h.dht.Provide(ctx, classCid, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PublishCapabilities publishes the node's capability record to the DHT
+// and keeps refreshing it before it expires, until quit is closed
+func (h *Host) PublishCapabilities(quit <-chan struct{}) {
+	ticker := time.NewTicker(common.CapabilityRefreshInterval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), common.RequestTimeout)
+		if err := h.publishCapabilities(ctx); err != nil {
+			log.Println("Couldn't publish the capability record. Error: ", err)
+		}
+		cancel()
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// GetCapabilityRecord gets the capability record of the node nodeID from the DHT
+func (h *Host) GetCapabilityRecord(ctx context.Context, nodeID peer.ID) (*api.CapabilityRecord, error) {
+	// The DHT validates the record before returning it
+	value, err := h.dht.GetValue(ctx, CapabilityRecordKey(nodeID))
+	if err != nil {
+		return nil, err
+	}
+	rec := &api.CapabilityRecord{}
+	return rec, proto.Unmarshal(value, rec)
+}
+
+// FindProviders finds up to count nodes of the capability class through the DHT
+// and returns their capability records
+func (h *Host) FindProviders(ctx context.Context, class string, count int) ([]*api.CapabilityRecord, error) {
+	classCid, err := capabilityClassCid(class)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*api.CapabilityRecord, 0, count)
+	for provider := range h.dht.FindProvidersAsync(ctx, classCid, count) {
+		h.P2PHost.Peerstore().AddAddrs(provider.ID, provider.Addrs, ps.TempAddrTTL)
+		rec, err := h.GetCapabilityRecord(ctx, provider.ID)
+		if err != nil {
+			log.Printf("Couldn't get the capability record of %s. Error: %s", provider.ID, err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilityClasses(t *testing.T) {
+	rec := &api.CapabilityRecord{Cpu: 4, Memory: 1024, Storage: 1024, Arch: "amd64", Labels: []string{"gpu=nvidia"}, Price: 5}
+	assert.Equal(t, []string{"arch/amd64", "cpu/1", "cpu/2", "cpu/4", "memory/256", "memory/512", "memory/1024",
+		"storage/1024", "label/gpu=nvidia", "price/8"}, CapabilityClasses(rec))
+}
+
+func TestCapabilityRecordValidation(t *testing.T) {
+	rec, err := commonTestHost1.capabilityRecord(time.Now())
+	assert.Nil(t, err)
+	value, err := proto.Marshal(rec)
+	assert.Nil(t, err)
+
+	validator := CapabilityValidator{}
+	assert.Nil(t, validator.Validate(CapabilityRecordKey(commonTestHost1.P2PHost.ID()), value))
+	// Stored under the key of another node
+	assert.Equal(t, ErrCapabilityWrongKey, validator.Validate(CapabilityRecordKey(commonTestHost2.P2PHost.ID()), value))
+	// Tampered with
+	rec.Price++
+	tampered, _ := proto.Marshal(rec)
+	assert.Equal(t, ErrCapabilitySignature, validator.Validate(CapabilityRecordKey(commonTestHost1.P2PHost.ID()), tampered))
+	// Expired
+	old, _ := commonTestHost1.capabilityRecord(time.Now().Add(-2 * time.Hour))
+	oldValue, _ := proto.Marshal(old)
+	assert.Equal(t, ErrCapabilityExpired, validator.Validate(CapabilityRecordKey(commonTestHost1.P2PHost.ID()), oldValue))
+
+	// The newest record gets selected
+	best, err := validator.Select(CapabilityRecordKey(commonTestHost1.P2PHost.ID()), [][]byte{oldValue, value})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, best)
+}
@@ -0,0 +1,215 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+)
+
+var (
+	// ErrJobDoesNotFit is returned when a job needs more than a single container of the node offers
+	ErrJobDoesNotFit = errors.New("The job doesn't fit in a container of this node")
+	// ErrNoFreeSlot is returned when the node already runs its maximum number of containers
+	ErrNoFreeSlot = errors.New("The node runs its maximum number of containers")
+	// ErrReservationExists is returned when reserving twice with the same ID
+	ErrReservationExists = errors.New("A reservation with this ID already exists")
+)
+
+// capacityProtoError returns the error envelope of a request rejected by the capacity tracker
+func capacityProtoError(err error) *api.Error {
+	switch err {
+	case ErrNoFreeSlot:
+		return newProtoError(api.ErrorCode_ResourceExhausted, true, err)
+	case ErrJobDoesNotFit:
+		return newProtoError(api.ErrorCode_ResourceExhausted, false, err)
+	default:
+		return newProtoError(api.ErrorCode_InvalidRequest, false, err)
+	}
+}
+
+// Resources represents an amount of the node's resources
+type Resources struct {
+	Containers int    `json:"containers"`
+	CPU        uint64 `json:"cpu"`
+	Memory     uint64 `json:"memory"`  // in MB
+	Storage    uint64 `json:"storage"` // in MB
+}
+
+// Capacity represents the total and the reserved resources of the node.
+// A zero total means that the resource is not limited.
+type Capacity struct {
+	Total    Resources `json:"total"`
+	Reserved Resources `json:"reserved"`
+	Jobs     []string  `json:"jobs"` // The IDs of the reservations, which are container IDs once the containers run
+}
+
+// CapacityTracker keeps track of the resources reserved by the jobs of the node
+type CapacityTracker struct {
+	hostCfg      *config.Host
+	reservations map[string]Resources // Reserved resources by reservation ID
+	containers   map[string]struct{}  // The reservations assigned to the ID of their container
+	mu           sync.Mutex
+}
+
+// NewCapacityTracker returns a new CapacityTracker for a node with the hostCfg limits
+func NewCapacityTracker(hostCfg *config.Host) *CapacityTracker {
+	return &CapacityTracker{
+		hostCfg:      hostCfg,
+		reservations: make(map[string]Resources),
+		containers:   make(map[string]struct{}),
+	}
+}
+
+// Fits checks whether a job with the requirements reqs can be admitted right now
+func (c *CapacityTracker) Fits(reqs *api.JobRequirements) bool {
+	if !jobFits(c.hostCfg, reqs) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slotAvailable()
+}
+
+// Reserve reserves a container slot and the resources the job with the requirements reqs needs.
+// The reservation is kept under id until it gets released or reassigned.
+func (c *CapacityTracker) Reserve(id string, reqs *api.JobRequirements) error {
+	if !jobFits(c.hostCfg, reqs) {
+		return ErrJobDoesNotFit
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.reservations[id]; ok {
+		return ErrReservationExists
+	}
+	if !c.slotAvailable() {
+		return ErrNoFreeSlot
+	}
+	c.reservations[id] = c.resourcesFor(reqs)
+	return nil
+}
+
+// Assign moves a reservation from id to newID, e.g. when the reserved job got a container ID
+func (c *CapacityTracker) Assign(id, newID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if res, ok := c.reservations[id]; ok {
+		delete(c.reservations, id)
+		c.reservations[newID] = res
+		c.containers[newID] = struct{}{}
+	}
+}
+
+// Release frees the resources reserved under id
+func (c *CapacityTracker) Release(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.reservations, id)
+	delete(c.containers, id)
+}
+
+// Containers returns the IDs of the containers of the reservations. Reservations of jobs that
+// don't have a container yet are left out.
+func (c *CapacityTracker) Containers() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	containerIDs := make([]string, 0, len(c.containers))
+	for containerID := range c.containers {
+		containerIDs = append(containerIDs, containerID)
+	}
+	return containerIDs
+}
+
+// Capacity returns the total and the currently reserved resources of the node
+func (c *CapacityTracker) Capacity() Capacity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	maxContainers := uint64(c.hostCfg.MaxContainers)
+	capacity := Capacity{
+		Total: Resources{
+			Containers: c.hostCfg.MaxContainers,
+			CPU:        maxContainers * uint64(c.hostCfg.CPUPerContainer),
+			Memory:     maxContainers * uint64(c.hostCfg.MemoryPerContainer),
+			Storage:    maxContainers * uint64(c.hostCfg.StoragePerContainer),
+		},
+		Jobs: make([]string, 0, len(c.reservations)),
+	}
+	for id, res := range c.reservations {
+		capacity.Reserved.Containers += res.Containers
+		capacity.Reserved.CPU += res.CPU
+		capacity.Reserved.Memory += res.Memory
+		capacity.Reserved.Storage += res.Storage
+		capacity.Jobs = append(capacity.Jobs, id)
+	}
+	return capacity
+}
+
+// slotAvailable checks if the node can start one more container without exceeding its MaxContainers.
+// It must be called with the mutex held.
+func (c *CapacityTracker) slotAvailable() bool {
+	return c.hostCfg.MaxContainers <= 0 || len(c.reservations) < c.hostCfg.MaxContainers
+}
+
+// resourcesFor returns the resources reserved for a job with the requirements reqs.
+// Every unspecified requirement reserves the whole per container amount.
+func (c *CapacityTracker) resourcesFor(reqs *api.JobRequirements) Resources {
+	res := Resources{
+		Containers: 1,
+		CPU:        uint64(c.hostCfg.CPUPerContainer),
+		Memory:     uint64(c.hostCfg.MemoryPerContainer),
+		Storage:    uint64(c.hostCfg.StoragePerContainer),
+	}
+	if reqs.GetCpu() > 0 {
+		res.CPU = uint64(reqs.GetCpu())
+	}
+	if reqs.GetMemory() > 0 {
+		res.Memory = reqs.GetMemory()
+	}
+	if reqs.GetStorage() > 0 {
+		res.Storage = reqs.GetStorage()
+	}
+	return res
+}
+
+// jobFits checks whether a job with the requirements reqs fits in a single container
+// of a node with the hostCfg limits, within the time the node allows a job.
+// A zero limit means that the resource is not limited.
+func jobFits(hostCfg *config.Host, reqs *api.JobRequirements) bool {
+	if reqs == nil {
+		return true
+	}
+	if hostCfg.CPUPerContainer > 0 && reqs.Cpu > uint32(hostCfg.CPUPerContainer) {
+		return false
+	}
+	if hostCfg.MemoryPerContainer > 0 && reqs.Memory > uint64(hostCfg.MemoryPerContainer) {
+		return false
+	}
+	if hostCfg.StoragePerContainer > 0 && reqs.Storage > uint64(hostCfg.StoragePerContainer) {
+		return false
+	}
+	if hostCfg.MaxJobTimeout > 0 && reqs.MaxRuntime > uint64(hostCfg.MaxJobTimeout) {
+		return false
+	}
+	if reqs.Arch != "" && reqs.Arch != runtime.GOARCH {
+		return false
+	}
+	return true
+}
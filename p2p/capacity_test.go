@@ -0,0 +1,73 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestJobFits checks that a job fits only when its requirements are within the per container limits and the job timeout
+func TestJobFits(t *testing.T) {
+	hostCfg := &config.Host{MaxContainers: 2, CPUPerContainer: 2, MemoryPerContainer: 1024, StoragePerContainer: 2048, MaxJobTimeout: 600}
+	assert.True(t, jobFits(hostCfg, nil))
+	assert.True(t, jobFits(hostCfg, &api.JobRequirements{Cpu: 2, Memory: 512, Storage: 2048, MaxRuntime: 600, Arch: runtime.GOARCH}))
+	assert.False(t, jobFits(hostCfg, &api.JobRequirements{Cpu: 4}))
+	assert.False(t, jobFits(hostCfg, &api.JobRequirements{Memory: 2048}))
+	assert.False(t, jobFits(hostCfg, &api.JobRequirements{Storage: 4096}))
+	assert.False(t, jobFits(hostCfg, &api.JobRequirements{MaxRuntime: 601}))
+	assert.False(t, jobFits(hostCfg, &api.JobRequirements{Arch: "unknown-arch"}))
+	// Zero limits mean no limit at all
+	assert.True(t, jobFits(&config.Host{}, &api.JobRequirements{Cpu: 64, Memory: 1 << 20, MaxRuntime: 1e6}))
+}
+
+// TestCapacityEnforcesMaxContainers checks that no more than MaxContainers reservations are admitted
+// and that releasing a reservation frees its slot
+func TestCapacityEnforcesMaxContainers(t *testing.T) {
+	capacity := NewCapacityTracker(&config.Host{MaxContainers: 2, CPUPerContainer: 2, MemoryPerContainer: 1024})
+	assert.Nil(t, capacity.Reserve("job1", nil))
+	assert.Nil(t, capacity.Reserve("job2", &api.JobRequirements{Cpu: 1, Memory: 256}))
+	assert.False(t, capacity.Fits(nil))
+	assert.Equal(t, ErrNoFreeSlot, capacity.Reserve("job3", nil))
+
+	capacity.Release("job1")
+	assert.True(t, capacity.Fits(nil))
+	assert.Nil(t, capacity.Reserve("job3", nil))
+}
+
+// TestCapacityAccounting checks the reserved resources reported by the tracker
+func TestCapacityAccounting(t *testing.T) {
+	capacity := NewCapacityTracker(&config.Host{MaxContainers: 4, CPUPerContainer: 2, MemoryPerContainer: 1024})
+	assert.Equal(t, ErrJobDoesNotFit, capacity.Reserve("job1", &api.JobRequirements{Cpu: 3}))
+	assert.Nil(t, capacity.Reserve("job1", nil))
+	assert.Nil(t, capacity.Reserve("job2", &api.JobRequirements{Cpu: 1, Memory: 256}))
+	assert.Equal(t, ErrReservationExists, capacity.Reserve("job2", nil))
+	capacity.Assign("job2", "container2")
+
+	c := capacity.Capacity()
+	assert.Equal(t, Resources{Containers: 4, CPU: 8, Memory: 4096}, c.Total)
+	assert.Equal(t, Resources{Containers: 2, CPU: 3, Memory: 1280}, c.Reserved)
+	assert.ElementsMatch(t, []string{"job1", "container2"}, c.Jobs)
+	// Only the reservations that got a container have one to pause or stop
+	assert.Equal(t, []string{"container2"}, capacity.Containers())
+	capacity.Release("container2")
+	assert.Empty(t, capacity.Containers())
+}
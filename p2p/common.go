@@ -19,6 +19,7 @@ package p2p
 import (
 	"bufio"
 	"context"
+	"io"
 	"time"
 
 	"github.com/crowdcompute/crowdengine/log"
@@ -29,12 +30,13 @@ import (
 
 	crypto "github.com/libp2p/go-libp2p-crypto"
 	host "github.com/libp2p/go-libp2p-host"
-	inet "github.com/libp2p/go-libp2p-net"
 	net "github.com/libp2p/go-libp2p-net"
 	peer "github.com/libp2p/go-libp2p-peer"
 	protocol "github.com/libp2p/go-libp2p-protocol"
 
 	"github.com/gogo/protobuf/proto"
+	msgio "github.com/libp2p/go-msgio"
+	multicodec "github.com/multiformats/go-multicodec"
 	protobufCodec "github.com/multiformats/go-multicodec/protobuf"
 )
 
@@ -74,7 +76,35 @@ func signProtoMsg(message proto.Message, key crypto.PrivKey) []byte {
 // authenticateProtoMsg authenticates incoming p2p message
 // message: a protobufs go data object
 // data: common p2p message data
-func authenticateProtoMsg(message proto.Message, data *api.MessageData) bool {
+// replay: rejects stale and already seen messages, nil to only check the signature
+func authenticateProtoMsg(message proto.Message, data *api.MessageData, replay *ReplayGuard) bool {
+	if !verifyProtoMsg(message, data) {
+		return false
+	}
+	// Only authenticated messages are checked for replays, so that no one can fill the sender's cache
+	if replay != nil && !replay.Check(data, time.Now()) {
+		log.Printf("Rejecting stale or replayed message %s from %s", data.Id, data.NodeId)
+		return false
+	}
+	return true
+}
+
+// authenticatePeerMsg authenticates a p2p message the peer from sent,
+// recording an authentication failure to the reputation of the peer if the signature is invalid
+func authenticatePeerMsg(from peer.ID, message proto.Message, data *api.MessageData, replay *ReplayGuard, reputation *Reputation) bool {
+	if data == nil || !verifyProtoMsg(message, data) {
+		reputation.Record(from, AuthFailure)
+		return false
+	}
+	if replay != nil && !replay.Check(data, time.Now()) {
+		log.Printf("Rejecting stale or replayed message %s from %s", data.Id, data.NodeId)
+		return false
+	}
+	return true
+}
+
+// verifyProtoMsg verifies the signature of a p2p message
+func verifyProtoMsg(message proto.Message, data *api.MessageData) bool {
 	// store a temp ref to signature and remove it from message data
 	// sign is a string to allow easy reset to zero-value (empty string)
 	sign := data.Sign
@@ -136,16 +166,31 @@ func verifyData(data []byte, signature []byte, peerID peer.ID, pubKeyData []byte
 	return res
 }
 
-// decodeProtoMessage receives a pointer to a proto.Message and decodes it's data
-func decodeProtoMessage(message proto.Message, s inet.Stream) error {
-	decoder := protobufCodec.Multicodec(nil).Decoder(bufio.NewReader(s))
-	err := decoder.Decode(message)
-	return err
+// decodeProtoMessage receives a pointer to a proto.Message and decodes it's data from the reader r.
+// It fails without reading the message if it's over maxSize bytes.
+func decodeProtoMessage(message proto.Message, r io.Reader, maxSize int) error {
+	br := bufio.NewReader(r)
+	if err := multicodec.ConsumeHeader(br, protobufCodec.HeaderMsgio); err != nil {
+		return err
+	}
+	reader := msgio.NewReader(br)
+	size, err := reader.NextMsgLen()
+	if err != nil {
+		return err
+	}
+	if size > maxSize {
+		return ErrMessageTooLarge
+	}
+	msg, err := reader.ReadMsg()
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(msg, message)
 }
 
-// sendMsg sends a message msg from fromHost to peer toID using the protocol
+// sendMsg sends a message msg from fromHost to peer toID using the highest version of the protocol both support
 func sendMsg(fromHost host.Host, toID peer.ID, msg proto.Message, protocol protocol.ID) bool {
-	s, err := fromHost.NewStream(context.Background(), toID, protocol)
+	s, err := fromHost.NewStream(context.Background(), toID, protocolIDs(protocol)...)
 	if err != nil {
 		log.Println(err)
 		return false
@@ -38,7 +38,7 @@ var (
 	commonTestHost3, _ = NewHost(&config.GlobalConfig{
 		P2P: config.P2P{ListenPort: 10311, ListenAddress: "127.0.0.1",
 			Bootstraper: config.Bootstraper{
-				Nodes: []string{commonTestHost2.FullAddr},
+				Nodes: []string{commonTestHost2.FullAddr()},
 			},
 		},
 	})
@@ -56,7 +56,7 @@ func TestSignAuthenticate(t *testing.T) {
 	req := discoveryRequestMsg(commonTestHost1.P2PHost)
 	key := commonTestHost1.P2PHost.Peerstore().PrivKey(commonTestHost1.P2PHost.ID())
 	req.DiscoveryMsgData.MessageData.Sign = signProtoMsg(req, key)
-	valid := authenticateProtoMsg(req, req.DiscoveryMsgData.MessageData)
+	valid := authenticateProtoMsg(req, req.DiscoveryMsgData.MessageData, nil)
 	assert.True(t, valid)
 }
 
@@ -0,0 +1,319 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	"github.com/crowdcompute/crowdengine/log"
+
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// defaultDialTimeout is the timeout of dialing a peer if no connection timeout is configured
+	defaultDialTimeout = 40 * time.Second
+	// defaultBootstrapPeriod is the period of checking the connections if no bootstrap period is configured
+	defaultBootstrapPeriod = 120 * time.Second
+	// connGracePeriod is the time a new connection can't be trimmed, so that it gets a chance to be used
+	connGracePeriod = 30 * time.Second
+)
+
+// ConnState is the state of the node's connections
+type ConnState struct {
+	MaxPeers int             `json:"maxPeers"`
+	MinPeers int             `json:"minPeers"`
+	Peers    []PeerConnState `json:"peers"`
+}
+
+// PeerConnState is the state of the connection to a peer
+type PeerConnState struct {
+	ID             string   `json:"id"`
+	Addrs          []string `json:"addrs"`
+	ConnectedSince int64    `json:"connectedSince"` // Unix time
+	Streams        int      `json:"streams"`        // Number of streams opened since connecting
+	Score          int      `json:"score"`
+	Bootnode       bool     `json:"bootnode"`
+}
+
+// peerConn holds what the ConnManager knows about a connected peer
+type peerConn struct {
+	connectedAt time.Time
+	streams     int
+}
+
+// ConnManager dials the bootnodes and keeps the number of the node's peers between
+// the configured minimum and MaxPeers. Peers with the lowest score get disconnected first
+// and banned peers are not allowed to connect at all.
+type ConnManager struct {
+	host       host.Host
+	cfg        *config.P2P
+	reputation *Reputation
+	bootnodes  map[peer.ID]struct{} // Bootnodes are never trimmed
+	peers      map[peer.ID]*peerConn
+	mu         sync.Mutex
+}
+
+// NewConnManager creates a new ConnManager for the p2pHost and starts tracking its connections
+func NewConnManager(p2pHost host.Host, cfg *config.P2P, reputation *Reputation) *ConnManager {
+	c := &ConnManager{
+		host:       p2pHost,
+		cfg:        cfg,
+		reputation: reputation,
+		bootnodes:  make(map[peer.ID]struct{}),
+		peers:      make(map[peer.ID]*peerConn),
+	}
+	p2pHost.Network().Notify((*connNotifiee)(c))
+	reputation.OnBan(c.disconnect)
+	return c
+}
+
+// disconnect closes the connections to the peer pID
+func (c *ConnManager) disconnect(pID peer.ID) {
+	if c.host.Network().Connectedness(pID) == inet.Connected {
+		log.Printf("Disconnecting from the banned peer %s", pID)
+		c.host.Network().ClosePeer(pID)
+	}
+}
+
+// Connect dials the bootnodes and keeps them connected from now on
+func (c *ConnManager) Connect(bootnodes []ps.PeerInfo) {
+	c.addBootnodes(bootnodes)
+	c.dial(bootnodes)
+}
+
+// ConnectAsync dials the bootnodes in the background and keeps them connected from now on.
+// The bootnodes that can't be reached are dialed again by the reconnect loop of Run.
+func (c *ConnManager) ConnectAsync(bootnodes []ps.PeerInfo) {
+	c.addBootnodes(bootnodes)
+	go c.dial(bootnodes)
+}
+
+// addBootnodes adds the peers to the bootnodes that are kept connected
+func (c *ConnManager) addBootnodes(bootnodes []ps.PeerInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, pi := range bootnodes {
+		c.bootnodes[pi.ID] = struct{}{}
+	}
+}
+
+// dial connects to all the peers in parallel and waits until all dials finish
+func (c *ConnManager) dial(peers []ps.PeerInfo) {
+	timeout := time.Duration(c.cfg.ConnectionTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	var wg sync.WaitGroup
+	for _, pi := range peers {
+		if pi.ID == c.host.ID() || c.host.Network().Connectedness(pi.ID) == inet.Connected || c.reputation.Banned(pi.ID) {
+			continue
+		}
+		wg.Add(1)
+		go func(pi ps.PeerInfo) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := c.host.Connect(ctx, pi); err != nil {
+				log.Printf("Couldn't connect to %s. Error: %s", pi.ID, err)
+			}
+		}(pi)
+	}
+	wg.Wait()
+}
+
+// Run trims the connections above MaxPeers and re-bootstraps when the node has fewer peers
+// than the threshold, every bootstrap period until quit is closed
+func (c *ConnManager) Run(quit <-chan struct{}) {
+	period := time.Duration(c.cfg.Bootstraper.BootstrapPeriodic) * time.Second
+	if period <= 0 {
+		period = defaultBootstrapPeriod
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.TrimConnections()
+			if len(c.host.Network().Peers()) < c.cfg.MinPeersThreashold {
+				c.rebootstrap()
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// rebootstrap dials the bootnodes and the known peers the node isn't connected to,
+// until the node has MaxPeers peers
+func (c *ConnManager) rebootstrap() {
+	log.Println("Too few peers. Bootstrapping again...")
+	c.mu.Lock()
+	candidates := make([]ps.PeerInfo, 0, len(c.bootnodes))
+	for pID := range c.bootnodes {
+		candidates = append(candidates, c.host.Peerstore().PeerInfo(pID))
+	}
+	for _, pID := range c.host.Peerstore().PeersWithAddrs() {
+		if _, ok := c.bootnodes[pID]; !ok && pID != c.host.ID() {
+			candidates = append(candidates, c.host.Peerstore().PeerInfo(pID))
+		}
+	}
+	c.mu.Unlock()
+	missing := c.cfg.MaxPeers - len(c.host.Network().Peers())
+	if c.cfg.MaxPeers > 0 && len(candidates) > missing {
+		if missing <= 0 {
+			return
+		}
+		candidates = candidates[:missing]
+	}
+	c.dial(candidates)
+}
+
+// TrimConnections disconnects the peers with the lowest score, until the node has at most MaxPeers peers.
+// Bootnodes and new connections are never trimmed. It returns the number of disconnected peers.
+func (c *ConnManager) TrimConnections() int {
+	peers := c.host.Network().Peers()
+	if c.cfg.MaxPeers <= 0 || len(peers) <= c.cfg.MaxPeers {
+		return 0
+	}
+	c.mu.Lock()
+	candidates := make([]peer.ID, 0, len(peers))
+	for _, pID := range peers {
+		conn, ok := c.peers[pID]
+		if _, bootnode := c.bootnodes[pID]; bootnode || !ok || time.Since(conn.connectedAt) < connGracePeriod {
+			continue
+		}
+		candidates = append(candidates, pID)
+	}
+	// Lowest score first and, for the same score, the newest connection first
+	sort.Slice(candidates, func(i, j int) bool {
+		si, sj := c.score(candidates[i]), c.score(candidates[j])
+		if si != sj {
+			return si < sj
+		}
+		return c.peers[candidates[i]].connectedAt.After(c.peers[candidates[j]].connectedAt)
+	})
+	c.mu.Unlock()
+
+	trimmed := 0
+	for _, pID := range candidates {
+		if len(peers)-trimmed <= c.cfg.MaxPeers {
+			break
+		}
+		log.Printf("Too many peers. Disconnecting from %s", pID)
+		if err := c.host.Network().ClosePeer(pID); err == nil {
+			trimmed++
+		}
+	}
+	return trimmed
+}
+
+// score returns how useful the connection to the peer pID is, based on its use and the peer's reputation.
+// The mutex must be held.
+func (c *ConnManager) score(pID peer.ID) int {
+	score := int(c.reputation.Score(pID))
+	if conn, ok := c.peers[pID]; ok {
+		score += conn.streams
+	}
+	return score
+}
+
+// State returns the state of the node's connections
+func (c *ConnManager) State() ConnState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	state := ConnState{MaxPeers: c.cfg.MaxPeers, MinPeers: c.cfg.MinPeersThreashold, Peers: make([]PeerConnState, 0, len(c.peers))}
+	for pID, conn := range c.peers {
+		// The disconnection notifications arrive asynchronously
+		if c.host.Network().Connectedness(pID) != inet.Connected {
+			continue
+		}
+		_, bootnode := c.bootnodes[pID]
+		peerState := PeerConnState{
+			ID:             pID.Pretty(),
+			Addrs:          make([]string, 0),
+			ConnectedSince: conn.connectedAt.Unix(),
+			Streams:        conn.streams,
+			Score:          c.score(pID),
+			Bootnode:       bootnode,
+		}
+		for _, conn := range c.host.Network().ConnsToPeer(pID) {
+			peerState.Addrs = append(peerState.Addrs, conn.RemoteMultiaddr().String())
+		}
+		state.Peers = append(state.Peers, peerState)
+	}
+	return state
+}
+
+// connNotifiee tracks the connections of the ConnManager's host
+type connNotifiee ConnManager
+
+func (n *connNotifiee) cm() *ConnManager {
+	return (*ConnManager)(n)
+}
+
+// Connected starts tracking the peer of the connection, trimming the connections if there are too many.
+// Connections of banned peers are closed.
+func (n *connNotifiee) Connected(net inet.Network, conn inet.Conn) {
+	c := n.cm()
+	if c.reputation.Banned(conn.RemotePeer()) {
+		log.Printf("Rejecting the connection of the banned peer %s", conn.RemotePeer())
+		conn.Close()
+		return
+	}
+	c.mu.Lock()
+	if _, ok := c.peers[conn.RemotePeer()]; !ok {
+		c.peers[conn.RemotePeer()] = &peerConn{connectedAt: time.Now()}
+	}
+	c.mu.Unlock()
+	if c.cfg.MaxPeers > 0 && len(net.Peers()) > c.cfg.MaxPeers {
+		go c.TrimConnections()
+	}
+}
+
+// Disconnected stops tracking the peer of the connection, once there are no more connections to it
+func (n *connNotifiee) Disconnected(net inet.Network, conn inet.Conn) {
+	if net.Connectedness(conn.RemotePeer()) == inet.Connected {
+		return
+	}
+	c := n.cm()
+	c.mu.Lock()
+	delete(c.peers, conn.RemotePeer())
+	c.mu.Unlock()
+}
+
+// OpenedStream counts the streams opened with a peer
+func (n *connNotifiee) OpenedStream(net inet.Network, s inet.Stream) {
+	c := n.cm()
+	c.mu.Lock()
+	if conn, ok := c.peers[s.Conn().RemotePeer()]; ok {
+		conn.streams++
+	}
+	c.mu.Unlock()
+}
+
+func (n *connNotifiee) Listen(inet.Network, ma.Multiaddr)      {}
+func (n *connNotifiee) ListenClose(inet.Network, ma.Multiaddr) {}
+func (n *connNotifiee) ClosedStream(inet.Network, inet.Stream) {}
@@ -0,0 +1,90 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	connTestHost1, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10321, ListenAddress: "127.0.0.1", MaxPeers: 1},
+	})
+	connTestHost2, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10322, ListenAddress: "127.0.0.1"},
+	})
+	connTestHost3, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10323, ListenAddress: "127.0.0.1"},
+	})
+	connTestHost4, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10324, ListenAddress: "127.0.0.1"},
+	})
+)
+
+func TestTrimConnections(t *testing.T) {
+	cm := connTestHost1.ConnManager
+	cm.Connect([]ps.PeerInfo{peerInfo(connTestHost2)})
+	err := connTestHost1.P2PHost.Connect(context.Background(), peerInfo(connTestHost3))
+	assert.NoError(t, err)
+
+	// New connections are not trimmed
+	assert.Equal(t, 0, cm.TrimConnections())
+	assert.Len(t, cm.State().Peers, 2)
+
+	cm.mu.Lock()
+	for _, conn := range cm.peers {
+		conn.connectedAt = time.Now().Add(-2 * connGracePeriod)
+	}
+	cm.mu.Unlock()
+
+	// The bootnode is kept, even though the other peer has the same score
+	assert.Equal(t, 1, cm.TrimConnections())
+	peers := connTestHost1.P2PHost.Network().Peers()
+	assert.Len(t, peers, 1)
+	assert.Equal(t, connTestHost2.P2PHost.ID(), peers[0])
+	state := cm.State()
+	assert.Len(t, state.Peers, 1)
+	assert.True(t, state.Peers[0].Bootnode)
+}
+
+func TestConnectAsync(t *testing.T) {
+	cm := connTestHost4.ConnManager
+	cm.ConnectAsync([]ps.PeerInfo{peerInfo(connTestHost3)})
+	// The bootnode is kept connected even before the dial finishes
+	cm.mu.Lock()
+	_, ok := cm.bootnodes[connTestHost3.P2PHost.ID()]
+	cm.mu.Unlock()
+	assert.True(t, ok)
+
+	for i := 0; i < 50 && len(connTestHost4.P2PHost.Network().Peers()) == 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Equal(t, inet.Connected, connTestHost4.P2PHost.Network().Connectedness(connTestHost3.P2PHost.ID()))
+}
+
+// peerInfo returns the ID and the listen addresses of the host h
+func peerInfo(h *Host) ps.PeerInfo {
+	return ps.PeerInfo{ID: h.P2PHost.ID(), Addrs: h.P2PHost.Addrs()}
+}
@@ -0,0 +1,136 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// lvlDatastore is a datastore backed by a leveldb database, so that the DHT records
+// and the known peers survive a restart of the node
+type lvlDatastore struct {
+	db *leveldb.DB
+}
+
+var _ ds.Batching = (*lvlDatastore)(nil)
+
+// newLvlDatastore opens, or creates, the leveldb datastore at path
+func newLvlDatastore(path string) (*lvlDatastore, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &lvlDatastore{db: db}, nil
+}
+
+// Put stores the value under key
+func (d *lvlDatastore) Put(key ds.Key, value []byte) error {
+	return d.db.Put(key.Bytes(), value, nil)
+}
+
+// Get returns the value of key, or ds.ErrNotFound
+func (d *lvlDatastore) Get(key ds.Key) ([]byte, error) {
+	value, err := d.db.Get(key.Bytes(), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ds.ErrNotFound
+	}
+	return value, err
+}
+
+// Has returns whether a value is stored under key
+func (d *lvlDatastore) Has(key ds.Key) (bool, error) {
+	return d.db.Has(key.Bytes(), nil)
+}
+
+// GetSize returns the size of the value of key, or ds.ErrNotFound
+func (d *lvlDatastore) GetSize(key ds.Key) (int, error) {
+	value, err := d.Get(key)
+	if err != nil {
+		return -1, err
+	}
+	return len(value), nil
+}
+
+// Delete removes the value of key
+func (d *lvlDatastore) Delete(key ds.Key) error {
+	return d.db.Delete(key.Bytes(), nil)
+}
+
+// Query returns the entries matching the query q
+func (d *lvlDatastore) Query(q dsq.Query) (dsq.Results, error) {
+	var iterRange *util.Range
+	if q.Prefix != "" {
+		iterRange = util.BytesPrefix(ds.NewKey(q.Prefix).Bytes())
+	}
+	iter := d.db.NewIterator(iterRange, nil)
+	entries := make([]dsq.Entry, 0)
+	for iter.Next() {
+		entry := dsq.Entry{Key: string(iter.Key())}
+		if !q.KeysOnly {
+			entry.Value = append([]byte{}, iter.Value()...)
+		}
+		entries = append(entries, entry)
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+	// The limit is applied separately, as NaiveQueryApply limits by the offset
+	limit := q.Limit
+	q.Limit = 0
+	results := dsq.NaiveQueryApply(q, dsq.ResultsWithEntries(q, entries))
+	if limit > 0 {
+		results = dsq.NaiveLimit(results, limit)
+	}
+	return results, nil
+}
+
+// Batch returns a batch that writes all its changes at once
+func (d *lvlDatastore) Batch() (ds.Batch, error) {
+	return &lvlBatch{db: d.db, batch: new(leveldb.Batch)}, nil
+}
+
+// Close closes the leveldb database
+func (d *lvlDatastore) Close() error {
+	return d.db.Close()
+}
+
+// lvlBatch is a batch of changes to a lvlDatastore
+type lvlBatch struct {
+	db    *leveldb.DB
+	batch *leveldb.Batch
+}
+
+// Put adds storing the value under key to the batch
+func (b *lvlBatch) Put(key ds.Key, value []byte) error {
+	b.batch.Put(key.Bytes(), value)
+	return nil
+}
+
+// Delete adds removing the value of key to the batch
+func (b *lvlBatch) Delete(key ds.Key) error {
+	b.batch.Delete(key.Bytes())
+	return nil
+}
+
+// Commit writes the changes of the batch
+func (b *lvlBatch) Commit() error {
+	return b.db.Write(b.batch, nil)
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLvlDatastore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2pstore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	dstore, err := newLvlDatastore(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, dstore.Put(ds.NewKey("/peers/a"), []byte("1")))
+	batch, _ := dstore.Batch()
+	batch.Put(ds.NewKey("/peers/b"), []byte("2"))
+	batch.Put(ds.NewKey("/other"), []byte("3"))
+	assert.Nil(t, batch.Commit())
+
+	results, err := dstore.Query(dsq.Query{Prefix: "/peers"})
+	assert.Nil(t, err)
+	entries, err := results.Rest()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	size, err := dstore.GetSize(ds.NewKey("/peers/b"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, size)
+
+	assert.Nil(t, dstore.Delete(ds.NewKey("/peers/a")))
+	_, err = dstore.Get(ds.NewKey("/peers/a"))
+	assert.Equal(t, ds.ErrNotFound, err)
+	_, err = dstore.GetSize(ds.NewKey("/peers/a"))
+	assert.Equal(t, ds.ErrNotFound, err)
+	assert.Nil(t, dstore.Close())
+
+	// The values survive reopening the datastore
+	dstore, err = newLvlDatastore(dir)
+	assert.Nil(t, err)
+	value, err := dstore.Get(ds.NewKey("/other"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("3"), value)
+	dstore.Close()
+}
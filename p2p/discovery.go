@@ -19,15 +19,15 @@ package p2p
 import (
 	"context"
 	"encoding/hex"
+	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/crowdcompute/crowdengine/log"
 
 	"github.com/crowdcompute/crowdengine/common"
 	"github.com/crowdcompute/crowdengine/crypto"
-	"github.com/crowdcompute/crowdengine/manager"
-	"github.com/docker/docker/api/types"
 
 	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
 	host "github.com/libp2p/go-libp2p-host"
@@ -40,34 +40,89 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-// pattern: /protocol-name/request-or-response-message/version
-const discoveryRequest = "/Discovery/discoveryreq/0.0.1"
-const discoveryResponse = "/Discovery/discoveryresp/0.0.1"
+// pattern: /protocol-name/request-or-response-message, which gets a /version suffix from protocolVersions
+const discoveryRequest = "/Discovery/discoveryreq"
+const discoveryResponse = "/Discovery/discoveryresp"
+const discoveryRelay = "/Discovery/discoveryrelay"
+
+// maxDiscoveryHops is the number of nodes a discovery request goes through at most.
+// It bounds the path carried by the request and the route of its responses.
+const maxDiscoveryHops = 32
+
+// Discovery modes
+const (
+	// FloodDiscovery sends every discovery request to all the peers, which forward it to theirs
+	FloodDiscovery = "flood"
+	// GossipsubDiscovery publishes discovery requests on a libp2p pubsub topic. Responses come back directly.
+	GossipsubDiscovery = "gossipsub"
+)
+
+// checkDiscoveryMode checks that the discovery mode is known. An empty mode means flooding.
+func checkDiscoveryMode(mode string) error {
+	switch mode {
+	case "", FloodDiscovery, GossipsubDiscovery:
+		return nil
+	default:
+		return fmt.Errorf("Unknown discovery mode %s", mode)
+	}
+}
+
+// DiscoveryStats counts the discovery messages of the node and times the responses to its requests,
+// so that the discovery modes can be compared
+type DiscoveryStats struct {
+	Mode               string `json:"mode"`
+	RequestsSent       uint64 `json:"requestsSent"`
+	RequestsReceived   uint64 `json:"requestsReceived"`
+	DuplicatesReceived uint64 `json:"duplicatesReceived"`
+	ResponsesSent      uint64 `json:"responsesSent"`
+	ResponsesReceived  uint64 `json:"responsesReceived"`
+	ResponsesRelayed   uint64 `json:"responsesRelayed"`
+	// AvgResponseLatencyMs is the average time from sending a discovery request to receiving a response to it
+	AvgResponseLatencyMs uint64 `json:"avgResponseLatencyMs"`
+}
 
 // DiscoveryProtocol implements Observer interface
 type DiscoveryProtocol struct {
 	p2pHost       host.Host                          // local host
 	dht           *dht.IpfsDHT                       // local host
+	capacity      *CapacityTracker                   // The node's resources in use
+	availability  *common.Availability               // The node's availability windows
+	replay        *ReplayGuard                       // Rejects stale and replayed messages
+	reputation    *Reputation                        // Records the outcomes of the interactions with peers
+	limiter       *Limiter                           // Limits the work each peer can make the node do
 	receivedMsgs  map[string]uint32                  // Store all received msgs, so that we do not re-send them when received again
 	pendingReq    map[*api.DiscoveryRequest]struct{} // Store all requests that were unable to be fullfiled at the time the node was busy
 	maxPendingReq uint16                             // The maximum requests the node stores for later process
 	NodeIDs       map[string][]string                // Stores the Node IDs of each public Key account
+	sentAt        map[string]time.Time               // When the node sent each of its own requests, by init hash
+	stats         DiscoveryStats                     // Updated atomically
+	latencyMs     uint64                             // Total latency of the timed responses to the node's requests. Updated atomically.
+	timedResps    uint64                             // Responses that arrived while the time of their request was known. Updated atomically.
+	gossip        *gossipDiscovery                   // Spreads the requests in the gossipsub mode. It's nil in the flood mode.
 	mu            sync.Mutex
 }
 
 // NewDiscoveryProtocol sets the protocol's stream handlers and returns a new DiscoveryProtocol
-func NewDiscoveryProtocol(p2pHost host.Host, dht *dht.IpfsDHT) *DiscoveryProtocol {
+func NewDiscoveryProtocol(p2pHost host.Host, dht *dht.IpfsDHT, capacity *CapacityTracker, availability *common.Availability, replay *ReplayGuard, reputation *Reputation, limiter *Limiter) *DiscoveryProtocol {
 	p := &DiscoveryProtocol{
 		p2pHost:       p2pHost,
 		dht:           dht,
+		capacity:      capacity,
+		availability:  availability,
+		replay:        replay,
+		reputation:    reputation,
+		limiter:       limiter,
 		receivedMsgs:  make(map[string]uint32),
 		maxPendingReq: 5,
 		NodeIDs:       make(map[string][]string),
+		sentAt:        make(map[string]time.Time),
 	}
+	p.stats.Mode = FloodDiscovery
 	p.pendingReq = map[*api.DiscoveryRequest]struct{}{} //p.maxPendingReq
 	// Set the handlers the node will be listening to
-	p2pHost.SetStreamHandler(discoveryRequest, p.onDiscoveryRequest)
-	p2pHost.SetStreamHandler(discoveryResponse, p.onDiscoveryResponse)
+	setStreamHandler(p2pHost, discoveryRequest, p.onDiscoveryRequest)
+	setStreamHandler(p2pHost, discoveryResponse, p.onDiscoveryResponse)
+	setStreamHandler(p2pHost, discoveryRelay, p.onDiscoveryRelay)
 	return p
 }
 
@@ -75,7 +130,11 @@ func NewDiscoveryProtocol(p2pHost host.Host, dht *dht.IpfsDHT) *DiscoveryProtoco
 func (p *DiscoveryProtocol) onNotify() {
 	log.Println(" pending requests: ", p.pendingReq)
 	for req := range p.pendingReq {
-		if !p.requestExpired(req) {
+		if p.requestExpired(req) {
+			delete(p.pendingReq, req)
+			continue
+		}
+		if p.availableFor(req.Requirements) && p.capacity.Fits(req.Requirements) {
 			log.Println("Request not expired, trying to send response")
 			if p.createSendResponse(req) {
 				delete(p.pendingReq, req)
@@ -95,9 +154,11 @@ func (p *DiscoveryProtocol) InitializeDiscovery(pubKey string, numberOfNodes int
 // Sets the ID of the node that initiated the discovery request
 // Sets the unique hash of the msg request
 // Sets the TTL & expiry time of the msg request
-func (p *DiscoveryProtocol) GetInitialDiscoveryReq() (*api.DiscoveryRequest, error) {
+// Sets the requirements of the job that nodes have to fulfil in order to reply
+func (p *DiscoveryProtocol) GetInitialDiscoveryReq(reqs *api.JobRequirements) (*api.DiscoveryRequest, error) {
 	req := &api.DiscoveryRequest{DiscoveryMsgData: NewDiscoveryMsgData(uuid.Must(uuid.NewV4(), nil).String(), true, p.p2pHost),
-		Message: api.DiscoveryMessage_DiscoveryReq}
+		Message:      api.DiscoveryMessage_DiscoveryReq,
+		Requirements: reqs}
 
 	// The node initilizing this request is the init Node
 	req.DiscoveryMsgData.InitNodeID = p.p2pHost.ID().Pretty()
@@ -106,6 +167,7 @@ func (p *DiscoveryProtocol) GetInitialDiscoveryReq() (*api.DiscoveryRequest, err
 		return nil, err
 	}
 	req.DiscoveryMsgData.InitHash = hex.EncodeToString(hash)
+	req.DiscoveryMsgData.Path = []string{req.DiscoveryMsgData.InitNodeID}
 	log.Println("req.DiscoveryMsgData.InitHash: ", req.DiscoveryMsgData.InitHash)
 	p.setTTLForDiscReq(req, common.TTLmsg)
 	return req, err
@@ -117,22 +179,42 @@ func (p *DiscoveryProtocol) setTTLForDiscReq(req *api.DiscoveryRequest, ttl time
 	req.DiscoveryMsgData.Expiry = uint32(time.Now().Add(ttl).Unix())
 }
 
+// SendDiscoveryReq sends a discovery request the node initialized to the network,
+// publishing it on the discovery topic in the gossipsub mode or sending it to all the peers otherwise
+func (p *DiscoveryProtocol) SendDiscoveryReq(req *api.DiscoveryRequest) {
+	p.mu.Lock()
+	p.sentAt[req.DiscoveryMsgData.InitHash] = time.Now()
+	p.mu.Unlock()
+	if p.gossip != nil {
+		p.gossip.publish(req)
+		return
+	}
+	// No neighbour sent me this message, that's why the empty peer ID
+	p.ForwardMsgToPeers(req, "")
+}
+
 // ForwardMsgToPeers makes a copy of a Discovery request and sends it to its peers
 // We don't want to send the message to the peer that we received the message from (peerWhoSentMsg)
 func (p *DiscoveryProtocol) ForwardMsgToPeers(request *api.DiscoveryRequest, peerWhoSentMsg peer.ID) {
+	if len(request.DiscoveryMsgData.Path) >= maxDiscoveryHops {
+		log.Println("The discovery request went through too many nodes. Not forwarding it...")
+		return
+	}
 	req := p.copyNewDiscoveryRequest(request)
 	p.sendMsgToPeers(req, peerWhoSentMsg)
 }
 
 // copyNewDiscoveryRequest gets a DiscoveryRequest and returns a copy of it,
-// but with a new message ID and the current node's signature instead
+// but with a new message ID, the current node added to its path and the current node's signature instead
 func (p *DiscoveryProtocol) copyNewDiscoveryRequest(request *api.DiscoveryRequest) *api.DiscoveryRequest {
 	req := &api.DiscoveryRequest{DiscoveryMsgData: NewDiscoveryMsgData(uuid.Must(uuid.NewV4(), nil).String(), true, p.p2pHost),
-		Message: api.DiscoveryMessage_DiscoveryReq}
+		Message:      api.DiscoveryMessage_DiscoveryReq,
+		Requirements: request.Requirements}
 	req.DiscoveryMsgData.InitNodeID = request.DiscoveryMsgData.InitNodeID
 	req.DiscoveryMsgData.TTL = request.DiscoveryMsgData.TTL
 	req.DiscoveryMsgData.Expiry = request.DiscoveryMsgData.Expiry
 	req.DiscoveryMsgData.InitHash = request.DiscoveryMsgData.InitHash
+	req.DiscoveryMsgData.Path = append(append([]string{}, request.DiscoveryMsgData.Path...), p.p2pHost.ID().Pretty())
 	log.Println("COPYING: ", req.DiscoveryMsgData.InitHash)
 
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
@@ -150,7 +232,11 @@ func (p *DiscoveryProtocol) sendMsgToPeers(req *api.DiscoveryRequest, peerWhoSen
 	// Send message to peers
 	for _, neighbourID := range p.p2pHost.Peerstore().Peers() {
 		if _, ok := excludedPeers[neighbourID]; !ok {
-			go sendMsg(p.p2pHost, neighbourID, req, protocol.ID(discoveryRequest))
+			go func(neighbourID peer.ID) {
+				if sendMsg(p.p2pHost, neighbourID, req, protocol.ID(discoveryRequest)) {
+					atomic.AddUint64(&p.stats.RequestsSent, 1)
+				}
+			}(neighbourID)
 			log.Printf("%s: Discovery message to: %s was sent. Message Id: %s, Message: %s",
 				p.p2pHost.ID(), neighbourID, req.DiscoveryMsgData.MessageData.Id, req.Message)
 		}
@@ -159,12 +245,24 @@ func (p *DiscoveryProtocol) sendMsgToPeers(req *api.DiscoveryRequest, peerWhoSen
 
 // onDiscoveryRequest represents a handler
 func (p *DiscoveryProtocol) onDiscoveryRequest(s inet.Stream) {
+	// Discovery requests are never answered with an error, so the ones over the limits are just dropped
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), discoveryRequest)
+	if err != nil {
+		log.Println("Dropping discovery request. Error: ", err)
+		s.Reset()
+		return
+	}
+	defer release()
 	// get request data
 	data := &api.DiscoveryRequest{}
-	decodeProtoMessage(data, s)
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.DiscoveryMsgData == nil {
+		log.Println("Failed to decode discovery request")
+		return
+	}
 
 	// Log the reception of the message
 	log.Printf("%s: Received discovery request from %s. Message: %s", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.Message)
+	atomic.AddUint64(&p.stats.RequestsReceived, 1)
 
 	// If the request's TTL expired or
 	// If I received the same message again, I will skip
@@ -175,28 +273,32 @@ func (p *DiscoveryProtocol) onDiscoveryRequest(s inet.Stream) {
 	p.receivedMsgs[data.DiscoveryMsgData.InitHash] = data.DiscoveryMsgData.Expiry
 
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.DiscoveryMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.DiscoveryMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
 
 	// Pass this message to my neighbours
 	p.ForwardMsgToPeers(data, s.Conn().RemotePeer())
+	p.answerDiscoveryRequest(data)
+}
 
-	// Even if there is possibility that we never send a reply to this Node (because of being busy),
-	// we still store it our our Peerstore, because there is high possibility to
-	// receive a request again.
+// answerDiscoveryRequest responds to an authentic discovery request if the node can run the job,
+// or keeps it for later if the node is busy
+func (p *DiscoveryProtocol) answerDiscoveryRequest(data *api.DiscoveryRequest) {
+	// Never reply out of my availability windows or when the job would outlast the current window
+	if !p.availableFor(data.Requirements) {
+		log.Println("I am not available for this job. Returning...")
+		return
+	}
 
-	// If the node who sent this message is different than the initPeerID
-	// then we need to add the init node to our neighbours before sending the message
-	initPeerID, _ := peer.IDB58Decode(data.DiscoveryMsgData.InitNodeID)
-	if s.Conn().RemotePeer().String() != initPeerID.String() {
-		p.dhtFindAddrAndStore(initPeerID)
+	// Never reply to jobs that wouldn't fit in any of my containers
+	if !jobFits(p.capacity.hostCfg, data.Requirements) {
+		log.Println("The job doesn't fit my resources. Returning...")
+		return
 	}
 
-	busy, err := NodeBusy()
-	common.FatalIfErr(err, "Error on checking if node is busy")
-	if busy {
+	if !p.capacity.Fits(data.Requirements) {
 		// Cache the request for a later time
 		if uint16(len(p.pendingReq)) < p.maxPendingReq {
 			p.pendingReq[data] = struct{}{}
@@ -208,25 +310,15 @@ func (p *DiscoveryProtocol) onDiscoveryRequest(s inet.Stream) {
 	p.createSendResponse(data)
 }
 
-// NodeBusy checks if there is at least one running container then it returns true
-// This function removes finished containers as well.
-// TODO : the logic of a busy node doesn't have to be this way
-func NodeBusy() (bool, error) {
-	containers, err := manager.GetInstance().ListContainers()
-
-	for _, container := range containers {
-		// If at least one is running then state that I am busy
-		if containerRunning(container.ID) {
-			return false, err
-		}
-		// If finished container remove it from docker
-		manager.GetInstance().RemoveContainer(container.ID, types.ContainerRemoveOptions{})
-		break
-	}
-	return false, err
+// availableFor checks if the node is available now and stays available for the maximum runtime of a job with the requirements reqs
+func (p *DiscoveryProtocol) availableFor(reqs *api.JobRequirements) bool {
+	maxRuntime := time.Duration(reqs.GetMaxRuntime()) * time.Second
+	return p.availability.AvailableFor(time.Now(), maxRuntime)
 }
 
-// createSendResponse creates and sends a response back to the peer who initialized the request
+// createSendResponse creates and sends a response back to the peer who initialized the request.
+// The response goes back along the reverse path of the request, so that initiators the node can't dial get it as well.
+// If that fails, the node dials the initiator directly.
 func (p *DiscoveryProtocol) createSendResponse(data *api.DiscoveryRequest) bool {
 	// Get the init node ID
 	initPeerID, _ := peer.IDB58Decode(data.DiscoveryMsgData.InitNodeID)
@@ -234,7 +326,12 @@ func (p *DiscoveryProtocol) createSendResponse(data *api.DiscoveryRequest) bool
 	resp := &api.DiscoveryResponse{DiscoveryMsgData: NewDiscoveryMsgData(data.DiscoveryMsgData.MessageData.Id, false, p.p2pHost),
 		Message: api.DiscoveryMessage_DiscoveryRes}
 
+	resp.DiscoveryMsgData.InitNodeID = data.DiscoveryMsgData.InitNodeID
 	resp.DiscoveryMsgData.InitHash = data.DiscoveryMsgData.InitHash
+	// Advertise until when I am available
+	if start, end, ok := p.availability.Window(time.Now()); ok {
+		resp.Availability = &api.AvailabilityWindow{Start: start.Unix(), End: end.Unix()}
+	}
 	// sign the data
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
 	resp.DiscoveryMsgData.MessageData.Sign = signProtoMsg(resp, key)
@@ -243,9 +340,52 @@ func (p *DiscoveryProtocol) createSendResponse(data *api.DiscoveryRequest) bool
 		p.p2pHost.ID(), initPeerID, resp.DiscoveryMsgData.MessageData.Id, resp.Message)
 
 	// send the response
+	sent := false
+	// The initiator is my neighbour if the path has a single node
+	if route := reverseRoute(data.DiscoveryMsgData.Path); len(route) > 1 {
+		if sent = p.relayResponse(resp, route); !sent {
+			log.Println("Couldn't send the discovery response along the path of the request. Dialing the initiator...")
+		}
+	}
+	if !sent {
+		sent = p.sendResponseDirectly(resp, initPeerID)
+	}
+	if sent {
+		atomic.AddUint64(&p.stats.ResponsesSent, 1)
+	}
+	return sent
+}
+
+// relayResponse sends the response resp to the first node of the route, which passes it along the rest of it
+func (p *DiscoveryProtocol) relayResponse(resp *api.DiscoveryResponse, route []string) bool {
+	next, err := peer.IDB58Decode(route[0])
+	if err != nil {
+		return false
+	}
+	relay := &api.DiscoveryRelay{Response: resp, Route: route}
+	return sendMsg(p.p2pHost, next, relay, protocol.ID(discoveryRelay))
+}
+
+// sendResponseDirectly sends the response resp straight to the initiator initPeerID,
+// looking its addresses up in the DHT if the node isn't connected to it
+func (p *DiscoveryProtocol) sendResponseDirectly(resp *api.DiscoveryResponse, initPeerID peer.ID) bool {
+	if p.p2pHost.Network().Connectedness(initPeerID) != inet.Connected {
+		if err := p.dhtFindAddrAndStore(initPeerID); err != nil {
+			log.Printf("Couldn't find the addresses of %s. Error: %s", initPeerID, err)
+		}
+	}
 	return sendMsg(p.p2pHost, initPeerID, resp, protocol.ID(discoveryResponse))
 }
 
+// reverseRoute returns the nodes of the path of a request in reverse order, the initiator last
+func reverseRoute(path []string) []string {
+	route := make([]string, len(path))
+	for i, nodeID := range path {
+		route[len(path)-1-i] = nodeID
+	}
+	return route
+}
+
 // requestExpired checks if a request req expired
 func (p *DiscoveryProtocol) requestExpired(req *api.DiscoveryRequest) bool {
 	now := uint32(time.Now().Unix())
@@ -261,6 +401,7 @@ func (p *DiscoveryProtocol) requestExpired(req *api.DiscoveryRequest) bool {
 // checkMsgReceived checks if request req exists in the receivedMsgs slice
 func (p *DiscoveryProtocol) checkMsgReceived(req *api.DiscoveryRequest) bool {
 	if _, ok := p.receivedMsgs[req.DiscoveryMsgData.InitHash]; ok {
+		atomic.AddUint64(&p.stats.DuplicatesReceived, 1)
 		log.Println("Already received this message. Dropping message!")
 		return true
 	}
@@ -269,7 +410,8 @@ func (p *DiscoveryProtocol) checkMsgReceived(req *api.DiscoveryRequest) bool {
 
 // dhtFindAddrAndStore finds a peer from the DHT and stores it to the node's peerstore
 func (p *DiscoveryProtocol) dhtFindAddrAndStore(initPeerID peer.ID) error {
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), common.RequestTimeout)
+	defer cancel()
 	initPeerInfo, err := p.dht.FindPeer(ctx, initPeerID)
 	if err != nil {
 		return err
@@ -277,33 +419,152 @@ func (p *DiscoveryProtocol) dhtFindAddrAndStore(initPeerID peer.ID) error {
 	log.Println("[DHT] Found this init addresses: ")
 	log.Println(initPeerInfo.Addrs)
 	log.Println("Adding init node to my neighbours:")
-	p.p2pHost.Peerstore().AddAddrs(p.p2pHost.ID(), initPeerInfo.Addrs, ps.PermanentAddrTTL)
+	p.p2pHost.Peerstore().AddAddrs(initPeerID, initPeerInfo.Addrs, ps.PermanentAddrTTL)
 	return nil
 }
 
 // onDiscoveryResponse is a discovery response stream handler
 func (p *DiscoveryProtocol) onDiscoveryResponse(s inet.Stream) {
 	data := &api.DiscoveryResponse{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), discoveryResponse)
+	if err != nil {
+		log.Println("Dropping discovery response. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.DiscoveryMsgData == nil {
+		log.Println("Failed to decode discovery response")
+		p.reputation.Record(s.Conn().RemotePeer(), InvalidResponse)
+		return
+	}
+	p.handleDiscoveryResponse(s.Conn().RemotePeer(), data)
+}
+
+// onDiscoveryRelay is a relayed discovery response stream handler.
+// It passes the response to the next node of its route, or handles it if the node is the initiator.
+func (p *DiscoveryProtocol) onDiscoveryRelay(s inet.Stream) {
+	from := s.Conn().RemotePeer()
+	release, err := p.limiter.Acquire(from, discoveryRelay)
+	if err != nil {
+		log.Println("Dropping relayed discovery response. Error: ", err)
+		return
+	}
+	defer release()
+	data := &api.DiscoveryRelay{}
+	if err := p.limiter.Decode(from, data, s); err != nil || data.Response == nil || data.Response.DiscoveryMsgData == nil {
+		log.Println("Failed to decode relayed discovery response")
+		p.reputation.Record(from, InvalidResponse)
+		return
+	}
+	resp := data.Response
+	// Only the node the route is at handles the response, so that no node can make others relay it
+	if len(data.Route) == 0 || data.Route[0] != p.p2pHost.ID().Pretty() {
+		log.Println("Dropping a relayed discovery response that isn't at this node of its route")
+		return
+	}
+	route := data.Route[1:]
+	if len(route) == 0 {
+		if resp.DiscoveryMsgData.InitNodeID != p.p2pHost.ID().Pretty() {
+			log.Println("Dropping a relayed discovery response for another node")
+			return
+		}
+		p.handleDiscoveryResponse(from, resp)
+		return
+	}
+
+	// Only the responses of authentic responders are relayed. The initiator checks for replays.
+	if valid := authenticatePeerMsg(from, resp, resp.DiscoveryMsgData.MessageData, nil, p.reputation); !valid {
+		log.Println("Failed to authenticate relayed message")
+		return
+	}
+	if len(route) >= maxDiscoveryHops {
+		log.Println("The discovery response has too long a route. Dropping it...")
+		return
+	}
+	atomic.AddUint64(&p.stats.ResponsesRelayed, 1)
+	if p.relayResponse(resp, route) {
+		return
+	}
+	log.Println("Couldn't relay the discovery response. Dialing the initiator...")
+	initPeerID, err := peer.IDB58Decode(resp.DiscoveryMsgData.InitNodeID)
+	if err != nil {
+		return
+	}
+	p.sendResponseDirectly(resp, initPeerID)
+}
 
+// handleDiscoveryResponse handles a discovery response the peer from delivered, either the responder itself or a relay
+func (p *DiscoveryProtocol) handleDiscoveryResponse(from peer.ID, data *api.DiscoveryResponse) {
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.DiscoveryMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(from, data, data.DiscoveryMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
 
-	discoveryPeer := s.Conn().RemotePeer()
+	atomic.AddUint64(&p.stats.ResponsesReceived, 1)
+	// The responder signed the response, so it's known even if the response got relayed
+	discoveryPeer, err := peer.IDB58Decode(data.DiscoveryMsgData.MessageData.NodeId)
+	if err != nil {
+		return
+	}
+	if p.reputation.Banned(discoveryPeer) {
+		log.Printf("Ignoring the discovery response of the banned peer %s", discoveryPeer)
+		return
+	}
+	p.reputation.Responded(discoveryPeer)
 	pubKey := data.DiscoveryMsgData.InitHash // TODO: InitHash is a temporary solution for the public key.
 	log.Println("pubKey: ", pubKey)
 	p.mu.Lock()
-	p.NodeIDs[pubKey] = append(p.NodeIDs[pubKey], discoveryPeer.Pretty())
+	if sentAt, ok := p.sentAt[data.DiscoveryMsgData.InitHash]; ok {
+		atomic.AddUint64(&p.latencyMs, uint64(time.Since(sentAt)/time.Millisecond))
+		atomic.AddUint64(&p.timedResps, 1)
+	}
+	p.NodeIDs[pubKey] = p.rankNodes(append(p.NodeIDs[pubKey], discoveryPeer.Pretty()))
 	if len(p.NodeIDs[pubKey]) == cap(p.NodeIDs[pubKey]) {
 		// TODO: Return the nodes as an event here
 		// TODO: Remove the key of this map
 	}
 	p.mu.Unlock()
 
-	log.Printf("%s: Received discovery response from %s. Message id:%s. Message: %s.", s.Conn().LocalPeer(), discoveryPeer, data.DiscoveryMsgData.MessageData.Id, data.Message)
+	log.Printf("%s: Received discovery response from %s. Message id:%s. Message: %s.", p.p2pHost.ID(), discoveryPeer, data.DiscoveryMsgData.MessageData.Id, data.Message)
+	if data.Availability != nil {
+		log.Printf("%s is available from %s until %s", discoveryPeer,
+			time.Unix(data.Availability.Start, 0), time.Unix(data.Availability.End, 0))
+	}
+}
+
+// rankNodes sorts the node IDs that responded to a discovery request from the most to the least reputable
+func (p *DiscoveryProtocol) rankNodes(nodeIDs []string) []string {
+	peers := make([]peer.ID, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		if pID, err := peer.IDB58Decode(nodeID); err == nil {
+			peers = append(peers, pID)
+		}
+	}
+	p.reputation.Rank(peers)
+	ranked := nodeIDs[:0]
+	for _, pID := range peers {
+		ranked = append(ranked, pID.Pretty())
+	}
+	return ranked
+}
+
+// DiscoveryStats returns the number of discovery messages the node sent and received so far
+// and the average latency of the responses to its requests
+func (p *DiscoveryProtocol) DiscoveryStats() DiscoveryStats {
+	stats := DiscoveryStats{
+		Mode:               p.stats.Mode,
+		RequestsSent:       atomic.LoadUint64(&p.stats.RequestsSent),
+		RequestsReceived:   atomic.LoadUint64(&p.stats.RequestsReceived),
+		DuplicatesReceived: atomic.LoadUint64(&p.stats.DuplicatesReceived),
+		ResponsesSent:      atomic.LoadUint64(&p.stats.ResponsesSent),
+		ResponsesReceived:  atomic.LoadUint64(&p.stats.ResponsesReceived),
+		ResponsesRelayed:   atomic.LoadUint64(&p.stats.ResponsesRelayed),
+	}
+	if timed := atomic.LoadUint64(&p.timedResps); timed > 0 {
+		stats.AvgResponseLatencyMs = atomic.LoadUint64(&p.latencyMs) / timed
+	}
+	return stats
 }
 
 // DeleteDiscoveryMsgs checks for expired received messages
@@ -317,6 +578,7 @@ func (p *DiscoveryProtocol) DeleteDiscoveryMsgs(quit <-chan struct{}) {
 		select {
 		case <-ticker.C:
 			p.deleteExpiredMsgs()
+			p.reputation.ExpireResponses(time.Now())
 		case <-quit:
 			return
 		}
@@ -324,7 +586,7 @@ func (p *DiscoveryProtocol) DeleteDiscoveryMsgs(quit <-chan struct{}) {
 }
 
 // deleteExpiredMsgs iterates over all the receivedMsgs map
-// removing all expired messages
+// removing all expired messages, and forgets when the expired requests of the node were sent
 func (p *DiscoveryProtocol) deleteExpiredMsgs() {
 	now := uint32(time.Now().Unix())
 	for hash, expiry := range p.receivedMsgs {
@@ -333,4 +595,11 @@ func (p *DiscoveryProtocol) deleteExpiredMsgs() {
 			delete(p.receivedMsgs, hash)
 		}
 	}
+	p.mu.Lock()
+	for hash, sentAt := range p.sentAt {
+		if time.Since(sentAt) > common.TTLmsg {
+			delete(p.sentAt, hash)
+		}
+	}
+	p.mu.Unlock()
 }
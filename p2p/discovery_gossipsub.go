@@ -0,0 +1,154 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/log"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	"github.com/gogo/protobuf/proto"
+	peer "github.com/libp2p/go-libp2p-peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+)
+
+// discoveryTopic is the pubsub topic the discovery requests are published on in the gossipsub mode
+const discoveryTopic = "/Discovery/discoverytopic"
+
+// gossipDiscovery spreads the discovery requests through gossipsub instead of flooding them.
+// The requests reach every node of the topic once and the responses go straight to the initiator.
+type gossipDiscovery struct {
+	p      *DiscoveryProtocol
+	pubsub *pubsub.PubSub
+	sub    *pubsub.Subscription
+	cancel context.CancelFunc
+}
+
+// enableGossipsub switches the discovery protocol to the gossipsub mode
+// and starts handling the requests published on the discovery topic
+func (p *DiscoveryProtocol) enableGossipsub() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	ps, err := pubsub.NewGossipSub(ctx, p.p2pHost)
+	if err != nil {
+		cancel()
+		return err
+	}
+	g := &gossipDiscovery{p: p, pubsub: ps, cancel: cancel}
+	// Invalid requests are neither delivered nor passed on to other nodes
+	if err := ps.RegisterTopicValidator(discoveryTopic, g.validate); err != nil {
+		cancel()
+		return err
+	}
+	if g.sub, err = ps.Subscribe(discoveryTopic); err != nil {
+		cancel()
+		return err
+	}
+	p.gossip = g
+	p.stats.Mode = GossipsubDiscovery
+	go g.readRequests(ctx)
+	return nil
+}
+
+// disableGossipsub stops the gossipsub mode, if it's enabled
+func (p *DiscoveryProtocol) disableGossipsub() {
+	if p.gossip != nil {
+		p.gossip.cancel()
+	}
+}
+
+// publish signs a discovery request the node initialized and publishes it on the discovery topic
+func (g *gossipDiscovery) publish(req *api.DiscoveryRequest) {
+	key := g.p.p2pHost.Peerstore().PrivKey(g.p.p2pHost.ID())
+	req.DiscoveryMsgData.MessageData.Sign = signProtoMsg(req, key)
+	data, err := proto.Marshal(req)
+	if err != nil {
+		log.Println("Failed to marshal the discovery request. Error: ", err)
+		return
+	}
+	if err := g.pubsub.Publish(discoveryTopic, data); err != nil {
+		log.Println("Couldn't publish the discovery request. Error: ", err)
+		return
+	}
+	atomic.AddUint64(&g.p.stats.RequestsSent, 1)
+	log.Printf("%s: Discovery message was published. Message Id: %s", g.p.p2pHost.ID(), req.DiscoveryMsgData.MessageData.Id)
+}
+
+// validate checks a request the peer from passed on before gossipsub delivers it or passes it on.
+// Only fresh requests signed by the node that published them are valid.
+func (g *gossipDiscovery) validate(ctx context.Context, from peer.ID, msg *pubsub.Message) bool {
+	if from == g.p.p2pHost.ID() {
+		return true
+	}
+	release, err := g.p.limiter.Acquire(from, discoveryTopic)
+	if err != nil {
+		log.Println("Dropping discovery request. Error: ", err)
+		return false
+	}
+	defer release()
+	data := &api.DiscoveryRequest{}
+	if err := g.p.limiter.Unmarshal(from, data, msg.Data); err != nil || data.DiscoveryMsgData == nil || data.DiscoveryMsgData.MessageData == nil {
+		log.Println("Failed to decode discovery request")
+		return false
+	}
+	if g.p.requestExpired(data) {
+		return false
+	}
+	initNodeID := msg.GetFrom().Pretty()
+	if data.DiscoveryMsgData.InitNodeID != initNodeID || data.DiscoveryMsgData.MessageData.NodeId != initNodeID {
+		log.Println("Dropping a discovery request that wasn't published by its initiator")
+		g.p.reputation.Record(from, AuthFailure)
+		return false
+	}
+	// Replays are checked once the request is delivered, as gossipsub validates it on every node
+	if valid := authenticatePeerMsg(from, data, data.DiscoveryMsgData.MessageData, nil, g.p.reputation); !valid {
+		log.Println("Failed to authenticate message")
+		return false
+	}
+	return true
+}
+
+// readRequests answers the requests delivered on the discovery topic until the context is cancelled
+func (g *gossipDiscovery) readRequests(ctx context.Context) {
+	for {
+		msg, err := g.sub.Next(ctx)
+		if err != nil {
+			return
+		}
+		// The node's own requests are delivered to it as well
+		if msg.GetFrom() == g.p.p2pHost.ID() {
+			continue
+		}
+		data := &api.DiscoveryRequest{}
+		if err := proto.Unmarshal(msg.Data, data); err != nil {
+			continue
+		}
+		log.Printf("%s: Received discovery request of %s. Message: %s", g.p.p2pHost.ID(), msg.GetFrom(), data.Message)
+		atomic.AddUint64(&g.p.stats.RequestsReceived, 1)
+		if g.p.checkMsgReceived(data) {
+			continue
+		}
+		g.p.receivedMsgs[data.DiscoveryMsgData.InitHash] = data.DiscoveryMsgData.Expiry
+		if !g.p.replay.Check(data.DiscoveryMsgData.MessageData, time.Now()) {
+			log.Printf("Rejecting stale or replayed message %s from %s", data.DiscoveryMsgData.MessageData.Id, msg.GetFrom())
+			continue
+		}
+		go g.p.answerDiscoveryRequest(data)
+	}
+}
@@ -17,6 +17,7 @@
 package p2p
 
 import (
+	"context"
 	"encoding/hex"
 	"testing"
 	"time"
@@ -25,6 +26,9 @@ import (
 	"github.com/crowdcompute/crowdengine/crypto"
 	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
 	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,6 +57,24 @@ var (
 	discTestHost10, _ = NewHost(&config.GlobalConfig{
 		P2P: config.P2P{ListenPort: 10216, ListenAddress: "127.0.0.1"},
 	})
+	relayTestInit, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10351, ListenAddress: "127.0.0.1"},
+	})
+	relayTestRelay, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10352, ListenAddress: "127.0.0.1"},
+	})
+	relayTestResponder, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10353, ListenAddress: "127.0.0.1"},
+	})
+	gossipTestInit, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10401, ListenAddress: "127.0.0.1", DiscoveryMode: GossipsubDiscovery},
+	})
+	gossipTestMiddle, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10402, ListenAddress: "127.0.0.1", DiscoveryMode: GossipsubDiscovery},
+	})
+	gossipTestFar, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10403, ListenAddress: "127.0.0.1", DiscoveryMode: GossipsubDiscovery},
+	})
 )
 
 func discoveryRequestMsg(host host.Host) *api.DiscoveryRequest {
@@ -116,3 +138,100 @@ func TestCopyNewDiscoveryRequestHaveDiffSignatures(t *testing.T) {
 	copiedReqSignature := string(copiedReq.DiscoveryMsgData.MessageData.Sign)
 	assert.True(t, reqSignature != copiedReqSignature)
 }
+
+// TestCopyNewDiscoveryRequestKeepsRequirements checks that the job requirements travel along with the forwarded request
+func TestCopyNewDiscoveryRequestKeepsRequirements(t *testing.T) {
+	req := discoveryRequestMsg(discTestHost9.P2PHost)
+	req.Requirements = &api.JobRequirements{Cpu: 1, Memory: 256, Arch: "amd64"}
+	copiedReq := discTestHost10.copyNewDiscoveryRequest(req)
+	assert.True(t, copiedReq.Requirements.Cpu == 1 && copiedReq.Requirements.Memory == 256)
+	assert.True(t, copiedReq.Requirements.Arch == "amd64")
+}
+
+// TestCopyNewDiscoveryRequestExtendsPath checks that every forwarding node adds itself to the path of the request
+func TestCopyNewDiscoveryRequestExtendsPath(t *testing.T) {
+	req, err := discTestHost9.GetInitialDiscoveryReq(nil)
+	assert.NoError(t, err)
+	copiedReq := discTestHost10.copyNewDiscoveryRequest(req)
+	path := []string{discTestHost9.P2PHost.ID().Pretty(), discTestHost10.P2PHost.ID().Pretty()}
+	assert.Equal(t, path, copiedReq.DiscoveryMsgData.Path)
+	assert.Equal(t, []string{path[1], path[0]}, reverseRoute(copiedReq.DiscoveryMsgData.Path))
+	assert.Len(t, req.DiscoveryMsgData.Path, 1)
+}
+
+// TestRelayDiscoveryResponse checks that a response reaches an initiator the responder isn't connected to
+// along the reverse path of the request, signed by the responder
+func TestRelayDiscoveryResponse(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, relayTestInit.P2PHost.Connect(ctx, peerInfo(relayTestRelay)))
+	assert.NoError(t, relayTestResponder.P2PHost.Connect(ctx, peerInfo(relayTestRelay)))
+
+	req, err := relayTestInit.GetInitialDiscoveryReq(nil)
+	assert.NoError(t, err)
+	initHash := req.DiscoveryMsgData.InitHash
+	relayTestInit.InitializeDiscovery(initHash, 1)
+	forwarded := relayTestRelay.copyNewDiscoveryRequest(req)
+	assert.True(t, relayTestResponder.DiscoveryProtocol.createSendResponse(forwarded))
+
+	responder := relayTestResponder.P2PHost.ID().Pretty()
+	for i := 0; i < 50 && len(discoveredNodes(relayTestInit, initHash)) == 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Equal(t, []string{responder}, discoveredNodes(relayTestInit, initHash))
+	assert.Equal(t, uint64(1), relayTestRelay.DiscoveryStats().ResponsesRelayed)
+	assert.NotEqual(t, inet.Connected, relayTestInit.P2PHost.Network().Connectedness(relayTestResponder.P2PHost.ID()))
+
+	// A response whose route isn't at the relay is dropped
+	resp := &api.DiscoveryResponse{DiscoveryMsgData: NewDiscoveryMsgData("relay-elsewhere", false, relayTestResponder.P2PHost)}
+	key := relayTestResponder.P2PHost.Peerstore().PrivKey(relayTestResponder.P2PHost.ID())
+	resp.DiscoveryMsgData.MessageData.Sign = signProtoMsg(resp, key)
+	relay := &api.DiscoveryRelay{Response: resp, Route: []string{relayTestInit.P2PHost.ID().Pretty()}}
+	assert.True(t, sendMsg(relayTestResponder.P2PHost, relayTestRelay.P2PHost.ID(), relay, protocol.ID(discoveryRelay)))
+	time.Sleep(200 * time.Millisecond)
+	assert.Equal(t, uint64(1), relayTestRelay.DiscoveryStats().ResponsesRelayed)
+}
+
+// TestGossipsubDiscovery checks that a request published on the discovery topic reaches the nodes
+// the initiator isn't connected to, and that their responses come back and are timed
+func TestGossipsubDiscovery(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, gossipTestInit.P2PHost.Connect(ctx, peerInfo(gossipTestMiddle)))
+	assert.NoError(t, gossipTestFar.P2PHost.Connect(ctx, peerInfo(gossipTestMiddle)))
+	for i := 0; i < 50 && len(gossipTestMiddle.gossip.pubsub.ListPeers(discoveryTopic)) < 2; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	// Wait for the heartbeat of gossipsub to add the peers to the mesh of the topic
+	time.Sleep(2 * pubsub.GossipSubHeartbeatInterval)
+
+	req, err := gossipTestInit.GetInitialDiscoveryReq(nil)
+	assert.NoError(t, err)
+	initHash := req.DiscoveryMsgData.InitHash
+	gossipTestInit.InitializeDiscovery(initHash, 2)
+	gossipTestInit.SendDiscoveryReq(req)
+
+	for i := 0; i < 50 && len(discoveredNodes(gossipTestInit, initHash)) < 2; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.ElementsMatch(t, []string{gossipTestMiddle.P2PHost.ID().Pretty(), gossipTestFar.P2PHost.ID().Pretty()},
+		discoveredNodes(gossipTestInit, initHash))
+	stats := gossipTestInit.DiscoveryStats()
+	assert.Equal(t, GossipsubDiscovery, stats.Mode)
+	assert.Equal(t, uint64(1), stats.RequestsSent)
+	assert.Equal(t, uint64(2), stats.ResponsesReceived)
+	assert.Equal(t, uint64(1), gossipTestFar.DiscoveryStats().RequestsReceived)
+	assert.Equal(t, uint64(0), gossipTestMiddle.DiscoveryStats().ResponsesRelayed)
+}
+
+func TestCheckDiscoveryMode(t *testing.T) {
+	assert.Nil(t, checkDiscoveryMode(""))
+	assert.Nil(t, checkDiscoveryMode(FloodDiscovery))
+	assert.Nil(t, checkDiscoveryMode(GossipsubDiscovery))
+	assert.NotNil(t, checkDiscoveryMode("unknown"))
+}
+
+// discoveredNodes returns the nodes that responded to the discovery request of the hash initHash
+func discoveredNodes(h *Host, initHash string) []string {
+	h.DiscoveryProtocol.mu.Lock()
+	defer h.DiscoveryProtocol.mu.Unlock()
+	return append([]string{}, h.NodeIDs[initHash]...)
+}
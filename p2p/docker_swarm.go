@@ -27,6 +27,7 @@ import (
 	"github.com/crowdcompute/crowdengine/common"
 	"github.com/crowdcompute/crowdengine/manager"
 	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	"github.com/gogo/protobuf/proto"
 	host "github.com/libp2p/go-libp2p-host"
 	net "github.com/libp2p/go-libp2p-net"
 	peer "github.com/libp2p/go-libp2p-peer"
@@ -34,14 +35,14 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-// pattern: /protocol-name/request-or-response-message/version
-const joinReq = "/swarm/joinreq/0.0.1"
-const joinResOK = "/swarm/joinrespOK/0.0.1"
-const joinReqToken = "/swarm/joinreqtoken/0.0.1"
-const joinResJoined = "/swarm/joinresjoined/0.0.1"
-const leaveReq = "/swarm/leavereq/0.0.1"
-const leaveResOK = "/swarm/leaveres/0.0.1"
-const cantLeaveRes = "/swarm/cantleaveres/0.0.1"
+// pattern: /protocol-name/request-or-response-message, which gets a /version suffix from protocolVersions
+const joinReq = "/swarm/joinreq"
+const joinResOK = "/swarm/joinrespOK"
+const joinReqToken = "/swarm/joinreqtoken"
+const joinResJoined = "/swarm/joinresjoined"
+const leaveReq = "/swarm/leavereq"
+const leaveResOK = "/swarm/leaveres"
+const cantLeaveRes = "/swarm/cantleaveres"
 
 // SwarmProtocol type
 type SwarmProtocol struct {
@@ -52,27 +53,50 @@ type SwarmProtocol struct {
 	ManagerToken string
 	managerIP    string
 	swarmcfg     *config.DockerSwarm
+	replay       *ReplayGuard // Rejects stale and replayed messages
+	reputation   *Reputation  // Records the outcomes of the interactions with peers
+	limiter      *Limiter     // Limits the work each peer can make the node do
 }
 
 // NewSwarmProtocol sets the protocol's stream handlers and returns a new SwarmProtocol
-func NewSwarmProtocol(p2pHost host.Host, cfg *config.DockerSwarm) *SwarmProtocol {
+func NewSwarmProtocol(p2pHost host.Host, cfg *config.DockerSwarm, replay *ReplayGuard, reputation *Reputation, limiter *Limiter) *SwarmProtocol {
 	p := &SwarmProtocol{
 		p2pHost:    p2pHost,
 		managerIP:  cfg.AdvertiseAddress,
 		joinedNode: make(chan struct{}),
 		leaveNode:  make(chan struct{}),
 		swarmcfg:   cfg,
+		replay:     replay,
+		reputation: reputation,
+		limiter:    limiter,
 	}
-	p2pHost.SetStreamHandler(joinReq, p.onJoinRequest)
-	p2pHost.SetStreamHandler(joinResOK, p.onJoinResponseOK)
-	p2pHost.SetStreamHandler(joinReqToken, p.onJoinReqToken)
-	p2pHost.SetStreamHandler(joinResJoined, p.onJoinResJoined)
-	p2pHost.SetStreamHandler(leaveReq, p.onLeaveRequest)
-	p2pHost.SetStreamHandler(leaveResOK, p.onLeaveResponseOK)
-	p2pHost.SetStreamHandler(cantLeaveRes, p.onCantLeaveResponse)
+	setStreamHandler(p2pHost, joinReq, p.onJoinRequest)
+	setStreamHandler(p2pHost, joinResOK, p.onJoinResponseOK)
+	setStreamHandler(p2pHost, joinReqToken, p.onJoinReqToken)
+	setStreamHandler(p2pHost, joinResJoined, p.onJoinResJoined)
+	setStreamHandler(p2pHost, leaveReq, p.onLeaveRequest)
+	setStreamHandler(p2pHost, leaveResOK, p.onLeaveResponseOK)
+	setStreamHandler(p2pHost, cantLeaveRes, p.onCantLeaveResponse)
 	return p
 }
 
+// admit applies the limits to a message of the protocol protoID and decodes it into data.
+// The returned release function must be called once the message is handled.
+// The Swarm protocol has no error responses, so the messages over the limits are just dropped.
+func (p *SwarmProtocol) admit(s net.Stream, protoID protocol.ID, data proto.Message) (func(), bool) {
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), protoID)
+	if err != nil {
+		log.Printf("Dropping %s message. Error: %s", protoID, err)
+		return nil, false
+	}
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil {
+		log.Printf("Failed to decode %s message. Error: %s", protoID, err)
+		release()
+		return nil, false
+	}
+	return release, true
+}
+
 // SendJoinToPeersAndWait sends a join swarm request to <nodes>
 // And waits until <len(nodes)> nodes are connected
 func (p *SwarmProtocol) SendJoinToPeersAndWait(nodes []string) {
@@ -120,8 +144,12 @@ func (p *SwarmProtocol) onJoinRequest(s net.Stream) {
 	log.Printf("%s: Received join swarm request from %s.", s.Conn().LocalPeer(), s.Conn().RemotePeer())
 
 	data := &api.JoinRequest{}
-	decodeProtoMessage(data, s)
-	if valid := authenticateProtoMsg(data, data.MessageData); !valid {
+	release, ok := p.admit(s, joinReq, data)
+	if !ok {
+		return
+	}
+	defer release()
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
@@ -158,8 +186,12 @@ func nodePartOfSwarm() (bool, error) {
 // and sends the Join token and address back to the node
 func (p *SwarmProtocol) onJoinResponseOK(s net.Stream) {
 	data := &api.JoinResponse{}
-	decodeProtoMessage(data, s)
-	valid := authenticateProtoMsg(data, data.MessageData)
+	release, ok := p.admit(s, joinResOK, data)
+	if !ok {
+		return
+	}
+	defer release()
+	valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.MessageData, p.replay, p.reputation)
 	if !valid {
 		log.Println("Failed to authenticate message")
 		return
@@ -191,8 +223,12 @@ func (p *SwarmProtocol) onJoinReqToken(s net.Stream) {
 	log.Printf("%s: Received join request with Token from %s.", s.Conn().LocalPeer(), s.Conn().RemotePeer())
 
 	data := &api.JoinRequest{}
-	decodeProtoMessage(data, s)
-	valid := authenticateProtoMsg(data, data.MessageData)
+	release, ok := p.admit(s, joinReqToken, data)
+	if !ok {
+		return
+	}
+	defer release()
+	valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.MessageData, p.replay, p.reputation)
 	if !valid {
 		log.Println("Failed to authenticate message")
 		return
@@ -239,9 +275,13 @@ func (p *SwarmProtocol) createSendResponse(toPeer peer.ID, messageType api.Messa
 // Getting a sesponse from a node that they joined the swarm successfully
 func (p *SwarmProtocol) onJoinResJoined(s net.Stream) {
 	data := &api.JoinResponse{}
-	decodeProtoMessage(data, s)
+	release, ok := p.admit(s, joinResJoined, data)
+	if !ok {
+		return
+	}
+	defer release()
 
-	valid := authenticateProtoMsg(data, data.MessageData)
+	valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.MessageData, p.replay, p.reputation)
 
 	if !valid {
 		log.Println("Failed to authenticate message")
@@ -289,8 +329,12 @@ func (p *SwarmProtocol) onLeaveRequest(s net.Stream) {
 	log.Printf("%s: Received leave swarm request from %s.", s.Conn().LocalPeer(), s.Conn().RemotePeer())
 
 	data := &api.LeaveRequest{}
-	decodeProtoMessage(data, s)
-	if valid := authenticateProtoMsg(data, data.MessageData); !valid {
+	release, ok := p.admit(s, leaveReq, data)
+	if !ok {
+		return
+	}
+	defer release()
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
@@ -333,8 +377,12 @@ func (p *SwarmProtocol) onLeaveRequest(s net.Stream) {
 // onLeaveResponseOK is a leave response stream handler
 func (p *SwarmProtocol) onLeaveResponseOK(s net.Stream) {
 	data := &api.LeaveResponse{}
-	decodeProtoMessage(data, s)
-	valid := authenticateProtoMsg(data, data.MessageData)
+	release, ok := p.admit(s, leaveResOK, data)
+	if !ok {
+		return
+	}
+	defer release()
+	valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.MessageData, p.replay, p.reputation)
 	if !valid {
 		log.Println("Failed to authenticate message")
 		return
@@ -347,8 +395,12 @@ func (p *SwarmProtocol) onLeaveResponseOK(s net.Stream) {
 // onCantLeaveResponse is a can't leave response stream handler
 func (p *SwarmProtocol) onCantLeaveResponse(s net.Stream) {
 	data := &api.CantLeaveResponse{}
-	decodeProtoMessage(data, s)
-	valid := authenticateProtoMsg(data, data.MessageData)
+	release, ok := p.admit(s, cantLeaveRes, data)
+	if !ok {
+		return
+	}
+	defer release()
+	valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.MessageData, p.replay, p.reputation)
 	if !valid {
 		log.Println("Failed to authenticate message")
 		return
@@ -19,28 +19,59 @@ package p2p
 import (
 	"context"
 	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"time"
 
 	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	"github.com/crowdcompute/crowdengine/common"
 	"github.com/crowdcompute/crowdengine/log"
 
 	ds "github.com/ipfs/go-datastore"
 	dsync "github.com/ipfs/go-datastore/sync"
 	libp2p "github.com/libp2p/go-libp2p"
+	circuit "github.com/libp2p/go-libp2p-circuit"
 	crypto "github.com/libp2p/go-libp2p-crypto"
 	host "github.com/libp2p/go-libp2p-host"
+	pnet "github.com/libp2p/go-libp2p-interface-pnet"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
+	dhtopts "github.com/libp2p/go-libp2p-kad-dht/opts"
+	inet "github.com/libp2p/go-libp2p-net"
 	peer "github.com/libp2p/go-libp2p-peer"
 	ps "github.com/libp2p/go-libp2p-peerstore"
-	rhost "github.com/libp2p/go-libp2p/p2p/host/routed"
+	routing "github.com/libp2p/go-libp2p-routing"
 	ma "github.com/multiformats/go-multiaddr"
 )
 
+// datastoreDirName is the name of the p2p datastore's directory inside the data directory
+const datastoreDirName = "p2pstore"
+
 // Host represents a libp2p host
 type Host struct {
 	P2PHost  host.Host
 	dht      *dht.IpfsDHT
-	FullAddr string
+	dstore   ds.Batching // Holds the DHT records and the known peers
 	Cfg      *config.GlobalConfig
+	Capacity *CapacityTracker
+	// Availability holds the daily windows the node accepts jobs
+	Availability *common.Availability
+	// Replay rejects stale and replayed messages of all protocols
+	Replay *ReplayGuard
+	// Reputation records the outcomes of the interactions with peers of all protocols
+	Reputation *Reputation
+	// Limiter limits the work each peer can make the node do through each protocol
+	Limiter *Limiter
+	// ConnManager dials the bootnodes and keeps the number of peers within the configured limits
+	ConnManager *ConnManager
+	// MDNS finds the nodes of the local network. It's nil unless mDNS is enabled.
+	MDNS *MDNSService
+	// AutoNAT tells the nodes behind NAT if they can be dialed. It's nil unless the node is a relay server.
+	AutoNAT *AutoNATService
+	// Bootnode is set if the host only runs the DHT and the relay, without the job protocols
+	Bootnode bool
+	// Membership allows only the members of a private network to connect. It's nil unless a membership admin is set.
+	Membership *Membership
 
 	*SwarmProtocol
 	*TaskProtocol
@@ -53,98 +84,262 @@ type Host struct {
 
 // NewHost creates a new Host
 func NewHost(cfg *config.GlobalConfig) (*Host, error) {
-	nodes := cfg.P2P.Bootstraper.Nodes
-	ip := cfg.P2P.ListenAddress
-	port := cfg.P2P.ListenPort
-	host := &Host{Cfg: cfg}
-	err := host.makeRandomHost(port, ip)
+	availability, err := common.ParseAvailability(cfg.Global.Availability)
 	if err != nil {
 		return nil, err
 	}
-	if len(nodes) > 0 {
-		err = host.ConnectWithNodes(nodes)
+	if err := checkDiscoveryMode(cfg.P2P.DiscoveryMode); err != nil {
+		return nil, err
+	}
+	host := &Host{Cfg: cfg, Availability: availability}
+	if err := host.setup(); err != nil {
+		return nil, err
+	}
+	if err := host.registerProtocols(); err != nil {
+		return nil, err
 	}
-	log.Print("Here is my p2p ID: ")
-	host.FullAddr = fmt.Sprintf("/ip4/%s/tcp/%d/ipfs/%s", ip, port, host.P2PHost.ID().Pretty())
-	log.Println(host.FullAddr)
-	host.registerProtocols()
-	return host, err
+	return host, host.join()
+}
+
+// NewBootnodeHost creates a Host that only runs the parts other nodes need to join the network:
+// the DHT, which is also the rendezvous of the relays, and a relay server. It serves none of
+// the job protocols, so it doesn't need Docker.
+func NewBootnodeHost(cfg *config.GlobalConfig) (*Host, error) {
+	// Bootnodes relay the connections of the nodes behind NAT
+	cfg.P2P.RelayServer = true
+	host := &Host{Cfg: cfg, Bootnode: true}
+	if err := host.setup(); err != nil {
+		return nil, err
+	}
+	return host, host.join()
+}
+
+// setup creates the libp2p host and the services that don't depend on the protocols
+func (h *Host) setup() error {
+	cfg := h.Cfg
+	priv, err := nodeIdentity(&cfg.Global)
+	if err != nil {
+		return err
+	}
+	if err := h.makeHost(priv); err != nil {
+		return err
+	}
+	if cfg.P2P.MembershipAdmin != "" {
+		admin, err := peer.IDB58Decode(cfg.P2P.MembershipAdmin)
+		if err != nil {
+			return err
+		}
+		membershipFile := MembershipFile(cfg.P2P.MembershipFile, cfg.Global.DataDir)
+		if h.Membership, err = NewMembership(h.P2PHost, membershipFile, admin); err != nil {
+			return err
+		}
+	}
+	// The reputations are stored in the database of the data directory
+	h.Reputation = NewReputation(cfg.Global.DataDir != "")
+	h.Replay = NewReplayGuard(time.Duration(cfg.P2P.MessageFreshness)*time.Second, cfg.P2P.NonceCacheSize)
+	h.Limiter = NewLimiter(&cfg.P2P.Limits, h.Reputation)
+	h.ConnManager = NewConnManager(h.P2PHost, &cfg.P2P, h.Reputation)
+	if cfg.P2P.MDNS {
+		if h.MDNS, err = NewMDNSService(h.P2PHost, cfg.P2P.NetworkName, h.connectLocalPeer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join connects to the bootnodes and to the peers of the previous run
+func (h *Host) join() error {
+	var err error
+	if nodes := h.Cfg.P2P.Bootstraper.Nodes; len(nodes) > 0 {
+		// Dial the bootnodes in the background, so a slow or unreachable bootnode doesn't block the start
+		var peers []ps.PeerInfo
+		if peers, err = h.addNodesToPeerstore(nodes); err == nil {
+			log.Println("Connecting to the bootnodes: ", nodes)
+			h.ConnManager.ConnectAsync(peers)
+		}
+	}
+	log.Print("Here are my p2p addresses: ")
+	log.Println(h.FullAddrs())
+	// Reconnect to the peers of the previous run, so that no bootnodes are needed
+	h.reconnectPeers()
+	return err
 }
 
 // registerProtocols registers all protocols for the node
-func (h *Host) registerProtocols() {
-	h.SwarmProtocol = NewSwarmProtocol(h.P2PHost, &h.Cfg.Host.DockerSwarm)
-	h.DiscoveryProtocol = NewDiscoveryProtocol(h.P2PHost, h.dht)
-	h.TaskProtocol = NewTaskProtocol(h.P2PHost)
+func (h *Host) registerProtocols() error {
+	h.SwarmProtocol = NewSwarmProtocol(h.P2PHost, &h.Cfg.Host.DockerSwarm, h.Replay, h.Reputation, h.Limiter)
+	h.Capacity = NewCapacityTracker(&h.Cfg.Host)
+	h.TaskProtocol = NewTaskProtocol(h.P2PHost, h.Capacity, h.Availability, h.Replay, h.Reputation, h.Limiter)
+	h.DiscoveryProtocol = NewDiscoveryProtocol(h.P2PHost, h.dht, h.Capacity, h.Availability, h.Replay, h.Reputation, h.Limiter)
 	// Registering the Observer that wants to get notified when the task is done.
 	h.TaskProtocol.Register(h.DiscoveryProtocol)
-	h.UploadImageProtocol = NewUploadImageProtocol(h.P2PHost)
-	h.InspectContainerProtocol = NewInspectContainerProtocol(h.P2PHost)
-	h.ListImagesProtocol = NewListImagesProtocol(h.P2PHost)
-	h.ListContainersProtocol = NewListContainersProtocol(h.P2PHost)
-}
-
-// makeRandomHost creates a libp2p host with a randomly generated identity.
-func (h *Host) makeRandomHost(port int, IP string) error {
-	// Ignoring most errors for brevity
-	// priv, _, err := crypto.GenerateKeyPairWithReader(crypto.RSA, 2048, rand.Reader)
-	// priv, _, _ := crypto.GenerateKeyPair(crypto.Secp256k1, 256)
-	priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 256)
+	h.UploadImageProtocol = NewUploadImageProtocol(h.P2PHost, h.Replay, h.Reputation, h.Limiter)
+	h.InspectContainerProtocol = NewInspectContainerProtocol(h.P2PHost, h.Replay, h.Reputation, h.Limiter)
+	h.ListImagesProtocol = NewListImagesProtocol(h.P2PHost, h.Replay, h.Reputation, h.Limiter)
+	h.ListContainersProtocol = NewListContainersProtocol(h.P2PHost, h.Replay, h.Reputation, h.Limiter)
+	if h.Cfg.P2P.DiscoveryMode == GossipsubDiscovery {
+		return h.DiscoveryProtocol.enableGossipsub()
+	}
+	return nil
+}
+
+// makeHost creates a libp2p host with the identity priv.
+func (h *Host) makeHost(priv crypto.PrivKey) error {
+	listen, err := listenAddrs(&h.Cfg.P2P)
+	if err != nil {
+		return err
+	}
+	announce, err := parseAddrs(h.Cfg.P2P.AnnounceAddrs)
 	if err != nil {
 		return err
 	}
-	// listen, _ := ma.NewMultiaddr(fmt.Sprintf("/ip4/%s/tcp/%d", IP, port))
-	listen, _ := ma.NewMultiaddr(fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port))
-	host, _ := libp2p.New(
-		context.Background(),
-		libp2p.ListenAddrs(listen),
-		libp2p.Identity(priv),
-	)
 
-	// Construct a datastore (needed by the DHT). This is just a simple, in-memory thread-safe datastore.
-	dstore := dsync.MutexWrap(ds.NewMapDatastore())
+	// Construct a datastore (needed by the DHT)
+	if h.dstore, err = openDatastore(h.Cfg.Global.DataDir); err != nil {
+		return err
+	}
 
-	// Make the DHT
 	ctx := context.Background()
-	h.dht = dht.NewDHT(ctx, host, dstore)
-
-	// Make the routed host
-	h.P2PHost = rhost.Wrap(host, h.dht)
+	opts := []libp2p.Option{
+		libp2p.ListenAddrs(listen...),
+		libp2p.Identity(priv),
+		// The DHT routes the host to peers it only knows the ID of, and lets nodes behind NAT find relays
+		libp2p.Routing(func(p2pHost host.Host) (routing.PeerRouting, error) {
+			var err error
+			h.dht, err = dht.New(ctx, p2pHost, dhtopts.Datastore(h.dstore),
+				dhtopts.NamespacedValidator(capabilityNamespace, CapabilityValidator{}))
+			return h.dht, err
+		}),
+	}
+	opts = append(opts, natOptions(&h.Cfg.P2P)...)
+	if len(announce) > 0 {
+		opts = append(opts, libp2p.AddrsFactory(func([]ma.Multiaddr) []ma.Multiaddr { return announce }))
+	}
+	// Only nodes with the same pre-shared key can connect to a private network
+	var protector pnet.Protector
+	if h.Cfg.P2P.SwarmKeyFile != "" {
+		psk, err := NewPSKProtector(h.Cfg.P2P.SwarmKeyFile)
+		if err != nil {
+			return err
+		}
+		protector = psk
+		opts = append(opts, libp2p.PrivateNetwork(protector))
+	}
+	// The host is wrapped in a routed host, since the routing is set
+	if h.P2PHost, err = libp2p.New(ctx, opts...); err != nil {
+		return err
+	}
+	if h.Cfg.P2P.RelayServer {
+		if h.AutoNAT, err = NewAutoNATService(ctx, h.P2PHost, protector); err != nil {
+			return err
+		}
+	}
 
 	// Bootstrap the host
 	return h.dht.Bootstrap(ctx)
 }
 
-// ConnectWithNodes establishes a libp2p connection with the nodes
+// natOptions returns the libp2p options that make the node reachable behind NAT.
+// Every node can dial and be dialed through relays. Hole punching isn't supported by this
+// version of libp2p, so the port is mapped on the router through UPnP or NAT-PMP instead.
+func natOptions(cfg *config.P2P) []libp2p.Option {
+	var opts []libp2p.Option
+	if cfg.NATPortMap {
+		opts = append(opts, libp2p.NATPortMap())
+	}
+	switch {
+	case cfg.RelayServer:
+		// Relay servers advertise themselves in the DHT, so that the nodes behind NAT find them
+		opts = append(opts, libp2p.EnableRelay(circuit.OptHop), libp2p.EnableAutoRelay())
+	case cfg.AutoRelay:
+		opts = append(opts, libp2p.EnableRelay(), libp2p.EnableAutoRelay())
+	default:
+		opts = append(opts, libp2p.EnableRelay())
+	}
+	return opts
+}
+
+// openDatastore opens the on-disk datastore of the data directory dataDir.
+// Without a data directory an in-memory thread-safe datastore is used.
+func openDatastore(dataDir string) (ds.Batching, error) {
+	if dataDir == "" {
+		return dsync.MutexWrap(ds.NewMapDatastore()), nil
+	}
+	return newLvlDatastore(filepath.Join(dataDir, datastoreDirName))
+}
+
+// Close stores the peers the node is connected to, stops the AutoNAT service and the gossipsub discovery
+// and closes the datastore
+func (h *Host) Close() error {
+	if err := h.savePeers(); err != nil {
+		log.Println("Couldn't store the known peers. Error: ", err)
+	}
+	if h.DiscoveryProtocol != nil {
+		h.DiscoveryProtocol.disableGossipsub()
+	}
+	if h.AutoNAT != nil {
+		h.AutoNAT.Close()
+	}
+	if closer, ok := h.dstore.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// ConnectWithNodes adds the nodes to the peerstore and establishes a libp2p connection with them.
+// Nodes that can't be reached are logged, only invalid addresses are returned as errors.
 func (h *Host) ConnectWithNodes(nodes []string) error {
 	log.Println("Connecting to the nodes: ", nodes)
+	peers, err := h.addNodesToPeerstore(nodes)
+	if err != nil {
+		return err
+	}
+	h.ConnManager.Connect(peers)
+	return nil
+}
+
+// addNodesToPeerstore adds the addresses of all the nodes to the peerstore
+func (h *Host) addNodesToPeerstore(nodes []string) ([]ps.PeerInfo, error) {
+	peers := make([]ps.PeerInfo, 0, len(nodes))
 	for _, nodeAddr := range nodes {
-		if err := h.addAddrToPeerstore(nodeAddr); err != nil {
-			return err
+		pi, err := h.addAddrToPeerstore(nodeAddr)
+		if err != nil {
+			return nil, err
 		}
+		peers = append(peers, pi)
 	}
-	return nil
+	return peers, nil
+}
+
+// connectLocalPeer connects to a node found on the local network, if not connected already
+func (h *Host) connectLocalPeer(pi ps.PeerInfo) {
+	if h.P2PHost.Network().Connectedness(pi.ID) == inet.Connected {
+		return
+	}
+	log.Println("Found a node on the local network: ", pi.ID.Pretty())
+	h.P2PHost.Peerstore().AddAddrs(pi.ID, pi.Addrs, ps.AddressTTL)
+	go h.ConnManager.dial([]ps.PeerInfo{pi})
 }
 
 // addAddrToPeerstore parses a peer multiaddress and adds
 // it to the given host's peerstore, so it knows how to
-// contact it. It returns the peer ID of the remote peer.
-func (h *Host) addAddrToPeerstore(addr string) error {
+// contact it. It returns the peer info of the remote peer.
+func (h *Host) addAddrToPeerstore(addr string) (ps.PeerInfo, error) {
 	// The following code extracts target's the peer ID from the
 	// given multiaddress
 	ipfsaddr, err := ma.NewMultiaddr(addr)
 	if err != nil {
-		return err
+		return ps.PeerInfo{}, err
 	}
 
 	pid, err := ipfsaddr.ValueForProtocol(ma.P_IPFS)
 	if err != nil {
-		return err
+		return ps.PeerInfo{}, err
 	}
 
 	peerid, err := peer.IDB58Decode(pid)
 	if err != nil {
-		return err
+		return ps.PeerInfo{}, err
 	}
 	// Decapsulate the /ipfs/<peerID> part from the target
 	// /ip4/<a.b.c.d>/ipfs/<peer> becomes /ip4/<a.b.c.d>
@@ -156,7 +351,7 @@ func (h *Host) addAddrToPeerstore(addr string) error {
 	// it to the peerstore so LibP2P knows how to contact it
 	h.P2PHost.Peerstore().AddAddr(peerid, targetAddr, ps.PermanentAddrTTL)
 
-	return nil
+	return ps.PeerInfo{ID: peerid, Addrs: []ma.Multiaddr{targetAddr}}, nil
 }
 
 // PeerCount returns the number of peers in the node's peerstore
@@ -164,3 +359,31 @@ func (h *Host) PeerCount() int {
 	// Peerstore has the current node's address as well, so we don't want to count it
 	return h.P2PHost.Peerstore().Peers().Len() - 1
 }
+
+// NetworkStats are the statistics of the node's network and memory usage
+type NetworkStats struct {
+	ID          string
+	Addresses   []string
+	Bootnode    bool
+	RelayServer bool
+	Peers       int    // The peers the node is connected to
+	KnownPeers  int    // The peers of the peerstore
+	MemoryAlloc uint64 // Bytes of allocated heap objects
+	MemorySys   uint64 // Bytes of memory obtained from the OS
+}
+
+// Stats returns the statistics of the node's network
+func (h *Host) Stats() NetworkStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return NetworkStats{
+		ID:          h.P2PHost.ID().Pretty(),
+		Addresses:   h.FullAddrs(),
+		Bootnode:    h.Bootnode,
+		RelayServer: h.Cfg.P2P.RelayServer,
+		Peers:       len(h.P2PHost.Network().Peers()),
+		KnownPeers:  h.PeerCount(),
+		MemoryAlloc: mem.Alloc,
+		MemorySys:   mem.Sys,
+	}
+}
@@ -32,10 +32,20 @@ var (
 	hostTestHost2, _ = NewHost(&config.GlobalConfig{
 		P2P: config.P2P{ListenPort: 10210, ListenAddress: "127.0.0.1"},
 	})
+	hostTestBootnode, _ = NewBootnodeHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10381, ListenAddress: "127.0.0.1"},
+	})
+	hostTestHost3, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10382, ListenAddress: "127.0.0.1",
+			Bootstraper: config.Bootstraper{
+				Nodes: []string{hostTestBootnode.FullAddr()},
+			},
+		},
+	})
 )
 
 func TestConnectWithNodes(t *testing.T) {
-	hostTestHost1.ConnectWithNodes([]string{hostTestHost2.FullAddr})
+	hostTestHost1.ConnectWithNodes([]string{hostTestHost2.FullAddr()})
 	// Should have itself and the peer 5
 	noOfPeers := hostTestHost1.P2PHost.Peerstore().Peers().Len()
 	assert.True(t, noOfPeers == 2)
@@ -43,3 +53,19 @@ func TestConnectWithNodes(t *testing.T) {
 	existsInPeerstore := common.SliceExists(hostTestHost1.P2PHost.Peerstore().Peers(), hostTestHost2.P2PHost.ID())
 	assert.True(t, existsInPeerstore)
 }
+
+func TestBootnodeHost(t *testing.T) {
+	// The bootnode relays and serves the DHT, but none of the job protocols
+	protocols := hostTestBootnode.P2PHost.Mux().Protocols()
+	assert.NotContains(t, protocols, string(protocolIDs(discoveryRequest)[0]))
+	assert.NotContains(t, protocols, string(protocolIDs(runRequest)[0]))
+	assert.Nil(t, hostTestBootnode.DiscoveryProtocol)
+	assert.NotNil(t, hostTestBootnode.AutoNAT)
+
+	// Nodes join the network through the bootnode
+	assert.Contains(t, hostTestBootnode.P2PHost.Network().Peers(), hostTestHost3.P2PHost.ID())
+	stats := hostTestBootnode.Stats()
+	assert.True(t, stats.Bootnode)
+	assert.True(t, stats.RelayServer)
+	assert.Equal(t, 1, stats.Peers)
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/accounts/keystore"
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	cccrypto "github.com/crowdcompute/crowdengine/crypto"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	"github.com/pborman/uuid"
+)
+
+// identityFileName is the name of the node's identity key file inside the data directory
+const identityFileName = "nodekey"
+
+// ErrIdentityEncrypted is returned when loading an encrypted identity without a passphrase
+var ErrIdentityEncrypted = errors.New("The node identity is encrypted, a passphrase is needed")
+
+// IdentityFile returns the path of the node's identity key file. An empty path means no persistent identity.
+func IdentityFile(keyFile, dataDir string) string {
+	if keyFile != "" || dataDir == "" {
+		return keyFile
+	}
+	return filepath.Join(dataDir, identityFileName)
+}
+
+// ReadPassphraseFile reads a passphrase from a file. An empty file path means no passphrase.
+func ReadPassphraseFile(passphraseFile string) (string, error) {
+	if passphraseFile == "" {
+		return "", nil
+	}
+	data, err := ioutil.ReadFile(passphraseFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// nodeIdentity returns the node's persistent identity as configured in cfg,
+// or a random one if there is no place to store it.
+func nodeIdentity(cfg *config.Global) (crypto.PrivKey, error) {
+	keyFile := IdentityFile(cfg.NodeKeyFile, cfg.DataDir)
+	if keyFile == "" {
+		priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 256)
+		return priv, err
+	}
+	passphrase, err := ReadPassphraseFile(cfg.NodeKeyPassphraseFile)
+	if err != nil {
+		return nil, err
+	}
+	return LoadOrCreateIdentity(keyFile, passphrase)
+}
+
+// LoadOrCreateIdentity loads the node's private key from keyFile, creating it on first start.
+// A non-empty passphrase encrypts the key file with the keystore scheme.
+func LoadOrCreateIdentity(keyFile, passphrase string) (crypto.PrivKey, error) {
+	if _, err := os.Stat(keyFile); os.IsNotExist(err) {
+		priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 256)
+		if err != nil {
+			return nil, err
+		}
+		return priv, StoreIdentity(keyFile, priv, passphrase)
+	}
+	return LoadIdentity(keyFile, passphrase)
+}
+
+// LoadIdentity loads the node's private key from keyFile.
+// Encrypted key files are decrypted with the passphrase.
+func LoadIdentity(keyFile, passphrase string) (crypto.PrivKey, error) {
+	data, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	// Encrypted identities are stored as keystore JSON files
+	if bytes.HasPrefix(data, []byte("{")) {
+		if passphrase == "" {
+			return nil, ErrIdentityEncrypted
+		}
+		key, err := keystore.UnmarshalKey(data, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return key.KeyPair.Private, nil
+	}
+	keyBytes, err := hex.DecodeString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("Wrong node identity file %s. Error: %s", keyFile, err)
+	}
+	return crypto.UnmarshalPrivateKey(keyBytes)
+}
+
+// StoreIdentity stores the private key priv to keyFile, encrypted with the passphrase if given
+func StoreIdentity(keyFile string, priv crypto.PrivKey, passphrase string) error {
+	data, err := marshalIdentity(priv, passphrase)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(keyFile, data)
+}
+
+// RotateIdentity replaces the identity of keyFile with a newly generated one.
+// The old key file is copied to a backup next to it and its path is returned.
+// The key file holds either the old or the new key at any point, even if the node crashes.
+func RotateIdentity(keyFile, passphrase string) (crypto.PrivKey, string, error) {
+	// Make sure the passphrase is the right one before replacing the key
+	if _, err := LoadIdentity(keyFile, passphrase); err != nil {
+		return nil, "", err
+	}
+	old, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, "", err
+	}
+	backup := fmt.Sprintf("%s.%d.bak", keyFile, time.Now().Unix())
+	if err := writeFileAtomic(backup, old); err != nil {
+		return nil, "", err
+	}
+	priv, _, err := crypto.GenerateKeyPair(crypto.Secp256k1, 256)
+	if err != nil {
+		return nil, "", err
+	}
+	return priv, backup, StoreIdentity(keyFile, priv, passphrase)
+}
+
+// writeFileAtomic writes data to a temporary file only the owner can read, syncs it to the disk
+// and renames it to file, so that file is never left partly written
+func writeFileAtomic(file string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// marshalIdentity marshals the private key priv as hex, or as a keystore JSON if encrypted with a passphrase
+func marshalIdentity(priv crypto.PrivKey, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		keyBytes, err := crypto.MarshalPrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(hex.EncodeToString(keyBytes)), nil
+	}
+	pubBytes, err := priv.GetPublic().Bytes()
+	if err != nil {
+		return nil, err
+	}
+	key := &keystore.Key{
+		ID:      uuid.NewRandom(),
+		KeyPair: &cccrypto.KeyPair{Private: priv, Address: cccrypto.PublicToAddress(pubBytes)},
+	}
+	return key.MarshalJSON(passphrase)
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestIdentityPersists checks that the identity created on the first start is loaded on the next ones
+func TestIdentityPersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := IdentityFile("", dir)
+	assert.Equal(t, filepath.Join(dir, identityFileName), keyFile)
+
+	priv, err := LoadOrCreateIdentity(keyFile, "")
+	assert.Nil(t, err)
+	loaded, err := LoadOrCreateIdentity(keyFile, "")
+	assert.Nil(t, err)
+	assert.True(t, priv.Equals(loaded))
+}
+
+// TestEncryptedIdentity checks that an encrypted identity can only be loaded with its passphrase
+func TestEncryptedIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, identityFileName)
+
+	priv, err := LoadOrCreateIdentity(keyFile, "passphrase")
+	assert.Nil(t, err)
+	_, err = LoadIdentity(keyFile, "")
+	assert.Equal(t, ErrIdentityEncrypted, err)
+	loaded, err := LoadIdentity(keyFile, "passphrase")
+	assert.Nil(t, err)
+	assert.True(t, priv.Equals(loaded))
+
+	rotated, backup, err := RotateIdentity(keyFile, "passphrase")
+	assert.Nil(t, err)
+	assert.False(t, priv.Equals(rotated))
+	old, err := LoadIdentity(backup, "passphrase")
+	assert.Nil(t, err)
+	assert.True(t, priv.Equals(old))
+	loaded, err = LoadIdentity(keyFile, "passphrase")
+	assert.Nil(t, err)
+	assert.True(t, rotated.Equals(loaded))
+
+	// Only the key file and its backup are left, readable by the owner only
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 2)
+	for _, file := range files {
+		assert.Equal(t, os.FileMode(0600), file.Mode().Perm())
+	}
+}
@@ -17,6 +17,8 @@
 package p2p
 
 import (
+	"context"
+
 	"github.com/crowdcompute/crowdengine/log"
 
 	"github.com/crowdcompute/crowdengine/common/dockerutil"
@@ -28,57 +30,102 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-const inspectContainerRequest = "/image/inspectreq/0.0.1"
-const inspectContainerResponse = "/image/inspectresp/0.0.1"
+const inspectContainerRequest = "/image/inspectreq"
+const inspectContainerResponse = "/image/inspectresp"
 
 // InspectContainerProtocol type
 type InspectContainerProtocol struct {
-	p2pHost     host.Host // local host
-	stream      inet.Stream
-	InspectChan chan string
+	p2pHost    host.Host // local host
+	stream     inet.Stream
+	pending    *pendingRequests // Inspect requests waiting for a response
+	replay     *ReplayGuard     // Rejects stale and replayed messages
+	reputation *Reputation      // Records the outcomes of the interactions with peers
+	limiter    *Limiter         // Limits the work each peer can make the node do
 }
 
 // NewInspectContainerProtocol sets the protocol's stream handlers and returns a new InspectContainerProtocol
-func NewInspectContainerProtocol(p2pHost host.Host) *InspectContainerProtocol {
-	p := &InspectContainerProtocol{p2pHost: p2pHost, InspectChan: make(chan string, 1)}
-	p2pHost.SetStreamHandler(inspectContainerRequest, p.onInspectRequest)
-	p2pHost.SetStreamHandler(inspectContainerResponse, p.onInspectResponse)
+func NewInspectContainerProtocol(p2pHost host.Host, replay *ReplayGuard, reputation *Reputation, limiter *Limiter) *InspectContainerProtocol {
+	p := &InspectContainerProtocol{p2pHost: p2pHost, pending: newPendingRequests(reputation), replay: replay, reputation: reputation}
+	setStreamHandler(p2pHost, inspectContainerRequest, p.onInspectRequest)
+	setStreamHandler(p2pHost, inspectContainerResponse, p.onInspectResponse)
 	return p
 }
 
-// InitiateInspectRequest sends an inspect request to toHostID for the containerID
-func (p *InspectContainerProtocol) InitiateInspectRequest(toHostID peer.ID, containerID string) {
+// InspectFuture is the pending response of an inspect container request
+type InspectFuture struct {
+	*Future
+}
+
+// Wait waits for the raw inspection of the container
+func (f *InspectFuture) Wait(ctx context.Context) (string, error) {
+	resp, err := f.Future.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	inspectResp, ok := resp.(*api.InspectContResponse)
+	if !ok {
+		return "", ErrUnexpectedResponse
+	}
+	return inspectResp.Inspection, nil
+}
+
+// InitiateInspectRequest sends an inspect request to toHostID for the containerID.
+// The inspection arrives to the returned future.
+func (p *InspectContainerProtocol) InitiateInspectRequest(toHostID peer.ID, containerID string) (*InspectFuture, error) {
 	req := &api.InspectContRequest{InspectContMsgData: NewInspectContMsgData(uuid.Must(uuid.NewV4(), nil).String(), true, p.p2pHost),
 		ContainerID: containerID}
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
 	req.InspectContMsgData.MessageData.Sign = signProtoMsg(req, key)
 
-	sendMsg(p.p2pHost, toHostID, req, protocol.ID(inspectContainerRequest))
+	future := p.pending.add(req.InspectContMsgData.MessageData.Id, toHostID)
+	if !sendMsg(p.p2pHost, toHostID, req, protocol.ID(inspectContainerRequest)) {
+		future.Cancel()
+		return nil, ErrRequestNotSent
+	}
+	return &InspectFuture{future}, nil
 }
 
 func (p *InspectContainerProtocol) onInspectRequest(s inet.Stream) {
 	log.Println("Received inspect container request...")
+	// Requests over the limits are rejected without being handled, so that the requester can retry them later
 	data := &api.InspectContRequest{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), inspectContainerRequest)
+	if err != nil {
+		log.Println("Rejecting inspect container request. Error: ", err)
+		if p.limiter.Decode(s.Conn().RemotePeer(), data, s) == nil && data.InspectContMsgData != nil {
+			p.createSendResponse(s.Conn().RemotePeer(), data.InspectContMsgData.MessageData.Id, "", newProtoError(api.ErrorCode_ResourceExhausted, true, err))
+		}
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.InspectContMsgData == nil {
+		log.Println("Failed to decode inspect container request")
+		return
+	}
+	requestID := data.InspectContMsgData.MessageData.Id
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.InspectContMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.InspectContMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Unauthenticated, false, ErrNotAuthenticated))
 		return
 	}
 	rawInspection, err := dockerutil.InspectContainerRaw(data.ContainerID)
 	if err != nil {
 		log.Println("Could not inspect container. Error : ", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_NotFound, false, err))
 		return
 	}
 
-	p.createSendResponse(s.Conn().RemotePeer(), string(rawInspection))
+	p.createSendResponse(s.Conn().RemotePeer(), requestID, string(rawInspection), nil)
 }
 
-// Create and send a response to the toPeer node
-func (p *InspectContainerProtocol) createSendResponse(toPeer peer.ID, response string) bool {
+// Create and send a response to the request requestID of the toPeer node
+// respErr is set if the request failed
+func (p *InspectContainerProtocol) createSendResponse(toPeer peer.ID, requestID string, response string, respErr *api.Error) bool {
 	// Sending the response back to the sender of the msg
-	resp := &api.InspectContResponse{InspectContMsgData: NewInspectContMsgData(uuid.Must(uuid.NewV4(), nil).String(), false, p.p2pHost),
-		Inspection: response}
+	resp := &api.InspectContResponse{InspectContMsgData: NewInspectContMsgData(requestID, false, p.p2pHost),
+		Inspection: response,
+		Error:      respErr}
 
 	// sign the data
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
@@ -90,14 +137,26 @@ func (p *InspectContainerProtocol) createSendResponse(toPeer peer.ID, response s
 
 func (p *InspectContainerProtocol) onInspectResponse(s inet.Stream) {
 	data := &api.InspectContResponse{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), inspectContainerResponse)
+	if err != nil {
+		log.Println("Dropping inspect response. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.InspectContMsgData == nil {
+		log.Println("Failed to decode inspect response")
+		p.reputation.Record(s.Conn().RemotePeer(), InvalidResponse)
+		return
+	}
 
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.InspectContMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.InspectContMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
 
 	log.Printf("%s: Received inspect response from %s. Message id:%s.", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.InspectContMsgData.MessageData.Id)
-	p.InspectChan <- data.Inspection
+	if !p.pending.resolve(data.InspectContMsgData.MessageData.Id, s.Conn().RemotePeer(), data) {
+		log.Println("No pending inspect request for this response. Dropping it...")
+	}
 }
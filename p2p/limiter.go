@@ -0,0 +1,208 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+
+	"github.com/gogo/protobuf/proto"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+)
+
+const (
+	// defaultRequestRate is the messages per second a peer can send to a protocol if not configured
+	defaultRequestRate = 10
+	// defaultRequestBurst is the messages a peer can send at once to a protocol if not configured
+	defaultRequestBurst = 20
+	// defaultMaxConcurrent is the messages of a peer a protocol handles at the same time if not configured
+	defaultMaxConcurrent = 8
+	// defaultMaxMessageSize is the maximum size of a message in bytes if not configured
+	defaultMaxMessageSize = 4 << 20
+	// defaultMaxUploadSize is the maximum size of an uploaded image in MB if not configured
+	defaultMaxUploadSize = 4096
+	// maxLimitedPeers is the number of limited peers after which the idle ones are forgotten
+	maxLimitedPeers = 4096
+)
+
+var (
+	// ErrRateLimited is returned when a peer sends messages faster than the request rate
+	ErrRateLimited = errors.New("The peer exceeded the request rate")
+	// ErrTooManyConcurrent is returned when a peer has too many messages handled at the same time
+	ErrTooManyConcurrent = errors.New("The peer has too many requests in progress")
+	// ErrMessageTooLarge is returned when a message is over the maximum message size
+	ErrMessageTooLarge = errors.New("The message is over the maximum message size")
+	// ErrUploadTooLarge is returned when an upload is over the maximum upload size
+	ErrUploadTooLarge = errors.New("The upload is over the maximum upload size")
+)
+
+// Limiter bounds the work each peer can make the node do through each protocol.
+// Every peer gets a token bucket refilling at the request rate and a limit of messages handled at the same time.
+// A peer exceeding a limit gets penalized in the Reputation. It is shared by all the protocols of a node.
+type Limiter struct {
+	rate           float64
+	burst          float64
+	maxConcurrent  int
+	maxMessageSize int
+	maxUploadSize  int64
+	reputation     *Reputation
+	peers          map[limitKey]*peerLimit
+	mu             sync.Mutex
+}
+
+// limitKey identifies the limits of a peer for a protocol
+type limitKey struct {
+	peer     peer.ID
+	protocol protocol.ID
+}
+
+// peerLimit holds the state of the limits of a peer for a protocol
+type peerLimit struct {
+	tokens float64   // Messages the peer can send right now
+	last   time.Time // When the tokens were last refilled
+	active int       // Messages being handled
+}
+
+// NewLimiter returns a new Limiter with the limits cfg. Zero values mean the defaults.
+// The peers exceeding the limits are penalized in reputation.
+func NewLimiter(cfg *config.Limits, reputation *Reputation) *Limiter {
+	l := &Limiter{
+		rate:           cfg.RequestRate,
+		burst:          float64(cfg.RequestBurst),
+		maxConcurrent:  cfg.MaxConcurrent,
+		maxMessageSize: cfg.MaxMessageSize,
+		maxUploadSize:  int64(cfg.MaxUploadSize) << 20,
+		reputation:     reputation,
+		peers:          make(map[limitKey]*peerLimit),
+	}
+	if l.rate <= 0 {
+		l.rate = defaultRequestRate
+	}
+	if l.burst <= 0 {
+		l.burst = defaultRequestBurst
+	}
+	if l.maxConcurrent <= 0 {
+		l.maxConcurrent = defaultMaxConcurrent
+	}
+	if l.maxMessageSize <= 0 {
+		l.maxMessageSize = defaultMaxMessageSize
+	}
+	if l.maxUploadSize <= 0 {
+		l.maxUploadSize = defaultMaxUploadSize << 20
+	}
+	return l
+}
+
+// Acquire admits a message of the peer pID to the protocol proto.
+// The returned release function must be called once the message is handled.
+func (l *Limiter) Acquire(pID peer.ID, proto protocol.ID) (func(), error) {
+	return l.acquire(pID, proto, time.Now())
+}
+
+// acquire admits a message of the peer pID to the protocol proto at the time now
+func (l *Limiter) acquire(pID peer.ID, proto protocol.ID, now time.Time) (func(), error) {
+	key := limitKey{peer: pID, protocol: proto}
+	l.mu.Lock()
+	limit, ok := l.peers[key]
+	if !ok {
+		l.forgetIdlePeers(now)
+		limit = &peerLimit{tokens: l.burst, last: now}
+		l.peers[key] = limit
+	}
+	limit.refill(now, l.rate, l.burst)
+	var err error
+	switch {
+	case limit.active >= l.maxConcurrent:
+		err = ErrTooManyConcurrent
+	case limit.tokens < 1:
+		err = ErrRateLimited
+	default:
+		limit.tokens--
+		limit.active++
+	}
+	l.mu.Unlock()
+
+	if err != nil {
+		l.reputation.Record(pID, LimitExceeded)
+		return func() {}, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			limit.active--
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// Decode decodes a message of the peer pID from the reader r, rejecting it if it's over the maximum message size
+func (l *Limiter) Decode(pID peer.ID, message proto.Message, r io.Reader) error {
+	err := decodeProtoMessage(message, r, l.maxMessageSize)
+	if err == ErrMessageTooLarge {
+		l.reputation.Record(pID, LimitExceeded)
+	}
+	return err
+}
+
+// Unmarshal decodes a message of the peer pID from data, rejecting it if it's over the maximum message size
+func (l *Limiter) Unmarshal(pID peer.ID, message proto.Message, data []byte) error {
+	if len(data) > l.maxMessageSize {
+		l.reputation.Record(pID, LimitExceeded)
+		return ErrMessageTooLarge
+	}
+	return proto.Unmarshal(data, message)
+}
+
+// CheckUpload checks that an upload of the peer pID of size bytes isn't over the maximum upload size
+func (l *Limiter) CheckUpload(pID peer.ID, size int64) error {
+	if size > l.maxUploadSize {
+		l.reputation.Record(pID, LimitExceeded)
+		return ErrUploadTooLarge
+	}
+	return nil
+}
+
+// forgetIdlePeers drops the limits of the peers that have no messages in progress
+// and a full bucket, as they are the same as new ones. It must be called with the mutex held.
+func (l *Limiter) forgetIdlePeers(now time.Time) {
+	if len(l.peers) < maxLimitedPeers {
+		return
+	}
+	for key, limit := range l.peers {
+		limit.refill(now, l.rate, l.burst)
+		if limit.active == 0 && limit.tokens >= l.burst {
+			delete(l.peers, key)
+		}
+	}
+}
+
+// refill adds the tokens earned since the last refill at rate per second, up to burst
+func (p *peerLimit) refill(now time.Time, rate, burst float64) {
+	if elapsed := now.Sub(p.last).Seconds(); elapsed > 0 {
+		p.tokens += elapsed * rate
+		p.last = now
+	}
+	if p.tokens > burst {
+		p.tokens = burst
+	}
+}
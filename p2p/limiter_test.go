@@ -0,0 +1,98 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	protobufCodec "github.com/multiformats/go-multicodec/protobuf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterRate(t *testing.T) {
+	rep := NewReputation(false)
+	l := NewLimiter(&config.Limits{RequestRate: 2, RequestBurst: 3}, rep)
+	spammer, now := peer.ID("spammer"), time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.acquire(spammer, runRequest, now)
+		assert.NoError(t, err)
+		release()
+	}
+	_, err := l.acquire(spammer, runRequest, now)
+	assert.Equal(t, ErrRateLimited, err)
+	assert.Equal(t, uint64(1), rep.Peer(spammer).Record.LimitViolations)
+
+	// Every protocol has its own budget
+	_, err = l.acquire(spammer, inspectContainerRequest, now)
+	assert.NoError(t, err)
+
+	// The bucket refills at the request rate
+	_, err = l.acquire(spammer, runRequest, now.Add(500*time.Millisecond))
+	assert.NoError(t, err)
+	_, err = l.acquire(spammer, runRequest, now.Add(500*time.Millisecond))
+	assert.Equal(t, ErrRateLimited, err)
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := NewLimiter(&config.Limits{RequestRate: 100, RequestBurst: 100, MaxConcurrent: 2}, nil)
+	pID, now := peer.ID("peer"), time.Now()
+	release1, err := l.acquire(pID, imageUploadRequest, now)
+	assert.NoError(t, err)
+	_, err = l.acquire(pID, imageUploadRequest, now)
+	assert.NoError(t, err)
+	_, err = l.acquire(pID, imageUploadRequest, now)
+	assert.Equal(t, ErrTooManyConcurrent, err)
+
+	// Releasing twice frees a single slot
+	release1()
+	release1()
+	_, err = l.acquire(pID, imageUploadRequest, now)
+	assert.NoError(t, err)
+	_, err = l.acquire(pID, imageUploadRequest, now)
+	assert.Equal(t, ErrTooManyConcurrent, err)
+}
+
+func TestLimiterMessageSize(t *testing.T) {
+	rep := NewReputation(false)
+	l := NewLimiter(&config.Limits{MaxMessageSize: 64}, rep)
+	pID := peer.ID("peer")
+	encode := func(msg *api.RunRequest) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, protobufCodec.Multicodec(nil).Encoder(buf).Encode(msg))
+		return buf
+	}
+
+	data := &api.RunRequest{}
+	assert.NoError(t, l.Decode(pID, data, encode(&api.RunRequest{ImageID: "small"})))
+	assert.Equal(t, "small", data.ImageID)
+
+	large := encode(&api.RunRequest{ImageID: string(make([]byte, 100))})
+	assert.Equal(t, ErrMessageTooLarge, l.Decode(pID, &api.RunRequest{}, large))
+	assert.Equal(t, uint64(1), rep.Peer(pID).Record.LimitViolations)
+}
+
+func TestLimiterUploadSize(t *testing.T) {
+	l := NewLimiter(&config.Limits{MaxUploadSize: 1}, nil)
+	assert.NoError(t, l.CheckUpload(peer.ID("peer"), 1<<20))
+	assert.Equal(t, ErrUploadTooLarge, l.CheckUpload(peer.ID("peer"), 1<<20+1))
+}
@@ -17,9 +17,11 @@
 package p2p
 
 import (
+	"context"
+
+	"github.com/crowdcompute/crowdengine/common/dockerutil"
 	"github.com/crowdcompute/crowdengine/log"
 	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
-	"github.com/crowdcompute/crowdengine/common/dockerutil"
 
 	host "github.com/libp2p/go-libp2p-host"
 	inet "github.com/libp2p/go-libp2p-net"
@@ -28,58 +30,107 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-const containersListRequest = "/container/ListContainersReq/0.0.1"
-const containersListResponse = "/container/ListContainersResp/0.0.1"
+const containersListRequest = "/container/ListContainersReq"
+const containersListResponse = "/container/ListContainersResp"
 
 // ListContainersProtocol type
 type ListContainersProtocol struct {
-	p2pHost      host.Host // local host
-	stream       inet.Stream
-	ListContChan chan string
+	p2pHost    host.Host // local host
+	stream     inet.Stream
+	pending    *pendingRequests // List requests waiting for a response
+	replay     *ReplayGuard     // Rejects stale and replayed messages
+	reputation *Reputation      // Records the outcomes of the interactions with peers
+	limiter    *Limiter         // Limits the work each peer can make the node do
 }
 
 // NewListContainersProtocol sets the protocol's stream handlers and returns a new ListContainersProtocol
-func NewListContainersProtocol(p2pHost host.Host) *ListContainersProtocol {
+func NewListContainersProtocol(p2pHost host.Host, replay *ReplayGuard, reputation *Reputation, limiter *Limiter) *ListContainersProtocol {
 	p := &ListContainersProtocol{
-		p2pHost:      p2pHost,
-		ListContChan: make(chan string, 1),
+		p2pHost:    p2pHost,
+		pending:    newPendingRequests(reputation),
+		replay:     replay,
+		reputation: reputation,
+		limiter:    limiter,
 	}
-	p2pHost.SetStreamHandler(containersListRequest, p.onListRequest)
-	p2pHost.SetStreamHandler(containersListResponse, p.onListResponse)
+	setStreamHandler(p2pHost, containersListRequest, p.onListRequest)
+	setStreamHandler(p2pHost, containersListResponse, p.onListResponse)
 	return p
 }
 
-// InitiateListContRequest sends a list images request to toHostID using the pubKey of the user who initiated it
-func (p *ListContainersProtocol) InitiateListContRequest(toHostID peer.ID, pubKey string) {
+// ListContainersFuture is the pending response of a list containers request
+type ListContainersFuture struct {
+	*Future
+}
+
+// Wait waits for the raw list of containers
+func (f *ListContainersFuture) Wait(ctx context.Context) (string, error) {
+	resp, err := f.Future.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	listResp, ok := resp.(*api.ListContainersResponse)
+	if !ok {
+		return "", ErrUnexpectedResponse
+	}
+	return listResp.ListResult, nil
+}
+
+// InitiateListContRequest sends a list containers request to toHostID using the pubKey of the user who initiated it.
+// The list arrives to the returned future.
+func (p *ListContainersProtocol) InitiateListContRequest(toHostID peer.ID, pubKey string) (*ListContainersFuture, error) {
 	req := &api.ListContainersRequest{ListContainersMsgData: NewListContainersMsgData(uuid.Must(uuid.NewV4(), nil).String(), true, p.p2pHost),
 		PubKey: pubKey}
 	p2pPrivKey := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
 	req.ListContainersMsgData.MessageData.Sign = signProtoMsg(req, p2pPrivKey)
-	sendMsg(p.p2pHost, toHostID, req, protocol.ID(containersListRequest))
+
+	future := p.pending.add(req.ListContainersMsgData.MessageData.Id, toHostID)
+	if !sendMsg(p.p2pHost, toHostID, req, protocol.ID(containersListRequest)) {
+		future.Cancel()
+		return nil, ErrRequestNotSent
+	}
+	return &ListContainersFuture{future}, nil
 }
 
 func (p *ListContainersProtocol) onListRequest(s inet.Stream) {
+	// Requests over the limits are rejected without being handled, so that the requester can retry them later
 	data := &api.ListContainersRequest{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), containersListRequest)
+	if err != nil {
+		log.Println("Rejecting list containers request. Error: ", err)
+		if p.limiter.Decode(s.Conn().RemotePeer(), data, s) == nil && data.ListContainersMsgData != nil {
+			p.createSendResponse(s.Conn().RemotePeer(), data.ListContainersMsgData.MessageData.Id, "", newProtoError(api.ErrorCode_ResourceExhausted, true, err))
+		}
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.ListContainersMsgData == nil {
+		log.Println("Failed to decode list containers request")
+		return
+	}
+	requestID := data.ListContainersMsgData.MessageData.Id
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.ListContainersMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.ListContainersMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Unauthenticated, false, ErrNotAuthenticated))
 		return
 	}
 
 	containersRaw, err := dockerutil.GetRawContainersForUser(data.PubKey)
 	if err != nil {
 		log.Println("Could not List containers. Error : ", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Internal, true, err))
 		return
 	}
-	p.createSendResponse(s.Conn().RemotePeer(), containersRaw)
+	p.createSendResponse(s.Conn().RemotePeer(), requestID, containersRaw, nil)
 }
 
-// Create and send a response to the toPeer note
-func (p *ListContainersProtocol) createSendResponse(toPeer peer.ID, response string) bool {
+// Create and send a response to the request requestID of the toPeer node
+// respErr is set if the request failed
+func (p *ListContainersProtocol) createSendResponse(toPeer peer.ID, requestID string, response string, respErr *api.Error) bool {
 	// Sending the response back to the sender of the msg
-	resp := &api.ListContainersResponse{ListContainersMsgData: NewListContainersMsgData(uuid.Must(uuid.NewV4(), nil).String(), false, p.p2pHost),
-		ListResult: response}
+	resp := &api.ListContainersResponse{ListContainersMsgData: NewListContainersMsgData(requestID, false, p.p2pHost),
+		ListResult: response,
+		Error:      respErr}
 
 	// sign the data
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
@@ -91,13 +142,25 @@ func (p *ListContainersProtocol) createSendResponse(toPeer peer.ID, response str
 
 func (p *ListContainersProtocol) onListResponse(s inet.Stream) {
 	data := &api.ListContainersResponse{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), containersListResponse)
+	if err != nil {
+		log.Println("Dropping list containers response. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.ListContainersMsgData == nil {
+		log.Println("Failed to decode list containers response")
+		p.reputation.Record(s.Conn().RemotePeer(), InvalidResponse)
+		return
+	}
 
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.ListContainersMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.ListContainersMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
 	log.Printf("%s: Received List response from %s. Message id:%s.", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.ListContainersMsgData.MessageData.Id)
-	p.ListContChan <- data.ListResult
+	if !p.pending.resolve(data.ListContainersMsgData.MessageData.Id, s.Conn().RemotePeer(), data) {
+		log.Println("No pending list containers request for this response. Dropping it...")
+	}
 }
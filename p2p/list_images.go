@@ -17,9 +17,11 @@
 package p2p
 
 import (
+	"context"
+
+	"github.com/crowdcompute/crowdengine/common/dockerutil"
 	"github.com/crowdcompute/crowdengine/log"
 	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
-	"github.com/crowdcompute/crowdengine/common/dockerutil"
 
 	host "github.com/libp2p/go-libp2p-host"
 	inet "github.com/libp2p/go-libp2p-net"
@@ -28,58 +30,107 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-const imageListRequest = "/image/ListImgReq/0.0.1"
-const imageListResponse = "/image/ListImgResp/0.0.1"
+const imageListRequest = "/image/ListImgReq"
+const imageListResponse = "/image/ListImgResp"
 
 // ListImagesProtocol type
 type ListImagesProtocol struct {
-	p2pHost  host.Host // local host
-	stream   inet.Stream
-	ListImgChan chan string
+	p2pHost    host.Host // local host
+	stream     inet.Stream
+	pending    *pendingRequests // List requests waiting for a response
+	replay     *ReplayGuard     // Rejects stale and replayed messages
+	reputation *Reputation      // Records the outcomes of the interactions with peers
+	limiter    *Limiter         // Limits the work each peer can make the node do
 }
 
 // NewListImagesProtocol sets the protocol's stream handlers and returns a new ListImagesProtocol
-func NewListImagesProtocol(p2pHost host.Host) *ListImagesProtocol {
+func NewListImagesProtocol(p2pHost host.Host, replay *ReplayGuard, reputation *Reputation, limiter *Limiter) *ListImagesProtocol {
 	p := &ListImagesProtocol{
-		p2pHost:  p2pHost,
-		ListImgChan: make(chan string, 1),
+		p2pHost:    p2pHost,
+		pending:    newPendingRequests(reputation),
+		replay:     replay,
+		reputation: reputation,
+		limiter:    limiter,
 	}
-	p2pHost.SetStreamHandler(imageListRequest, p.onListRequest)
-	p2pHost.SetStreamHandler(imageListResponse, p.onListResponse)
+	setStreamHandler(p2pHost, imageListRequest, p.onListRequest)
+	setStreamHandler(p2pHost, imageListResponse, p.onListResponse)
 	return p
 }
 
-// InitiateListImgRequest sends a list images request to toHostID using the pubKey of the user who initiated it
-func (p *ListImagesProtocol) InitiateListImgRequest(toHostID peer.ID, pubKey string) {
+// ListImagesFuture is the pending response of a list images request
+type ListImagesFuture struct {
+	*Future
+}
+
+// Wait waits for the raw list of images
+func (f *ListImagesFuture) Wait(ctx context.Context) (string, error) {
+	resp, err := f.Future.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	listResp, ok := resp.(*api.ListImagesResponse)
+	if !ok {
+		return "", ErrUnexpectedResponse
+	}
+	return listResp.ListResult, nil
+}
+
+// InitiateListImgRequest sends a list images request to toHostID using the pubKey of the user who initiated it.
+// The list arrives to the returned future.
+func (p *ListImagesProtocol) InitiateListImgRequest(toHostID peer.ID, pubKey string) (*ListImagesFuture, error) {
 	req := &api.ListImagesRequest{ListImagesMsgData: NewListImagesMsgData(uuid.Must(uuid.NewV4(), nil).String(), true, p.p2pHost),
 		PubKey: pubKey}
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
 	req.ListImagesMsgData.MessageData.Sign = signProtoMsg(req, key)
-	sendMsg(p.p2pHost, toHostID, req, protocol.ID(imageListRequest))
+
+	future := p.pending.add(req.ListImagesMsgData.MessageData.Id, toHostID)
+	if !sendMsg(p.p2pHost, toHostID, req, protocol.ID(imageListRequest)) {
+		future.Cancel()
+		return nil, ErrRequestNotSent
+	}
+	return &ListImagesFuture{future}, nil
 }
 
 func (p *ListImagesProtocol) onListRequest(s inet.Stream) {
+	// Requests over the limits are rejected without being handled, so that the requester can retry them later
 	data := &api.ListImagesRequest{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), imageListRequest)
+	if err != nil {
+		log.Println("Rejecting list images request. Error: ", err)
+		if p.limiter.Decode(s.Conn().RemotePeer(), data, s) == nil && data.ListImagesMsgData != nil {
+			p.createSendResponse(s.Conn().RemotePeer(), data.ListImagesMsgData.MessageData.Id, "", newProtoError(api.ErrorCode_ResourceExhausted, true, err))
+		}
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.ListImagesMsgData == nil {
+		log.Println("Failed to decode list images request")
+		return
+	}
+	requestID := data.ListImagesMsgData.MessageData.Id
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.ListImagesMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.ListImagesMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Unauthenticated, false, ErrNotAuthenticated))
 		return
 	}
 
 	imgSummariesRaw, err := dockerutil.GetRawImagesForUser(data.PubKey)
 	if err != nil {
 		log.Println("Could not List images. Error : ", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Internal, true, err))
 		return
 	}
-	p.createSendResponse(s.Conn().RemotePeer(), imgSummariesRaw)
+	p.createSendResponse(s.Conn().RemotePeer(), requestID, imgSummariesRaw, nil)
 }
 
-// Create and send a response to the toPeer note
-func (p *ListImagesProtocol) createSendResponse(toPeer peer.ID, response string) bool {
+// Create and send a response to the request requestID of the toPeer node
+// respErr is set if the request failed
+func (p *ListImagesProtocol) createSendResponse(toPeer peer.ID, requestID string, response string, respErr *api.Error) bool {
 	// Sending the response back to the sender of the msg
-	resp := &api.ListImagesResponse{ListImagesMsgData: NewListImagesMsgData(uuid.Must(uuid.NewV4(), nil).String(), false, p.p2pHost),
-		ListResult: response}
+	resp := &api.ListImagesResponse{ListImagesMsgData: NewListImagesMsgData(requestID, false, p.p2pHost),
+		ListResult: response,
+		Error:      respErr}
 
 	// sign the data
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
@@ -91,13 +142,25 @@ func (p *ListImagesProtocol) createSendResponse(toPeer peer.ID, response string)
 
 func (p *ListImagesProtocol) onListResponse(s inet.Stream) {
 	data := &api.ListImagesResponse{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), imageListResponse)
+	if err != nil {
+		log.Println("Dropping list images response. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.ListImagesMsgData == nil {
+		log.Println("Failed to decode list images response")
+		p.reputation.Record(s.Conn().RemotePeer(), InvalidResponse)
+		return
+	}
 
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.ListImagesMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.ListImagesMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
 	log.Printf("%s: Received List response from %s. Message id:%s.", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.ListImagesMsgData.MessageData.Id)
-	p.ListImgChan <- data.ListResult
+	if !p.pending.resolve(data.ListImagesMsgData.MessageData.Id, s.Conn().RemotePeer(), data) {
+		log.Println("No pending list images request for this response. Dropping it...")
+	}
 }
@@ -0,0 +1,95 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	host "github.com/libp2p/go-libp2p-host"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	"github.com/libp2p/go-libp2p/p2p/discovery"
+)
+
+// mdnsQueryInterval is how often the node looks for other nodes on the local network
+const mdnsQueryInterval = 10 * time.Second
+
+// networkNamePattern is the network names that fit in a DNS label along with the mdnsServiceTag prefix
+var networkNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,55}[A-Za-z0-9])?$`)
+
+// MDNSService finds the gocc nodes of the same network on the local network through multicast DNS
+// and announces the node to them. The nodes of a network announce themselves under its own service tag,
+// so the nodes of other networks don't find them.
+type MDNSService struct {
+	service discovery.Service
+	self    peer.ID
+	cancel  context.CancelFunc
+	// found is called for each node of the same network that answers
+	found func(ps.PeerInfo)
+}
+
+// NewMDNSService creates an MDNSService for the p2pHost on the network, which calls found for every discovered node
+func NewMDNSService(p2pHost host.Host, network string, found func(ps.PeerInfo)) (*MDNSService, error) {
+	if err := checkNetworkName(network); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	service, err := discovery.NewMdnsService(ctx, p2pHost, mdnsQueryInterval, mdnsServiceTag(network))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	m := &MDNSService{service: service, self: p2pHost.ID(), cancel: cancel, found: found}
+	service.RegisterNotifee(m)
+	return m, nil
+}
+
+// Run keeps discovering the nodes of the local network until quit is closed
+func (m *MDNSService) Run(quit <-chan struct{}) {
+	<-quit
+	m.Close()
+}
+
+// Close stops querying and answering the local network
+func (m *MDNSService) Close() error {
+	m.cancel()
+	return m.service.Close()
+}
+
+// HandlePeerFound is called by the mDNS service for every node of the network it finds
+func (m *MDNSService) HandlePeerFound(pi ps.PeerInfo) {
+	if pi.ID == m.self {
+		return
+	}
+	m.found(pi)
+}
+
+// mdnsServiceTag returns the DNS-SD service the nodes of the network announce themselves under
+func mdnsServiceTag(network string) string {
+	return "_gocc-" + network + "._udp"
+}
+
+// checkNetworkName checks that the network name can be a part of a DNS-SD service name
+func checkNetworkName(network string) error {
+	if !networkNamePattern.MatchString(network) {
+		return fmt.Errorf("Invalid network name %q. It can only have letters, digits and hyphens", network)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"strings"
+	"testing"
+
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkName(t *testing.T) {
+	assert.NoError(t, checkNetworkName("crowdcompute"))
+	assert.NoError(t, checkNetworkName("ci-rack-2"))
+	assert.NoError(t, checkNetworkName(strings.Repeat("a", 57)))
+	assert.Error(t, checkNetworkName(""))
+	assert.Error(t, checkNetworkName("-lab"))
+	assert.Error(t, checkNetworkName("lab.local"))
+	assert.Error(t, checkNetworkName("lab_1"))
+	// The service tag label can't be longer than 63 bytes
+	assert.Error(t, checkNetworkName(strings.Repeat("a", 58)))
+	assert.Equal(t, "_gocc-lab._udp", mdnsServiceTag("lab"))
+}
+
+func TestMDNSFoundPeers(t *testing.T) {
+	found := make([]ps.PeerInfo, 0)
+	m := &MDNSService{self: commonTestHost1.P2PHost.ID(), found: func(pi ps.PeerInfo) { found = append(found, pi) }}
+	m.HandlePeerFound(ps.PeerInfo{ID: commonTestHost1.P2PHost.ID()})
+	m.HandlePeerFound(ps.PeerInfo{ID: commonTestHost2.P2PHost.ID()})
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, commonTestHost2.P2PHost.ID(), found[0].ID)
+	}
+}
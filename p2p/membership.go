@@ -0,0 +1,254 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/log"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/gogo/protobuf/proto"
+	crypto "github.com/libp2p/go-libp2p-crypto"
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// membershipFileName is the default name of the membership list file inside the data directory
+const membershipFileName = "members"
+
+var (
+	// ErrNotMembershipAdmin is returned when a node other than the admin tries to change the membership list
+	ErrNotMembershipAdmin = errors.New("Only the network's admin can change the membership list")
+	// ErrInvalidMembershipList is returned when the membership list isn't signed by the network's admin
+	ErrInvalidMembershipList = errors.New("The membership list isn't signed by the network's admin")
+	// ErrOldMembershipList is returned when replacing the membership list with an older version
+	ErrOldMembershipList = errors.New("The membership list is older than the current one")
+)
+
+// MembershipFile returns the path of the membership list file.
+// It defaults to the "members" file of the data directory dataDir.
+func MembershipFile(file, dataDir string) string {
+	if file != "" || dataDir == "" {
+		return file
+	}
+	return filepath.Join(dataDir, membershipFileName)
+}
+
+// Membership holds the signed list of the peers allowed to connect to a private network.
+// It closes any connection from a peer that isn't a member, except for the admin.
+type Membership struct {
+	host    host.Host
+	admin   peer.ID
+	path    string
+	list    *api.MembershipList
+	members map[peer.ID]struct{}
+	mu      sync.RWMutex
+}
+
+// NewMembership loads the membership list of the admin from the file path and enforces it on the p2pHost's connections.
+// A missing file means that no peer other than the admin is a member yet. Without a path the list is kept in memory.
+func NewMembership(p2pHost host.Host, path string, admin peer.ID) (*Membership, error) {
+	m := &Membership{host: p2pHost, admin: admin, path: path, list: &api.MembershipList{}, members: make(map[peer.ID]struct{})}
+	list, err := LoadMembershipList(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := VerifyMembershipList(list, admin); err != nil {
+			return nil, err
+		}
+		m.set(list)
+	}
+	p2pHost.Network().Notify((*membershipGater)(m))
+	return m, nil
+}
+
+// Allowed checks if the peer pID may connect to the node
+func (m *Membership) Allowed(pID peer.ID) bool {
+	if pID == m.admin || pID == m.host.ID() {
+		return true
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.members[pID]
+	return ok
+}
+
+// Members returns the peer IDs of the members
+func (m *Membership) Members() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string{}, m.list.Members...)
+}
+
+// List returns the current signed membership list
+func (m *Membership) List() *api.MembershipList {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return proto.Clone(m.list).(*api.MembershipList)
+}
+
+// Update replaces the membership list with a newer one signed by the admin,
+// stores it and disconnects from the peers that aren't members anymore
+func (m *Membership) Update(list *api.MembershipList) error {
+	if err := VerifyMembershipList(list, m.admin); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	if list.Version <= m.list.Version {
+		m.mu.Unlock()
+		return ErrOldMembershipList
+	}
+	if m.path != "" {
+		if err := StoreMembershipList(m.path, list); err != nil {
+			m.mu.Unlock()
+			return err
+		}
+	}
+	m.set(list)
+	m.mu.Unlock()
+
+	for _, pID := range m.host.Network().Peers() {
+		if !m.Allowed(pID) {
+			log.Println("Disconnecting from a peer that isn't a member anymore: ", pID.Pretty())
+			m.host.Network().ClosePeer(pID)
+		}
+	}
+	return nil
+}
+
+// Edit adds and removes members from the list, signing it with the admin's key priv
+func (m *Membership) Edit(priv crypto.PrivKey, add, remove []peer.ID) error {
+	list, err := EditMembershipList(m.List(), priv, m.admin, add, remove)
+	if err != nil {
+		return err
+	}
+	return m.Update(list)
+}
+
+// set replaces the members with the ones of the list. The mutex must be held.
+func (m *Membership) set(list *api.MembershipList) {
+	m.list = list
+	m.members = make(map[peer.ID]struct{}, len(list.Members))
+	for _, member := range list.Members {
+		if pID, err := peer.IDB58Decode(member); err == nil {
+			m.members[pID] = struct{}{}
+		}
+	}
+}
+
+// EditMembershipList returns a copy of the list with the members added and removed, signed by the admin's key priv
+func EditMembershipList(list *api.MembershipList, priv crypto.PrivKey, admin peer.ID, add, remove []peer.ID) (*api.MembershipList, error) {
+	if id, err := peer.IDFromPrivateKey(priv); err != nil || id != admin {
+		return nil, ErrNotMembershipAdmin
+	}
+	members := make(map[string]struct{})
+	for _, member := range list.GetMembers() {
+		members[member] = struct{}{}
+	}
+	for _, pID := range add {
+		members[pID.Pretty()] = struct{}{}
+	}
+	for _, pID := range remove {
+		delete(members, pID.Pretty())
+	}
+	adminPubKey, err := priv.GetPublic().Bytes()
+	if err != nil {
+		return nil, err
+	}
+	edited := &api.MembershipList{
+		Members:     make([]string, 0, len(members)),
+		Version:     time.Now().UnixNano(),
+		AdminPubKey: adminPubKey,
+	}
+	for member := range members {
+		edited.Members = append(edited.Members, member)
+	}
+	sort.Strings(edited.Members)
+	// A list signed within the same nanosecond still has to replace the previous one
+	if edited.Version <= list.GetVersion() {
+		edited.Version = list.GetVersion() + 1
+	}
+	edited.Sign = signProtoMsg(edited, priv)
+	return edited, nil
+}
+
+// VerifyMembershipList checks that the list is signed by the admin
+func VerifyMembershipList(list *api.MembershipList, admin peer.ID) error {
+	sign := list.Sign
+	list.Sign = nil
+	bin, err := proto.Marshal(list)
+	list.Sign = sign
+	if err != nil || !verifyData(bin, sign, admin, list.AdminPubKey) {
+		return ErrInvalidMembershipList
+	}
+	return nil
+}
+
+// LoadMembershipList reads the membership list of the file path
+func LoadMembershipList(path string) (*api.MembershipList, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	list := &api.MembershipList{}
+	if err := proto.Unmarshal(data, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// StoreMembershipList writes the membership list to the file path
+func StoreMembershipList(path string, list *api.MembershipList) error {
+	data, err := proto.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// membershipGater closes the connections and the streams of the peers that aren't members
+type membershipGater Membership
+
+// Connected closes the connection if its peer isn't a member
+func (g *membershipGater) Connected(net inet.Network, conn inet.Conn) {
+	if !(*Membership)(g).Allowed(conn.RemotePeer()) {
+		log.Println("Rejecting the connection of a peer that isn't a member: ", conn.RemotePeer().Pretty())
+		conn.Close()
+	}
+}
+
+// OpenedStream resets the streams of a peer that isn't a member, in case one gets opened before its connection is closed
+func (g *membershipGater) OpenedStream(net inet.Network, s inet.Stream) {
+	if !(*Membership)(g).Allowed(s.Conn().RemotePeer()) {
+		s.Reset()
+	}
+}
+
+func (g *membershipGater) Disconnected(inet.Network, inet.Conn)   {}
+func (g *membershipGater) Listen(inet.Network, ma.Multiaddr)      {}
+func (g *membershipGater) ListenClose(inet.Network, ma.Multiaddr) {}
+func (g *membershipGater) ClosedStream(inet.Network, inet.Stream) {}
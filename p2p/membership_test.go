@@ -0,0 +1,93 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	memberTestAdmin, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10331, ListenAddress: "127.0.0.1"},
+	})
+	memberTestHost1, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10332, ListenAddress: "127.0.0.1", MembershipAdmin: memberTestAdmin.P2PHost.ID().Pretty()},
+	})
+	memberTestHost2, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10333, ListenAddress: "127.0.0.1"},
+	})
+)
+
+func TestEditMembershipList(t *testing.T) {
+	adminKey := memberTestAdmin.P2PHost.Peerstore().PrivKey(memberTestAdmin.P2PHost.ID())
+	admin := memberTestAdmin.P2PHost.ID()
+	member := memberTestHost2.P2PHost.ID()
+
+	list, err := EditMembershipList(&api.MembershipList{}, adminKey, admin, []peer.ID{member}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{member.Pretty()}, list.Members)
+	assert.NoError(t, VerifyMembershipList(list, admin))
+
+	// Only the admin can sign a list
+	otherKey := memberTestHost2.P2PHost.Peerstore().PrivKey(member)
+	_, err = EditMembershipList(list, otherKey, admin, nil, []peer.ID{member})
+	assert.Equal(t, ErrNotMembershipAdmin, err)
+	assert.Equal(t, ErrInvalidMembershipList, VerifyMembershipList(list, member))
+
+	list.Members = append(list.Members, admin.Pretty())
+	assert.Equal(t, ErrInvalidMembershipList, VerifyMembershipList(list, admin))
+}
+
+func TestMembershipGater(t *testing.T) {
+	adminKey := memberTestAdmin.P2PHost.Peerstore().PrivKey(memberTestAdmin.P2PHost.ID())
+	membership := memberTestHost1.Membership
+	nonMember := memberTestHost2.P2PHost
+
+	// The connection of a peer that isn't a member is closed
+	nonMember.Connect(context.Background(), peerInfo(memberTestHost1))
+	assert.False(t, connectedEventually(memberTestHost1, nonMember.ID()))
+
+	// The admin can always connect
+	assert.NoError(t, memberTestAdmin.P2PHost.Connect(context.Background(), peerInfo(memberTestHost1)))
+	assert.True(t, connectedEventually(memberTestHost1, memberTestAdmin.P2PHost.ID()))
+
+	assert.NoError(t, membership.Edit(adminKey, []peer.ID{nonMember.ID()}, nil))
+	assert.True(t, membership.Allowed(nonMember.ID()))
+	assert.NoError(t, nonMember.Connect(context.Background(), peerInfo(memberTestHost1)))
+	assert.True(t, connectedEventually(memberTestHost1, nonMember.ID()))
+
+	// A removed member gets disconnected
+	oldList := membership.List()
+	assert.NoError(t, membership.Edit(adminKey, nil, []peer.ID{nonMember.ID()}))
+	assert.False(t, membership.Allowed(nonMember.ID()))
+	assert.False(t, connectedEventually(memberTestHost1, nonMember.ID()))
+	assert.Equal(t, ErrOldMembershipList, membership.Update(oldList))
+}
+
+// connectedEventually checks whether the host h is connected to the peer pID, after the connection notifications are handled
+func connectedEventually(h *Host, pID peer.ID) bool {
+	time.Sleep(200 * time.Millisecond)
+	return len(h.P2PHost.Network().ConnsToPeer(pID)) > 0
+}
@@ -0,0 +1,83 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+
+	autonat "github.com/libp2p/go-libp2p-autonat"
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	natTestRelay, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10361, ListenAddress: "127.0.0.1", RelayServer: true},
+	})
+	natTestClient, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10362, ListenAddress: "127.0.0.1"},
+	})
+	natTestTarget, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10363, ListenAddress: "127.0.0.1"},
+	})
+)
+
+func TestCircuitRelay(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, natTestClient.P2PHost.Connect(ctx, peerInfo(natTestRelay)))
+	assert.NoError(t, natTestTarget.P2PHost.Connect(ctx, peerInfo(natTestRelay)))
+
+	// The client only knows the target through the relay
+	relayAddr, err := ma.NewMultiaddr(fmt.Sprintf("/ip4/127.0.0.1/tcp/10361/ipfs/%s/p2p-circuit", natTestRelay.P2PHost.ID().Pretty()))
+	assert.NoError(t, err)
+	err = natTestClient.P2PHost.Connect(ctx, ps.PeerInfo{ID: natTestTarget.P2PHost.ID(), Addrs: []ma.Multiaddr{relayAddr}})
+	assert.NoError(t, err)
+	conns := natTestClient.P2PHost.Network().ConnsToPeer(natTestTarget.P2PHost.ID())
+	assert.Len(t, conns, 1)
+	assert.Contains(t, conns[0].RemoteMultiaddr().String(), "p2p-circuit")
+}
+
+func TestAutoNATService(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, natTestClient.P2PHost.Connect(ctx, peerInfo(natTestRelay)))
+	assert.NoError(t, natTestClient.P2PHost.Connect(ctx, peerInfo(natTestTarget)))
+	client := autonat.NewAutoNATClient(natTestClient.P2PHost, nil)
+
+	// The relay server dials the client back at the IP the client connected from
+	addr, err := client.DialBack(ctx, natTestRelay.P2PHost.ID())
+	assert.NoError(t, err)
+	conns := natTestClient.P2PHost.Network().ConnsToPeer(natTestRelay.P2PHost.ID())
+	if assert.NotNil(t, addr) && assert.NotEmpty(t, conns) {
+		assert.Equal(t, addrIP(conns[0].LocalMultiaddr()), addrIP(addr))
+	}
+
+	// Other nodes don't answer AutoNAT requests
+	_, err = client.DialBack(ctx, natTestTarget.P2PHost.ID())
+	assert.Error(t, err)
+
+	// The addresses to dial must have the IP the peer connected from
+	observed, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/4001")
+	other, _ := ma.NewMultiaddr("/ip4/8.8.8.8/tcp/4001")
+	same, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/4002")
+	addrs := dialBackAddrs(observed, [][]byte{other.Bytes(), same.Bytes()})
+	assert.Equal(t, []ma.Multiaddr{observed, same}, addrs)
+}
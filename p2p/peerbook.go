@@ -0,0 +1,172 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/common"
+	"github.com/crowdcompute/crowdengine/log"
+
+	ds "github.com/ipfs/go-datastore"
+	dsq "github.com/ipfs/go-datastore/query"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	ps "github.com/libp2p/go-libp2p-peerstore"
+	recpb "github.com/libp2p/go-libp2p-record/pb"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// peersPrefix is the datastore namespace of the peers the node has been connected to
+const peersPrefix = "/peers"
+
+// knownPeer is a peer the node has been connected to, as stored in the datastore
+type knownPeer struct {
+	Addrs    []string `json:"addrs"`
+	LastSeen int64    `json:"lastSeen"` // Unix time the node was last connected to the peer
+}
+
+// knownPeerKey returns the datastore key of the peer pID
+func knownPeerKey(pID peer.ID) ds.Key {
+	return ds.NewKey(peersPrefix).ChildString(peer.IDB58Encode(pID))
+}
+
+// savePeers stores the addresses of the peers the node is connected to
+func (h *Host) savePeers() error {
+	now := time.Now().Unix()
+	for _, pID := range h.P2PHost.Network().Peers() {
+		if h.P2PHost.Network().Connectedness(pID) != inet.Connected {
+			continue
+		}
+		known := knownPeer{LastSeen: now}
+		for _, addr := range h.P2PHost.Peerstore().Addrs(pID) {
+			known.Addrs = append(known.Addrs, addr.String())
+		}
+		if len(known.Addrs) == 0 {
+			continue
+		}
+		value, err := json.Marshal(known)
+		if err != nil {
+			return err
+		}
+		if err := h.dstore.Put(knownPeerKey(pID), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restorePeers adds the stored peers, that the node has been connected to recently, to the peerstore
+// and returns their IDs. Peers not seen for longer than common.KnownPeerExpiry are removed.
+func (h *Host) restorePeers() ([]peer.ID, error) {
+	results, err := h.dstore.Query(dsq.Query{Prefix: peersPrefix})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return nil, err
+	}
+	oldest := time.Now().Add(-common.KnownPeerExpiry).Unix()
+	restored := make([]peer.ID, 0, len(entries))
+	for _, entry := range entries {
+		key := ds.NewKey(entry.Key)
+		known := knownPeer{}
+		pID, err := peer.IDB58Decode(key.BaseNamespace())
+		if err != nil || json.Unmarshal(entry.Value, &known) != nil || known.LastSeen < oldest {
+			h.dstore.Delete(key)
+			continue
+		}
+		for _, addr := range known.Addrs {
+			if maddr, err := ma.NewMultiaddr(addr); err == nil {
+				h.P2PHost.Peerstore().AddAddr(pID, maddr, ps.AddressTTL)
+			}
+		}
+		restored = append(restored, pID)
+	}
+	return restored, nil
+}
+
+// reconnectPeers connects to the stored peers the node has been recently connected to
+func (h *Host) reconnectPeers() {
+	peers, err := h.restorePeers()
+	if err != nil {
+		log.Println("Couldn't restore the known peers. Error: ", err)
+		return
+	}
+	for _, pID := range peers {
+		go func(pID peer.ID) {
+			ctx, cancel := context.WithTimeout(context.Background(), common.RequestTimeout)
+			defer cancel()
+			if err := h.P2PHost.Connect(ctx, ps.PeerInfo{ID: pID}); err != nil {
+				log.Printf("Couldn't reconnect to %s. Error: %s", pID, err)
+			}
+		}(pID)
+	}
+}
+
+// pruneRecords removes the DHT records that are too old or no longer valid, e.g. expired capability records.
+// The DHT removes the expired provider records by itself.
+func (h *Host) pruneRecords() error {
+	results, err := h.dstore.Query(dsq.Query{})
+	if err != nil {
+		return err
+	}
+	entries, err := results.Rest()
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		key := ds.NewKey(entry.Key)
+		if ds.NewKey(peersPrefix).IsAncestorOf(key) || ds.NewKey("/providers").IsAncestorOf(key) {
+			continue
+		}
+		rec := &recpb.Record{}
+		if err := rec.Unmarshal(entry.Value); err != nil {
+			continue
+		}
+		received, err := time.Parse(time.RFC3339Nano, rec.GetTimeReceived())
+		if err != nil || time.Since(received) > dht.MaxRecordAge ||
+			h.dht.Validator.Validate(string(rec.GetKey()), rec.GetValue()) != nil {
+			h.dstore.Delete(key)
+		}
+	}
+	return nil
+}
+
+// PersistNetwork periodically stores the peers the node is connected to and prunes the stale entries
+// of the datastore, until quit is closed
+func (h *Host) PersistNetwork(quit <-chan struct{}) {
+	ticker := time.NewTicker(common.PersistNetworkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := h.savePeers(); err != nil {
+				log.Println("Couldn't store the known peers. Error: ", err)
+			}
+			if err := h.pruneRecords(); err != nil {
+				log.Println("Couldn't prune the DHT records. Error: ", err)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
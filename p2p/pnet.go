@@ -0,0 +1,228 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+
+	ipnet "github.com/libp2p/go-libp2p-interface-pnet"
+	"golang.org/x/crypto/salsa20"
+	"golang.org/x/crypto/salsa20/salsa"
+	"golang.org/x/crypto/sha3"
+)
+
+// The swarm key file holds the pre-shared key of a private network in the format of the libp2p PSK v1:
+//
+//	/key/swarm/psk/1.0.0/
+//	/base16/
+//	<64 hex characters>
+//
+// The /base64/ and /bin/ encodings are supported as well.
+const (
+	swarmKeyHeader = "/key/swarm/psk/1.0.0/"
+	pskNonceSize   = 24
+)
+
+var (
+	// ErrInvalidSwarmKey is returned when a swarm key file can't be decoded
+	ErrInvalidSwarmKey = errors.New("The swarm key file is invalid")
+	// errShortNonce is returned when a protected connection closes before the nonce is read
+	errShortNonce = errors.New("Couldn't read the nonce of the private network connection")
+)
+
+// PSKProtector protects the connections of a private network, so that only nodes with the pre-shared key
+// can connect. Connections are encrypted with XSalsa20 as in the libp2p PSK v1, which makes them
+// compatible with other libp2p private network implementations.
+type PSKProtector struct {
+	psk         [32]byte
+	fingerprint []byte
+}
+
+var _ ipnet.Protector = (*PSKProtector)(nil)
+
+// NewPSKProtector creates a PSKProtector with the pre-shared key of the swarm key file path
+func NewPSKProtector(path string) (*PSKProtector, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	psk, err := decodeSwarmKey(f)
+	if err != nil {
+		return nil, err
+	}
+	return &PSKProtector{psk: psk, fingerprint: pskFingerprint(&psk)}, nil
+}
+
+// Protect wraps the connection conn, encrypting everything sent through it
+func (p *PSKProtector) Protect(conn net.Conn) (net.Conn, error) {
+	return &pskConn{Conn: conn, psk: &p.psk}, nil
+}
+
+// Fingerprint returns a fingerprint of the pre-shared key that is safe to expose
+func (p *PSKProtector) Fingerprint() []byte {
+	return p.fingerprint
+}
+
+// GenerateSwarmKey writes a new random pre-shared key to the swarm key file path
+func GenerateSwarmKey(path string) error {
+	psk := make([]byte, 32)
+	if _, err := rand.Read(psk); err != nil {
+		return err
+	}
+	data := fmt.Sprintf("%s\n/base16/\n%s\n", swarmKeyHeader, hex.EncodeToString(psk))
+	return ioutil.WriteFile(path, []byte(data), 0600)
+}
+
+// decodeSwarmKey decodes the pre-shared key of a swarm key file
+func decodeSwarmKey(r io.Reader) ([32]byte, error) {
+	var psk [32]byte
+	reader := bufio.NewReader(r)
+	header, err := reader.ReadString('\n')
+	if err != nil || trimNewline(header) != swarmKeyHeader {
+		return psk, ErrInvalidSwarmKey
+	}
+	encoding, err := reader.ReadString('\n')
+	if err != nil {
+		return psk, ErrInvalidSwarmKey
+	}
+	rest, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return psk, err
+	}
+	var key []byte
+	switch trimNewline(encoding) {
+	case "/base16/":
+		key, err = hex.DecodeString(string(bytes.TrimSpace(rest)))
+	case "/base64/":
+		key, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(rest)))
+	case "/bin/":
+		key = rest
+	default:
+		return psk, ErrInvalidSwarmKey
+	}
+	if err != nil || len(key) != len(psk) {
+		return psk, ErrInvalidSwarmKey
+	}
+	copy(psk[:], key)
+	return psk, nil
+}
+
+func trimNewline(s string) string {
+	return string(bytes.TrimRight([]byte(s), "\r\n"))
+}
+
+// pskFingerprint returns a fingerprint of the pre-shared key that doesn't reveal it
+func pskFingerprint(psk *[32]byte) []byte {
+	// The key stream is not reversible, so the key isn't fed to the hash function directly
+	stream := make([]byte, 64)
+	salsa20.XORKeyStream(stream, stream, []byte("finprint"), psk)
+	out := make([]byte, 16)
+	sha3.ShakeSum128(out, stream)
+	return out
+}
+
+// pskConn is a connection encrypted with the pre-shared key. Each side sends a random nonce
+// before its first write and encrypts everything it writes with XSalsa20 under that nonce.
+type pskConn struct {
+	net.Conn
+	psk    *[32]byte
+	reader *xsalsa20Stream
+	writer *xsalsa20Stream
+}
+
+func (c *pskConn) Read(out []byte) (int, error) {
+	if c.reader == nil {
+		nonce := make([]byte, pskNonceSize)
+		if _, err := io.ReadFull(c.Conn, nonce); err != nil {
+			return 0, errShortNonce
+		}
+		c.reader = newXSalsa20Stream(c.psk, nonce)
+	}
+	n, err := c.Conn.Read(out)
+	if n > 0 {
+		c.reader.XORKeyStream(out[:n], out[:n])
+	}
+	return n, err
+}
+
+func (c *pskConn) Write(in []byte) (int, error) {
+	if c.writer == nil {
+		nonce := make([]byte, pskNonceSize)
+		if _, err := rand.Read(nonce); err != nil {
+			return 0, err
+		}
+		n, err := c.Conn.Write(nonce)
+		if err == nil && n != len(nonce) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return 0, err
+		}
+		c.writer = newXSalsa20Stream(c.psk, nonce)
+	}
+	out := make([]byte, len(in))
+	c.writer.XORKeyStream(out, in)
+	return c.Conn.Write(out)
+}
+
+// xsalsa20Stream is an XSalsa20 key stream, which keeps its position across calls of XORKeyStream
+type xsalsa20Stream struct {
+	key     [32]byte // The subkey of the nonce
+	counter [16]byte // The last 8 bytes of the nonce and the little endian block counter
+	block   [64]byte
+	used    int // Bytes of the current block already used
+}
+
+// newXSalsa20Stream returns the XSalsa20 key stream of the key with the 24 byte nonce
+func newXSalsa20Stream(key *[32]byte, nonce []byte) *xsalsa20Stream {
+	s := &xsalsa20Stream{used: 64}
+	var hNonce [16]byte
+	copy(hNonce[:], nonce[:16])
+	salsa.HSalsa20(&s.key, &hNonce, key, &salsa.Sigma)
+	copy(s.counter[:8], nonce[16:24])
+	return s
+}
+
+// XORKeyStream XORs src with the key stream into dst, which may be the same slice
+func (s *xsalsa20Stream) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if s.used == len(s.block) {
+			s.block = [64]byte{}
+			salsa.XORKeyStream(s.block[:], s.block[:], &s.counter, &s.key)
+			for j := 8; j < len(s.counter); j++ {
+				s.counter[j]++
+				if s.counter[j] != 0 {
+					break
+				}
+			}
+			s.used = 0
+		}
+		dst[i] = src[i] ^ s.block[s.used]
+		s.used++
+	}
+}
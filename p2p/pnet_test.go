@@ -0,0 +1,112 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/salsa20"
+)
+
+func TestXSalsa20(t *testing.T) {
+	var key [32]byte
+	copy(key[:], "this is 32-byte key for xsalsa20")
+	data := []byte("Hello world!")
+	newXSalsa20Stream(&key, []byte("24-byte nonce for xsalsa")).XORKeyStream(data, data)
+	assert.Equal(t, []byte{0x00, 0x2d, 0x45, 0x13, 0x84, 0x3f, 0xc2, 0x40, 0xc4, 0x01, 0xe5, 0x41}, data)
+
+	// The stream continues across calls and blocks
+	nonce := []byte("24-byte nonce for xsalsa")
+	long := make([]byte, 200)
+	expected := make([]byte, len(long))
+	salsa20.XORKeyStream(expected, long, nonce, &key)
+	stream := newXSalsa20Stream(&key, nonce)
+	stream.XORKeyStream(long[:10], long[:10])
+	stream.XORKeyStream(long[10:], long[10:])
+	assert.Equal(t, expected, long)
+}
+
+func TestDecodeSwarmKey(t *testing.T) {
+	hexKey := strings.Repeat("ab", 32)
+	psk, err := decodeSwarmKey(strings.NewReader(swarmKeyHeader + "\n/base16/\n" + hexKey + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0xab), psk[31])
+
+	_, err = decodeSwarmKey(strings.NewReader(swarmKeyHeader + "\n/base16/\nabab\n"))
+	assert.Equal(t, ErrInvalidSwarmKey, err)
+	_, err = decodeSwarmKey(strings.NewReader("/key/swarm/psk/2.0.0/\n/base16/\n" + hexKey))
+	assert.Equal(t, ErrInvalidSwarmKey, err)
+}
+
+func TestPSKConn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pnet")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "swarm.key")
+	assert.NoError(t, GenerateSwarmKey(keyFile))
+	protector, err := NewPSKProtector(keyFile)
+	assert.NoError(t, err)
+	assert.Len(t, protector.Fingerprint(), 16)
+
+	c1, c2 := net.Pipe()
+	p1, _ := protector.Protect(c1)
+	p2, _ := protector.Protect(c2)
+	go p1.Write([]byte("private message"))
+	buf := make([]byte, len("private message"))
+	_, err = p2.Read(buf[:4])
+	assert.NoError(t, err)
+	_, err = p2.Read(buf[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, "private message", string(buf))
+}
+
+func TestPrivateNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pnet")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "swarm.key")
+	assert.NoError(t, GenerateSwarmKey(keyFile))
+
+	private1, err := NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10334, ListenAddress: "127.0.0.1", SwarmKeyFile: keyFile},
+	})
+	assert.NoError(t, err)
+	private2, err := NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10335, ListenAddress: "127.0.0.1", SwarmKeyFile: keyFile},
+	})
+	assert.NoError(t, err)
+	public, err := NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10336, ListenAddress: "127.0.0.1"},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, private1.P2PHost.Connect(context.Background(), peerInfo(private2)))
+	// The handshake of a node without the key never completes
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Error(t, public.P2PHost.Connect(ctx, peerInfo(private1)))
+}
@@ -0,0 +1,163 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: capability.proto
+
+package protomsgs
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// CapabilityRecord describes what a single container of a node offers. It is signed by the node.
+type CapabilityRecord struct {
+	NodeId               string   `protobuf:"bytes,1,opt,name=nodeId,proto3" json:"nodeId,omitempty"`
+	NodePubKey           []byte   `protobuf:"bytes,2,opt,name=nodePubKey,proto3" json:"nodePubKey,omitempty"`
+	Cpu                  uint32   `protobuf:"varint,3,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	Memory               uint64   `protobuf:"varint,4,opt,name=memory,proto3" json:"memory,omitempty"`
+	Storage              uint64   `protobuf:"varint,5,opt,name=storage,proto3" json:"storage,omitempty"`
+	Arch                 string   `protobuf:"bytes,6,opt,name=arch,proto3" json:"arch,omitempty"`
+	Labels               []string `protobuf:"bytes,7,rep,name=labels,proto3" json:"labels,omitempty"`
+	Price                uint64   `protobuf:"varint,8,opt,name=price,proto3" json:"price,omitempty"`
+	Timestamp            int64    `protobuf:"varint,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Expiry               int64    `protobuf:"varint,10,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	Sign                 []byte   `protobuf:"bytes,11,opt,name=sign,proto3" json:"sign,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CapabilityRecord) Reset()         { *m = CapabilityRecord{} }
+func (m *CapabilityRecord) String() string { return proto.CompactTextString(m) }
+func (*CapabilityRecord) ProtoMessage()    {}
+func (*CapabilityRecord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_capability_d3ba63589daded36, []int{0}
+}
+func (m *CapabilityRecord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CapabilityRecord.Unmarshal(m, b)
+}
+func (m *CapabilityRecord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CapabilityRecord.Marshal(b, m, deterministic)
+}
+func (dst *CapabilityRecord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CapabilityRecord.Merge(dst, src)
+}
+func (m *CapabilityRecord) XXX_Size() int {
+	return xxx_messageInfo_CapabilityRecord.Size(m)
+}
+func (m *CapabilityRecord) XXX_DiscardUnknown() {
+	xxx_messageInfo_CapabilityRecord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CapabilityRecord proto.InternalMessageInfo
+
+func (m *CapabilityRecord) GetNodeId() string {
+	if m != nil {
+		return m.NodeId
+	}
+	return ""
+}
+
+func (m *CapabilityRecord) GetNodePubKey() []byte {
+	if m != nil {
+		return m.NodePubKey
+	}
+	return nil
+}
+
+func (m *CapabilityRecord) GetCpu() uint32 {
+	if m != nil {
+		return m.Cpu
+	}
+	return 0
+}
+
+func (m *CapabilityRecord) GetMemory() uint64 {
+	if m != nil {
+		return m.Memory
+	}
+	return 0
+}
+
+func (m *CapabilityRecord) GetStorage() uint64 {
+	if m != nil {
+		return m.Storage
+	}
+	return 0
+}
+
+func (m *CapabilityRecord) GetArch() string {
+	if m != nil {
+		return m.Arch
+	}
+	return ""
+}
+
+func (m *CapabilityRecord) GetLabels() []string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *CapabilityRecord) GetPrice() uint64 {
+	if m != nil {
+		return m.Price
+	}
+	return 0
+}
+
+func (m *CapabilityRecord) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *CapabilityRecord) GetExpiry() int64 {
+	if m != nil {
+		return m.Expiry
+	}
+	return 0
+}
+
+func (m *CapabilityRecord) GetSign() []byte {
+	if m != nil {
+		return m.Sign
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*CapabilityRecord)(nil), "protomsgs.CapabilityRecord")
+}
+
+func init() { proto.RegisterFile("capability.proto", fileDescriptor_capability_d3ba63589daded36) }
+
+var fileDescriptor_capability_d3ba63589daded36 = []byte{
+	// 234 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0xc1, 0x4a, 0xc4, 0x30,
+	0x10, 0x86, 0xc9, 0xb6, 0xdb, 0xb5, 0xa3, 0x42, 0x09, 0x22, 0x73, 0x10, 0x09, 0x9e, 0x72, 0xf2,
+	0xe2, 0x23, 0x78, 0x12, 0x2f, 0x92, 0x37, 0x48, 0xd3, 0x50, 0x03, 0xcd, 0x26, 0x24, 0x59, 0xb0,
+	0xaf, 0xe2, 0xd3, 0x4a, 0x66, 0xab, 0xee, 0x29, 0xff, 0x37, 0x3f, 0x7c, 0x93, 0x04, 0x06, 0xa3,
+	0xa3, 0x1e, 0xdd, 0xe2, 0xca, 0xfa, 0x1c, 0x53, 0x28, 0x81, 0xf7, 0x74, 0xf8, 0x3c, 0xe7, 0xa7,
+	0xef, 0x1d, 0x0c, 0xaf, 0x7f, 0xbd, 0xb2, 0x26, 0xa4, 0x89, 0xdf, 0x43, 0x77, 0x0c, 0x93, 0x7d,
+	0x9b, 0x90, 0x09, 0x26, 0x7b, 0xb5, 0x11, 0x7f, 0x04, 0xa8, 0xe9, 0xe3, 0x34, 0xbe, 0xdb, 0x15,
+	0x77, 0x82, 0xc9, 0x1b, 0x75, 0x31, 0xe1, 0x03, 0x34, 0x26, 0x9e, 0xb0, 0x11, 0x4c, 0xde, 0xaa,
+	0x1a, 0xab, 0xc9, 0x5b, 0x1f, 0xd2, 0x8a, 0xad, 0x60, 0xb2, 0x55, 0x1b, 0x71, 0x84, 0x43, 0x2e,
+	0x21, 0xe9, 0xd9, 0xe2, 0x9e, 0x8a, 0x5f, 0xe4, 0x1c, 0x5a, 0x9d, 0xcc, 0x27, 0x76, 0xb4, 0x99,
+	0x72, 0xb5, 0x2c, 0x7a, 0xb4, 0x4b, 0xc6, 0x83, 0x68, 0xea, 0x7d, 0xce, 0xc4, 0xef, 0x60, 0x1f,
+	0x93, 0x33, 0x16, 0xaf, 0xc8, 0x71, 0x06, 0xfe, 0x00, 0x7d, 0x71, 0xde, 0xe6, 0xa2, 0x7d, 0xc4,
+	0x5e, 0x30, 0xd9, 0xa8, 0xff, 0x41, 0x75, 0xd9, 0xaf, 0xe8, 0xd2, 0x8a, 0x40, 0xd5, 0x46, 0x75,
+	0x6f, 0x76, 0xf3, 0x11, 0xaf, 0xe9, 0x55, 0x94, 0xc7, 0x8e, 0xfe, 0xe9, 0xe5, 0x27, 0x00, 0x00,
+	0xff, 0xff, 0x4a, 0x85, 0x27, 0x8f, 0x42, 0x01, 0x00, 0x00,
+}
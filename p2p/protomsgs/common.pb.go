@@ -18,6 +18,41 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type ErrorCode int32
+
+const (
+	ErrorCode_Internal          ErrorCode = 0
+	ErrorCode_InvalidRequest    ErrorCode = 1
+	ErrorCode_Unauthenticated   ErrorCode = 2
+	ErrorCode_NotFound          ErrorCode = 3
+	ErrorCode_Unavailable       ErrorCode = 4
+	ErrorCode_ResourceExhausted ErrorCode = 5
+)
+
+var ErrorCode_name = map[int32]string{
+	0: "Internal",
+	1: "InvalidRequest",
+	2: "Unauthenticated",
+	3: "NotFound",
+	4: "Unavailable",
+	5: "ResourceExhausted",
+}
+var ErrorCode_value = map[string]int32{
+	"Internal":          0,
+	"InvalidRequest":    1,
+	"Unauthenticated":   2,
+	"NotFound":          3,
+	"Unavailable":       4,
+	"ResourceExhausted": 5,
+}
+
+func (x ErrorCode) String() string {
+	return proto.EnumName(ErrorCode_name, int32(x))
+}
+func (ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_common_665fc3eaf14bab61, []int{0}
+}
+
 // designed to be shared between all app protocols
 type MessageData struct {
 	// shared between all requests
@@ -37,7 +72,7 @@ func (m *MessageData) Reset()         { *m = MessageData{} }
 func (m *MessageData) String() string { return proto.CompactTextString(m) }
 func (*MessageData) ProtoMessage()    {}
 func (*MessageData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_9d2411bd3bdef3e9, []int{0}
+	return fileDescriptor_common_665fc3eaf14bab61, []int{0}
 }
 func (m *MessageData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_MessageData.Unmarshal(m, b)
@@ -106,25 +141,90 @@ func (m *MessageData) GetSign() []byte {
 	return nil
 }
 
-func init() {
-	proto.RegisterType((*MessageData)(nil), "protomsgs.MessageData")
+// Error is sent in a response when its request failed
+type Error struct {
+	Code                 ErrorCode `protobuf:"varint,1,opt,name=code,proto3,enum=protomsgs.ErrorCode" json:"code,omitempty"`
+	Message              string    `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Retryable            bool      `protobuf:"varint,3,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Error) Reset()         { *m = Error{} }
+func (m *Error) String() string { return proto.CompactTextString(m) }
+func (*Error) ProtoMessage()    {}
+func (*Error) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_665fc3eaf14bab61, []int{1}
+}
+func (m *Error) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Error.Unmarshal(m, b)
+}
+func (m *Error) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Error.Marshal(b, m, deterministic)
+}
+func (dst *Error) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Error.Merge(dst, src)
+}
+func (m *Error) XXX_Size() int {
+	return xxx_messageInfo_Error.Size(m)
+}
+func (m *Error) XXX_DiscardUnknown() {
+	xxx_messageInfo_Error.DiscardUnknown(m)
 }
 
-func init() { proto.RegisterFile("common.proto", fileDescriptor_common_9d2411bd3bdef3e9) }
-
-var fileDescriptor_common_9d2411bd3bdef3e9 = []byte{
-	// 195 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x8f, 0xb1, 0x6a, 0xc3, 0x30,
-	0x10, 0x86, 0x91, 0xed, 0xba, 0xf5, 0xd5, 0xed, 0x70, 0x43, 0xd1, 0x50, 0x8a, 0x28, 0x1d, 0x34,
-	0x75, 0xe9, 0x2b, 0x74, 0x09, 0x21, 0x10, 0x34, 0x64, 0x97, 0x2d, 0x21, 0x04, 0x91, 0x64, 0x7c,
-	0xca, 0x90, 0x07, 0xcc, 0x7b, 0x05, 0x0b, 0x43, 0x92, 0xe9, 0xfe, 0xef, 0xbb, 0xbb, 0xe1, 0x87,
-	0x7e, 0x4c, 0x21, 0xa4, 0xf8, 0x3b, 0xcd, 0x29, 0x27, 0xec, 0xca, 0x08, 0xe4, 0xe8, 0xfb, 0xc2,
-	0xe0, 0x75, 0x67, 0x89, 0xb4, 0xb3, 0xff, 0x3a, 0x6b, 0xfc, 0x81, 0xb7, 0xf1, 0xe8, 0x6d, 0xcc,
-	0x07, 0x3b, 0x93, 0x4f, 0x91, 0x33, 0xc1, 0x64, 0xa7, 0x1e, 0x25, 0x7e, 0x42, 0x97, 0x7d, 0xb0,
-	0x94, 0x75, 0x98, 0x78, 0x25, 0x98, 0xac, 0xd5, 0x4d, 0xe0, 0x3b, 0x54, 0xde, 0xf0, 0xba, 0x3c,
-	0x56, 0xde, 0xe0, 0x07, 0xb4, 0x2e, 0x11, 0xf9, 0x89, 0x37, 0x82, 0xc9, 0x17, 0xb5, 0xd2, 0xe2,
-	0x63, 0x32, 0x76, 0x63, 0xf8, 0x53, 0xb9, 0x5d, 0x09, 0xbf, 0x00, 0x96, 0xb4, 0x3f, 0x0d, 0x5b,
-	0x7b, 0xe6, 0xad, 0x60, 0xb2, 0x57, 0x77, 0x06, 0x11, 0x1a, 0xf2, 0x2e, 0xf2, 0xe7, 0xb2, 0x29,
-	0x79, 0x68, 0x4b, 0xa5, 0xbf, 0x6b, 0x00, 0x00, 0x00, 0xff, 0xff, 0x29, 0x19, 0x17, 0xb3, 0xe9,
-	0x00, 0x00, 0x00,
+var xxx_messageInfo_Error proto.InternalMessageInfo
+
+func (m *Error) GetCode() ErrorCode {
+	if m != nil {
+		return m.Code
+	}
+	return ErrorCode_Internal
+}
+
+func (m *Error) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *Error) GetRetryable() bool {
+	if m != nil {
+		return m.Retryable
+	}
+	return false
+}
+
+func init() {
+	proto.RegisterType((*MessageData)(nil), "protomsgs.MessageData")
+	proto.RegisterType((*Error)(nil), "protomsgs.Error")
+	proto.RegisterEnum("protomsgs.ErrorCode", ErrorCode_name, ErrorCode_value)
+}
+
+func init() { proto.RegisterFile("common.proto", fileDescriptor_common_665fc3eaf14bab61) }
+
+var fileDescriptor_common_665fc3eaf14bab61 = []byte{
+	// 334 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x8f, 0xcd, 0x6e, 0xe2, 0x30,
+	0x10, 0xc7, 0x37, 0x21, 0x7c, 0x64, 0x60, 0x21, 0x3b, 0xfb, 0x21, 0x1f, 0x56, 0xab, 0x08, 0xed,
+	0x21, 0xda, 0x03, 0x87, 0xed, 0x23, 0xb4, 0x54, 0x42, 0x55, 0xab, 0xca, 0x12, 0xbd, 0x9b, 0x78,
+	0x14, 0x2c, 0x25, 0x36, 0xb5, 0x1d, 0x04, 0x0f, 0xd8, 0xf7, 0xaa, 0xe2, 0x52, 0x68, 0x4f, 0x9e,
+	0xf9, 0xcd, 0xfc, 0x6d, 0xff, 0x60, 0x52, 0x9a, 0xa6, 0x31, 0x7a, 0xb1, 0xb3, 0xc6, 0x1b, 0x4c,
+	0xc3, 0xd1, 0xb8, 0xca, 0xcd, 0x5f, 0x22, 0x18, 0xdf, 0x93, 0x73, 0xa2, 0xa2, 0x1b, 0xe1, 0x05,
+	0xfe, 0x85, 0xaf, 0x65, 0xad, 0x48, 0xfb, 0x27, 0xb2, 0x4e, 0x19, 0xcd, 0xa2, 0x3c, 0x2a, 0x52,
+	0xfe, 0x19, 0xe2, 0x6f, 0x48, 0xbd, 0x6a, 0xc8, 0x79, 0xd1, 0xec, 0x58, 0x9c, 0x47, 0x45, 0x8f,
+	0x5f, 0x00, 0x4e, 0x21, 0x56, 0x92, 0xf5, 0x42, 0x30, 0x56, 0x12, 0x7f, 0xc1, 0xa0, 0x32, 0xce,
+	0xa9, 0x1d, 0x4b, 0xf2, 0xa8, 0x18, 0xf1, 0x53, 0xd7, 0x71, 0x6d, 0x24, 0xad, 0x24, 0xeb, 0x87,
+	0xdd, 0x53, 0x87, 0x7f, 0x00, 0xba, 0xea, 0xb1, 0xdd, 0xdc, 0xd1, 0x91, 0x0d, 0xf2, 0xa8, 0x98,
+	0xf0, 0x0f, 0x04, 0x11, 0x12, 0xa7, 0x2a, 0xcd, 0x86, 0x61, 0x12, 0xea, 0xb9, 0x82, 0xfe, 0xd2,
+	0x5a, 0x63, 0xb1, 0x80, 0xa4, 0x34, 0x92, 0xc2, 0xbf, 0xa7, 0xff, 0x7f, 0x2c, 0xce, 0xaa, 0x8b,
+	0x30, 0xbf, 0x36, 0x92, 0x78, 0xd8, 0x40, 0x06, 0xc3, 0xe6, 0xcd, 0x3c, 0x28, 0xa4, 0xfc, 0xbd,
+	0xed, 0xf4, 0x2c, 0x79, 0x7b, 0x14, 0x9b, 0x9a, 0x82, 0xc7, 0x88, 0x5f, 0xc0, 0xbf, 0x03, 0xa4,
+	0xe7, 0xab, 0x70, 0x02, 0xa3, 0x95, 0xf6, 0x64, 0xb5, 0xa8, 0xb3, 0x2f, 0x88, 0x30, 0x5d, 0xe9,
+	0xbd, 0xa8, 0x95, 0xe4, 0xf4, 0xdc, 0x92, 0xf3, 0x59, 0x84, 0xdf, 0x61, 0xb6, 0xd6, 0xa2, 0xf5,
+	0x5b, 0xd2, 0x5e, 0x95, 0xc2, 0x93, 0xcc, 0xe2, 0x2e, 0xf6, 0x60, 0xfc, 0xad, 0x69, 0xb5, 0xcc,
+	0x7a, 0x38, 0x83, 0xf1, 0x5a, 0x8b, 0xbd, 0x50, 0x75, 0xf7, 0x40, 0x96, 0xe0, 0x4f, 0xf8, 0xc6,
+	0xc9, 0x99, 0xd6, 0x96, 0xb4, 0x3c, 0x6c, 0x45, 0xeb, 0xba, 0x54, 0x7f, 0x33, 0x08, 0x32, 0x57,
+	0xaf, 0x01, 0x00, 0x00, 0xff, 0xff, 0xac, 0x50, 0x70, 0x64, 0xce, 0x01, 0x00, 0x00,
 }
@@ -38,7 +38,7 @@ func (x DiscoveryMessage) String() string {
 	return proto.EnumName(DiscoveryMessage_name, int32(x))
 }
 func (DiscoveryMessage) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_discovery_288524306e7a11d1, []int{0}
+	return fileDescriptor_discovery_4874f7e8c5a8a0f7, []int{0}
 }
 
 type DiscoveryMsgData struct {
@@ -47,6 +47,7 @@ type DiscoveryMsgData struct {
 	Expiry               uint32       `protobuf:"varint,3,opt,name=Expiry,proto3" json:"Expiry,omitempty"`
 	TTL                  uint32       `protobuf:"varint,4,opt,name=TTL,proto3" json:"TTL,omitempty"`
 	InitHash             string       `protobuf:"bytes,5,opt,name=InitHash,proto3" json:"InitHash,omitempty"`
+	Path                 []string     `protobuf:"bytes,6,rep,name=path,proto3" json:"path,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
 	XXX_unrecognized     []byte       `json:"-"`
 	XXX_sizecache        int32        `json:"-"`
@@ -56,7 +57,7 @@ func (m *DiscoveryMsgData) Reset()         { *m = DiscoveryMsgData{} }
 func (m *DiscoveryMsgData) String() string { return proto.CompactTextString(m) }
 func (*DiscoveryMsgData) ProtoMessage()    {}
 func (*DiscoveryMsgData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_discovery_288524306e7a11d1, []int{0}
+	return fileDescriptor_discovery_4874f7e8c5a8a0f7, []int{0}
 }
 func (m *DiscoveryMsgData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiscoveryMsgData.Unmarshal(m, b)
@@ -111,11 +112,90 @@ func (m *DiscoveryMsgData) GetInitHash() string {
 	return ""
 }
 
+func (m *DiscoveryMsgData) GetPath() []string {
+	if m != nil {
+		return m.Path
+	}
+	return nil
+}
+
+// JobRequirements describes the resources a job needs in order to run on a node
+type JobRequirements struct {
+	Cpu                  uint32   `protobuf:"varint,1,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	Memory               uint64   `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	Storage              uint64   `protobuf:"varint,3,opt,name=storage,proto3" json:"storage,omitempty"`
+	Arch                 string   `protobuf:"bytes,4,opt,name=arch,proto3" json:"arch,omitempty"`
+	MaxRuntime           uint64   `protobuf:"varint,5,opt,name=maxRuntime,proto3" json:"maxRuntime,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *JobRequirements) Reset()         { *m = JobRequirements{} }
+func (m *JobRequirements) String() string { return proto.CompactTextString(m) }
+func (*JobRequirements) ProtoMessage()    {}
+func (*JobRequirements) Descriptor() ([]byte, []int) {
+	return fileDescriptor_discovery_4874f7e8c5a8a0f7, []int{1}
+}
+func (m *JobRequirements) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JobRequirements.Unmarshal(m, b)
+}
+func (m *JobRequirements) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JobRequirements.Marshal(b, m, deterministic)
+}
+func (dst *JobRequirements) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JobRequirements.Merge(dst, src)
+}
+func (m *JobRequirements) XXX_Size() int {
+	return xxx_messageInfo_JobRequirements.Size(m)
+}
+func (m *JobRequirements) XXX_DiscardUnknown() {
+	xxx_messageInfo_JobRequirements.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JobRequirements proto.InternalMessageInfo
+
+func (m *JobRequirements) GetCpu() uint32 {
+	if m != nil {
+		return m.Cpu
+	}
+	return 0
+}
+
+func (m *JobRequirements) GetMemory() uint64 {
+	if m != nil {
+		return m.Memory
+	}
+	return 0
+}
+
+func (m *JobRequirements) GetStorage() uint64 {
+	if m != nil {
+		return m.Storage
+	}
+	return 0
+}
+
+func (m *JobRequirements) GetArch() string {
+	if m != nil {
+		return m.Arch
+	}
+	return ""
+}
+
+func (m *JobRequirements) GetMaxRuntime() uint64 {
+	if m != nil {
+		return m.MaxRuntime
+	}
+	return 0
+}
+
 // a protocol define a set of reuqest and responses
 type DiscoveryRequest struct {
 	DiscoveryMsgData *DiscoveryMsgData `protobuf:"bytes,1,opt,name=discoveryMsgData,proto3" json:"discoveryMsgData,omitempty"`
 	// method specific data
 	Message              DiscoveryMessage `protobuf:"varint,2,opt,name=message,proto3,enum=protomsgs.DiscoveryMessage" json:"message,omitempty"`
+	Requirements         *JobRequirements `protobuf:"bytes,3,opt,name=requirements,proto3" json:"requirements,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
 	XXX_sizecache        int32            `json:"-"`
@@ -125,7 +205,7 @@ func (m *DiscoveryRequest) Reset()         { *m = DiscoveryRequest{} }
 func (m *DiscoveryRequest) String() string { return proto.CompactTextString(m) }
 func (*DiscoveryRequest) ProtoMessage()    {}
 func (*DiscoveryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_discovery_288524306e7a11d1, []int{1}
+	return fileDescriptor_discovery_4874f7e8c5a8a0f7, []int{2}
 }
 func (m *DiscoveryRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiscoveryRequest.Unmarshal(m, b)
@@ -159,20 +239,75 @@ func (m *DiscoveryRequest) GetMessage() DiscoveryMessage {
 	return DiscoveryMessage_DiscoveryReq
 }
 
+func (m *DiscoveryRequest) GetRequirements() *JobRequirements {
+	if m != nil {
+		return m.Requirements
+	}
+	return nil
+}
+
+// AvailabilityWindow is a time window, in unix time, that a node is available for processing
+type AvailabilityWindow struct {
+	Start                int64    `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End                  int64    `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AvailabilityWindow) Reset()         { *m = AvailabilityWindow{} }
+func (m *AvailabilityWindow) String() string { return proto.CompactTextString(m) }
+func (*AvailabilityWindow) ProtoMessage()    {}
+func (*AvailabilityWindow) Descriptor() ([]byte, []int) {
+	return fileDescriptor_discovery_4874f7e8c5a8a0f7, []int{3}
+}
+func (m *AvailabilityWindow) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AvailabilityWindow.Unmarshal(m, b)
+}
+func (m *AvailabilityWindow) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AvailabilityWindow.Marshal(b, m, deterministic)
+}
+func (dst *AvailabilityWindow) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AvailabilityWindow.Merge(dst, src)
+}
+func (m *AvailabilityWindow) XXX_Size() int {
+	return xxx_messageInfo_AvailabilityWindow.Size(m)
+}
+func (m *AvailabilityWindow) XXX_DiscardUnknown() {
+	xxx_messageInfo_AvailabilityWindow.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AvailabilityWindow proto.InternalMessageInfo
+
+func (m *AvailabilityWindow) GetStart() int64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *AvailabilityWindow) GetEnd() int64 {
+	if m != nil {
+		return m.End
+	}
+	return 0
+}
+
 type DiscoveryResponse struct {
 	DiscoveryMsgData *DiscoveryMsgData `protobuf:"bytes,1,opt,name=discoveryMsgData,proto3" json:"discoveryMsgData,omitempty"`
 	// response specific data
-	Message              DiscoveryMessage `protobuf:"varint,2,opt,name=message,proto3,enum=protomsgs.DiscoveryMessage" json:"message,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+	Message              DiscoveryMessage    `protobuf:"varint,2,opt,name=message,proto3,enum=protomsgs.DiscoveryMessage" json:"message,omitempty"`
+	Availability         *AvailabilityWindow `protobuf:"bytes,3,opt,name=availability,proto3" json:"availability,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
 func (m *DiscoveryResponse) Reset()         { *m = DiscoveryResponse{} }
 func (m *DiscoveryResponse) String() string { return proto.CompactTextString(m) }
 func (*DiscoveryResponse) ProtoMessage()    {}
 func (*DiscoveryResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_discovery_288524306e7a11d1, []int{2}
+	return fileDescriptor_discovery_4874f7e8c5a8a0f7, []int{4}
 }
 func (m *DiscoveryResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiscoveryResponse.Unmarshal(m, b)
@@ -206,32 +341,103 @@ func (m *DiscoveryResponse) GetMessage() DiscoveryMessage {
 	return DiscoveryMessage_DiscoveryReq
 }
 
+func (m *DiscoveryResponse) GetAvailability() *AvailabilityWindow {
+	if m != nil {
+		return m.Availability
+	}
+	return nil
+}
+
+// DiscoveryRelay carries a discovery response back to the initiator along the reverse path of the request.
+// The response keeps the signature of the responder, only the route changes on every relay.
+type DiscoveryRelay struct {
+	Response             *DiscoveryResponse `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	Route                []string           `protobuf:"bytes,2,rep,name=route,proto3" json:"route,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *DiscoveryRelay) Reset()         { *m = DiscoveryRelay{} }
+func (m *DiscoveryRelay) String() string { return proto.CompactTextString(m) }
+func (*DiscoveryRelay) ProtoMessage()    {}
+func (*DiscoveryRelay) Descriptor() ([]byte, []int) {
+	return fileDescriptor_discovery_4874f7e8c5a8a0f7, []int{5}
+}
+func (m *DiscoveryRelay) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiscoveryRelay.Unmarshal(m, b)
+}
+func (m *DiscoveryRelay) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiscoveryRelay.Marshal(b, m, deterministic)
+}
+func (dst *DiscoveryRelay) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiscoveryRelay.Merge(dst, src)
+}
+func (m *DiscoveryRelay) XXX_Size() int {
+	return xxx_messageInfo_DiscoveryRelay.Size(m)
+}
+func (m *DiscoveryRelay) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiscoveryRelay.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiscoveryRelay proto.InternalMessageInfo
+
+func (m *DiscoveryRelay) GetResponse() *DiscoveryResponse {
+	if m != nil {
+		return m.Response
+	}
+	return nil
+}
+
+func (m *DiscoveryRelay) GetRoute() []string {
+	if m != nil {
+		return m.Route
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*DiscoveryMsgData)(nil), "protomsgs.DiscoveryMsgData")
+	proto.RegisterType((*JobRequirements)(nil), "protomsgs.JobRequirements")
 	proto.RegisterType((*DiscoveryRequest)(nil), "protomsgs.DiscoveryRequest")
+	proto.RegisterType((*AvailabilityWindow)(nil), "protomsgs.AvailabilityWindow")
 	proto.RegisterType((*DiscoveryResponse)(nil), "protomsgs.DiscoveryResponse")
+	proto.RegisterType((*DiscoveryRelay)(nil), "protomsgs.DiscoveryRelay")
 	proto.RegisterEnum("protomsgs.DiscoveryMessage", DiscoveryMessage_name, DiscoveryMessage_value)
 }
 
-func init() { proto.RegisterFile("discovery.proto", fileDescriptor_discovery_288524306e7a11d1) }
-
-var fileDescriptor_discovery_288524306e7a11d1 = []byte{
-	// 265 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x4f, 0xc9, 0x2c, 0x4e,
-	0xce, 0x2f, 0x4b, 0x2d, 0xaa, 0xd4, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x04, 0x53, 0xb9,
-	0xc5, 0xe9, 0xc5, 0x52, 0x3c, 0xc9, 0xf9, 0xb9, 0xb9, 0xf9, 0x79, 0x10, 0x09, 0xa5, 0x4d, 0x8c,
-	0x5c, 0x02, 0x2e, 0x30, 0xc5, 0xbe, 0xc5, 0xe9, 0x2e, 0x89, 0x25, 0x89, 0x42, 0x16, 0x5c, 0xdc,
-	0xb9, 0xa9, 0xc5, 0xc5, 0x89, 0xe9, 0xa9, 0x20, 0xae, 0x04, 0xa3, 0x02, 0xa3, 0x06, 0xb7, 0x91,
-	0x98, 0x1e, 0xdc, 0x0c, 0x3d, 0x5f, 0x84, 0x6c, 0x10, 0xb2, 0x52, 0x21, 0x39, 0x2e, 0xae, 0xcc,
-	0xbc, 0xcc, 0x12, 0xbf, 0xfc, 0x94, 0x54, 0x4f, 0x17, 0x09, 0x26, 0x05, 0x46, 0x0d, 0xce, 0x20,
-	0x24, 0x11, 0x21, 0x31, 0x2e, 0x36, 0xd7, 0x8a, 0x82, 0xcc, 0xa2, 0x4a, 0x09, 0x66, 0x05, 0x46,
-	0x0d, 0xde, 0x20, 0x28, 0x4f, 0x48, 0x80, 0x8b, 0x39, 0x24, 0xc4, 0x47, 0x82, 0x05, 0x2c, 0x08,
-	0x62, 0x0a, 0x49, 0x71, 0x71, 0x78, 0xe6, 0x65, 0x96, 0x78, 0x24, 0x16, 0x67, 0x48, 0xb0, 0x82,
-	0xcd, 0x81, 0xf3, 0x95, 0x26, 0x21, 0x3b, 0x3a, 0x28, 0xb5, 0xb0, 0x34, 0xb5, 0xb8, 0x44, 0xc8,
-	0x9d, 0x4b, 0x20, 0x05, 0xcd, 0x23, 0x50, 0x97, 0x4b, 0x23, 0xb9, 0x1c, 0xdd, 0xaf, 0x41, 0x18,
-	0x9a, 0x84, 0x4c, 0xb9, 0xd8, 0xa1, 0x5e, 0x02, 0x7b, 0x80, 0x0f, 0x87, 0x7e, 0x88, 0x92, 0x20,
-	0x98, 0x5a, 0xa5, 0xc9, 0x8c, 0x5c, 0x82, 0x48, 0x8e, 0x2a, 0x2e, 0xc8, 0xcf, 0x2b, 0x4e, 0x1d,
-	0x68, 0x57, 0x69, 0x99, 0x21, 0x47, 0x2f, 0x44, 0x4c, 0x48, 0x80, 0x8b, 0x07, 0x39, 0xf4, 0x04,
-	0x18, 0xd0, 0x44, 0x8a, 0x05, 0x18, 0x93, 0xd8, 0xc0, 0x86, 0x1b, 0x03, 0x02, 0x00, 0x00, 0xff,
-	0xff, 0x38, 0xbe, 0xd5, 0xa4, 0x4a, 0x02, 0x00, 0x00,
+func init() { proto.RegisterFile("discovery.proto", fileDescriptor_discovery_4874f7e8c5a8a0f7) }
+
+var fileDescriptor_discovery_4874f7e8c5a8a0f7 = []byte{
+	// 467 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x93, 0xcf, 0x6e, 0x13, 0x31,
+	0x10, 0xc6, 0x31, 0xbb, 0x4d, 0x9b, 0x49, 0xda, 0x2e, 0x16, 0xaa, 0x56, 0xe1, 0x8f, 0xa2, 0x3d,
+	0x45, 0x1c, 0x72, 0x28, 0x02, 0xf5, 0x80, 0x90, 0x2a, 0x05, 0x41, 0x11, 0xe5, 0x30, 0xaa, 0xc4,
+	0x15, 0x27, 0x6b, 0x25, 0x96, 0x62, 0x7b, 0xb1, 0x9d, 0xd2, 0x7d, 0x04, 0x5e, 0x8c, 0x07, 0xe0,
+	0xc0, 0xf3, 0x20, 0x3b, 0x4e, 0x70, 0x53, 0x38, 0xf7, 0x94, 0xf9, 0x66, 0x67, 0xec, 0xef, 0xfb,
+	0x65, 0x17, 0x8e, 0x6b, 0x61, 0x67, 0xfa, 0x9a, 0x9b, 0x76, 0xdc, 0x18, 0xed, 0x34, 0xed, 0x86,
+	0x1f, 0x69, 0xe7, 0x76, 0xd0, 0x9f, 0x69, 0x29, 0xb5, 0x5a, 0x3f, 0xa8, 0x7e, 0x12, 0x28, 0x26,
+	0x9b, 0xe1, 0x4b, 0x3b, 0x9f, 0x30, 0xc7, 0xe8, 0x19, 0xf4, 0x24, 0xb7, 0x96, 0xcd, 0xb9, 0x97,
+	0x25, 0x19, 0x92, 0x51, 0xef, 0xf4, 0x64, 0xbc, 0x3d, 0x63, 0x7c, 0xf9, 0xf7, 0x29, 0xa6, 0xa3,
+	0xf4, 0x39, 0x80, 0x50, 0xc2, 0x7d, 0xd6, 0x35, 0xbf, 0x98, 0x94, 0x0f, 0x87, 0x64, 0xd4, 0xc5,
+	0xa4, 0x43, 0x4f, 0xa0, 0xf3, 0xee, 0xa6, 0x11, 0xa6, 0x2d, 0xb3, 0x21, 0x19, 0x1d, 0x62, 0x54,
+	0xb4, 0x80, 0xec, 0xea, 0xea, 0x53, 0x99, 0x87, 0xa6, 0x2f, 0xe9, 0x00, 0x0e, 0x2e, 0x94, 0x70,
+	0x1f, 0x98, 0x5d, 0x94, 0x7b, 0xe1, 0x9c, 0xad, 0xa6, 0x14, 0xf2, 0x86, 0xb9, 0x45, 0xd9, 0x19,
+	0x66, 0xa3, 0x2e, 0x86, 0xba, 0xfa, 0x41, 0xe0, 0xf8, 0xa3, 0x9e, 0x22, 0xff, 0xb6, 0x12, 0x86,
+	0x4b, 0xae, 0x9c, 0xf5, 0xa7, 0xce, 0x9a, 0x55, 0xf0, 0x7f, 0x88, 0xbe, 0xf4, 0xf7, 0x4b, 0x2e,
+	0xb5, 0x69, 0x83, 0xb7, 0x1c, 0xa3, 0xa2, 0x25, 0xec, 0x5b, 0xa7, 0x0d, 0x9b, 0xf3, 0x60, 0x2c,
+	0xc7, 0x8d, 0xf4, 0x77, 0x31, 0x33, 0x5b, 0x04, 0x6b, 0x5d, 0x0c, 0xb5, 0x4f, 0x29, 0xd9, 0x0d,
+	0xae, 0x94, 0x13, 0x92, 0x07, 0x77, 0x39, 0x26, 0x9d, 0xea, 0x57, 0x0a, 0xd5, 0x3b, 0xe2, 0xd6,
+	0xd1, 0xf7, 0x50, 0xd4, 0x3b, 0xa0, 0x23, 0xd9, 0x27, 0x09, 0xd9, 0xdd, 0xff, 0x02, 0xef, 0x2c,
+	0xd1, 0x57, 0xb0, 0x1f, 0x91, 0x87, 0x10, 0x47, 0xff, 0xd9, 0x5f, 0x8f, 0xe0, 0x66, 0x96, 0xbe,
+	0x85, 0xbe, 0x49, 0xe0, 0x84, 0x9c, 0xbd, 0xd3, 0x41, 0xb2, 0xbb, 0x83, 0x0f, 0x6f, 0xcd, 0x57,
+	0x6f, 0x80, 0x9e, 0x5f, 0x33, 0xb1, 0x64, 0x53, 0xb1, 0x14, 0xae, 0xfd, 0x22, 0x54, 0xad, 0xbf,
+	0xd3, 0xc7, 0xb0, 0x67, 0x1d, 0x33, 0x2e, 0x44, 0xc9, 0x70, 0x2d, 0x3c, 0x78, 0xae, 0xea, 0x60,
+	0x2f, 0x43, 0x5f, 0x56, 0xbf, 0x09, 0x3c, 0x4a, 0x90, 0xd8, 0x46, 0x2b, 0xcb, 0xef, 0x9d, 0xc9,
+	0x39, 0xf4, 0x59, 0x92, 0x29, 0x32, 0x79, 0x96, 0xec, 0xde, 0x8d, 0x8c, 0xb7, 0x56, 0xaa, 0xaf,
+	0x70, 0x94, 0xe4, 0x5a, 0xb2, 0x96, 0x9e, 0xc1, 0x81, 0x89, 0x01, 0x63, 0x98, 0xa7, 0xff, 0x32,
+	0xb3, 0x81, 0x80, 0xdb, 0x69, 0x0f, 0xd3, 0xe8, 0x95, 0xf3, 0x19, 0xfc, 0x8b, 0xbd, 0x16, 0x2f,
+	0x5e, 0xa7, 0x5f, 0x68, 0x34, 0x5e, 0x40, 0x3f, 0x7d, 0xc1, 0x8a, 0x07, 0x3b, 0x1d, 0x5b, 0x90,
+	0x69, 0x27, 0x5c, 0xfa, 0xf2, 0x4f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x34, 0x7c, 0x36, 0xe6, 0x0d,
+	0x04, 0x00, 0x00,
 }
@@ -29,7 +29,7 @@ func (m *InspectContMsgData) Reset()         { *m = InspectContMsgData{} }
 func (m *InspectContMsgData) String() string { return proto.CompactTextString(m) }
 func (*InspectContMsgData) ProtoMessage()    {}
 func (*InspectContMsgData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_inspectCont_ac05da2e6246e7db, []int{0}
+	return fileDescriptor_inspectCont_52bc157ad683a4be, []int{0}
 }
 func (m *InspectContMsgData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InspectContMsgData.Unmarshal(m, b)
@@ -69,7 +69,7 @@ func (m *InspectContRequest) Reset()         { *m = InspectContRequest{} }
 func (m *InspectContRequest) String() string { return proto.CompactTextString(m) }
 func (*InspectContRequest) ProtoMessage()    {}
 func (*InspectContRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_inspectCont_ac05da2e6246e7db, []int{1}
+	return fileDescriptor_inspectCont_52bc157ad683a4be, []int{1}
 }
 func (m *InspectContRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InspectContRequest.Unmarshal(m, b)
@@ -106,6 +106,7 @@ func (m *InspectContRequest) GetContainerID() string {
 type InspectContResponse struct {
 	InspectContMsgData   *InspectContMsgData `protobuf:"bytes,1,opt,name=InspectContMsgData,proto3" json:"InspectContMsgData,omitempty"`
 	Inspection           string              `protobuf:"bytes,2,opt,name=inspection,proto3" json:"inspection,omitempty"`
+	Error                *Error              `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
 	XXX_unrecognized     []byte              `json:"-"`
 	XXX_sizecache        int32               `json:"-"`
@@ -115,7 +116,7 @@ func (m *InspectContResponse) Reset()         { *m = InspectContResponse{} }
 func (m *InspectContResponse) String() string { return proto.CompactTextString(m) }
 func (*InspectContResponse) ProtoMessage()    {}
 func (*InspectContResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_inspectCont_ac05da2e6246e7db, []int{2}
+	return fileDescriptor_inspectCont_52bc157ad683a4be, []int{2}
 }
 func (m *InspectContResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InspectContResponse.Unmarshal(m, b)
@@ -149,16 +150,23 @@ func (m *InspectContResponse) GetInspection() string {
 	return ""
 }
 
+func (m *InspectContResponse) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*InspectContMsgData)(nil), "protomsgs.InspectContMsgData")
 	proto.RegisterType((*InspectContRequest)(nil), "protomsgs.InspectContRequest")
 	proto.RegisterType((*InspectContResponse)(nil), "protomsgs.InspectContResponse")
 }
 
-func init() { proto.RegisterFile("inspectCont.proto", fileDescriptor_inspectCont_ac05da2e6246e7db) }
+func init() { proto.RegisterFile("inspectCont.proto", fileDescriptor_inspectCont_52bc157ad683a4be) }
 
-var fileDescriptor_inspectCont_ac05da2e6246e7db = []byte{
-	// 190 bytes of a gzipped FileDescriptorProto
+var fileDescriptor_inspectCont_52bc157ad683a4be = []byte{
+	// 212 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0xcc, 0xcc, 0x2b, 0x2e,
 	0x48, 0x4d, 0x2e, 0x71, 0xce, 0xcf, 0x2b, 0xd1, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x04,
 	0x53, 0xb9, 0xc5, 0xe9, 0xc5, 0x52, 0x3c, 0xc9, 0xf9, 0xb9, 0xb9, 0xf9, 0x79, 0x10, 0x09, 0x25,
@@ -167,8 +175,10 @@ var fileDescriptor_inspectCont_ac05da2e6246e7db = []byte{
 	0x48, 0x4c, 0x0f, 0x6e, 0x88, 0x9e, 0x2f, 0x42, 0x36, 0x08, 0x59, 0xa9, 0x52, 0x2b, 0x23, 0x8a,
 	0x81, 0x41, 0xa9, 0x85, 0xa5, 0xa9, 0xc5, 0x25, 0x42, 0xbe, 0xd8, 0xac, 0x81, 0x9a, 0x2b, 0x8b,
 	0x64, 0x2e, 0xa6, 0xa2, 0x20, 0x6c, 0xee, 0x53, 0xe0, 0xe2, 0x4e, 0xce, 0xcf, 0x2b, 0x49, 0xcc,
-	0xcc, 0x4b, 0x2d, 0xf2, 0x74, 0x91, 0x60, 0x52, 0x60, 0xd4, 0xe0, 0x0c, 0x42, 0x16, 0x52, 0x6a,
-	0x61, 0xe4, 0x12, 0x46, 0x71, 0x47, 0x71, 0x41, 0x7e, 0x5e, 0x71, 0x2a, 0xb5, 0x1d, 0x22, 0xc7,
-	0xc5, 0x05, 0x0d, 0xec, 0xcc, 0xfc, 0x3c, 0xa8, 0x3b, 0x90, 0x44, 0x92, 0xd8, 0xc0, 0x26, 0x1a,
-	0x03, 0x02, 0x00, 0x00, 0xff, 0xff, 0x79, 0x50, 0x63, 0x26, 0x93, 0x01, 0x00, 0x00,
+	0xcc, 0x4b, 0x2d, 0xf2, 0x74, 0x91, 0x60, 0x52, 0x60, 0xd4, 0xe0, 0x0c, 0x42, 0x16, 0x52, 0x5a,
+	0xc3, 0xc8, 0x25, 0x8c, 0xe2, 0x8e, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54, 0x6a, 0x3b, 0x44, 0x8e,
+	0x8b, 0x0b, 0x1a, 0xd8, 0x99, 0xf9, 0x79, 0x50, 0x77, 0x20, 0x89, 0x08, 0xa9, 0x71, 0xb1, 0xa6,
+	0x16, 0x15, 0xe5, 0x17, 0x49, 0x30, 0x83, 0x6d, 0x10, 0x40, 0xb2, 0xc1, 0x15, 0x24, 0x1e, 0x04,
+	0x91, 0x4e, 0x62, 0x03, 0x8b, 0x1b, 0x03, 0x02, 0x00, 0x00, 0xff, 0xff, 0xfe, 0xcb, 0xc3, 0x4e,
+	0xbb, 0x01, 0x00, 0x00,
 }
@@ -29,7 +29,7 @@ func (m *ListContainersMsgData) Reset()         { *m = ListContainersMsgData{} }
 func (m *ListContainersMsgData) String() string { return proto.CompactTextString(m) }
 func (*ListContainersMsgData) ProtoMessage()    {}
 func (*ListContainersMsgData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_listContainers_6899703f933438ff, []int{0}
+	return fileDescriptor_listContainers_55f88a436e0b7f79, []int{0}
 }
 func (m *ListContainersMsgData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListContainersMsgData.Unmarshal(m, b)
@@ -69,7 +69,7 @@ func (m *ListContainersRequest) Reset()         { *m = ListContainersRequest{} }
 func (m *ListContainersRequest) String() string { return proto.CompactTextString(m) }
 func (*ListContainersRequest) ProtoMessage()    {}
 func (*ListContainersRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_listContainers_6899703f933438ff, []int{1}
+	return fileDescriptor_listContainers_55f88a436e0b7f79, []int{1}
 }
 func (m *ListContainersRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListContainersRequest.Unmarshal(m, b)
@@ -106,6 +106,7 @@ func (m *ListContainersRequest) GetPubKey() string {
 type ListContainersResponse struct {
 	ListContainersMsgData *ListContainersMsgData `protobuf:"bytes,1,opt,name=ListContainersMsgData,proto3" json:"ListContainersMsgData,omitempty"`
 	ListResult            string                 `protobuf:"bytes,2,opt,name=listResult,proto3" json:"listResult,omitempty"`
+	Error                 *Error                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	XXX_NoUnkeyedLiteral  struct{}               `json:"-"`
 	XXX_unrecognized      []byte                 `json:"-"`
 	XXX_sizecache         int32                  `json:"-"`
@@ -115,7 +116,7 @@ func (m *ListContainersResponse) Reset()         { *m = ListContainersResponse{}
 func (m *ListContainersResponse) String() string { return proto.CompactTextString(m) }
 func (*ListContainersResponse) ProtoMessage()    {}
 func (*ListContainersResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_listContainers_6899703f933438ff, []int{2}
+	return fileDescriptor_listContainers_55f88a436e0b7f79, []int{2}
 }
 func (m *ListContainersResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListContainersResponse.Unmarshal(m, b)
@@ -149,6 +150,13 @@ func (m *ListContainersResponse) GetListResult() string {
 	return ""
 }
 
+func (m *ListContainersResponse) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*ListContainersMsgData)(nil), "protomsgs.ListContainersMsgData")
 	proto.RegisterType((*ListContainersRequest)(nil), "protomsgs.ListContainersRequest")
@@ -156,11 +164,11 @@ func init() {
 }
 
 func init() {
-	proto.RegisterFile("listContainers.proto", fileDescriptor_listContainers_6899703f933438ff)
+	proto.RegisterFile("listContainers.proto", fileDescriptor_listContainers_55f88a436e0b7f79)
 }
 
-var fileDescriptor_listContainers_6899703f933438ff = []byte{
-	// 191 bytes of a gzipped FileDescriptorProto
+var fileDescriptor_listContainers_55f88a436e0b7f79 = []byte{
+	// 213 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0xc9, 0xc9, 0x2c, 0x2e,
 	0x71, 0xce, 0xcf, 0x2b, 0x49, 0xcc, 0xcc, 0x4b, 0x2d, 0x2a, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9,
 	0x17, 0xe2, 0x04, 0x53, 0xb9, 0xc5, 0xe9, 0xc5, 0x52, 0x3c, 0xc9, 0xf9, 0xb9, 0xb9, 0xf9, 0x79,
@@ -169,8 +177,10 @@ var fileDescriptor_listContainers_6899703f933438ff = []byte{
 	0xa3, 0x06, 0xb7, 0x91, 0x98, 0x1e, 0xdc, 0x1c, 0x3d, 0x5f, 0x84, 0x6c, 0x10, 0xb2, 0x52, 0xa5,
 	0x76, 0x46, 0x74, 0x33, 0x83, 0x52, 0x0b, 0x4b, 0x53, 0x8b, 0x4b, 0x84, 0xc2, 0x70, 0x58, 0x06,
 	0x35, 0x5d, 0x01, 0xc9, 0x74, 0xac, 0xea, 0x82, 0x70, 0xb8, 0x55, 0x8c, 0x8b, 0xad, 0xa0, 0x34,
-	0xc9, 0x3b, 0xb5, 0x52, 0x82, 0x49, 0x81, 0x51, 0x83, 0x33, 0x08, 0xca, 0x53, 0x9a, 0xc0, 0xc8,
+	0xc9, 0x3b, 0xb5, 0x52, 0x82, 0x49, 0x81, 0x51, 0x83, 0x33, 0x08, 0xca, 0x53, 0xda, 0xc1, 0xc8,
 	0x25, 0x86, 0xee, 0x92, 0xe2, 0x82, 0xfc, 0xbc, 0xe2, 0x54, 0x9a, 0x39, 0x45, 0x8e, 0x8b, 0x0b,
-	0x14, 0x01, 0x41, 0xa9, 0xc5, 0xa5, 0x39, 0x25, 0x50, 0xe7, 0x20, 0x89, 0x24, 0xb1, 0x81, 0xcd,
-	0x35, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0xd0, 0xaa, 0xc0, 0xc7, 0xa7, 0x01, 0x00, 0x00,
+	0x14, 0x01, 0x41, 0xa9, 0xc5, 0xa5, 0x39, 0x25, 0x50, 0xe7, 0x20, 0x89, 0x08, 0xa9, 0x71, 0xb1,
+	0xa6, 0x16, 0x15, 0xe5, 0x17, 0x49, 0x30, 0x83, 0xed, 0x11, 0x40, 0xb2, 0xc7, 0x15, 0x24, 0x1e,
+	0x04, 0x91, 0x4e, 0x62, 0x03, 0x8b, 0x1b, 0x03, 0x02, 0x00, 0x00, 0xff, 0xff, 0x0a, 0x58, 0xbe,
+	0xe2, 0xcf, 0x01, 0x00, 0x00,
 }
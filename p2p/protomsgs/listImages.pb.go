@@ -29,7 +29,7 @@ func (m *ListImagesMsgData) Reset()         { *m = ListImagesMsgData{} }
 func (m *ListImagesMsgData) String() string { return proto.CompactTextString(m) }
 func (*ListImagesMsgData) ProtoMessage()    {}
 func (*ListImagesMsgData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_listImages_5de7a879170bb7e4, []int{0}
+	return fileDescriptor_listImages_9329735295e4d92f, []int{0}
 }
 func (m *ListImagesMsgData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListImagesMsgData.Unmarshal(m, b)
@@ -69,7 +69,7 @@ func (m *ListImagesRequest) Reset()         { *m = ListImagesRequest{} }
 func (m *ListImagesRequest) String() string { return proto.CompactTextString(m) }
 func (*ListImagesRequest) ProtoMessage()    {}
 func (*ListImagesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_listImages_5de7a879170bb7e4, []int{1}
+	return fileDescriptor_listImages_9329735295e4d92f, []int{1}
 }
 func (m *ListImagesRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListImagesRequest.Unmarshal(m, b)
@@ -106,6 +106,7 @@ func (m *ListImagesRequest) GetPubKey() string {
 type ListImagesResponse struct {
 	ListImagesMsgData    *ListImagesMsgData `protobuf:"bytes,1,opt,name=ListImagesMsgData,proto3" json:"ListImagesMsgData,omitempty"`
 	ListResult           string             `protobuf:"bytes,2,opt,name=listResult,proto3" json:"listResult,omitempty"`
+	Error                *Error             `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
 	XXX_unrecognized     []byte             `json:"-"`
 	XXX_sizecache        int32              `json:"-"`
@@ -115,7 +116,7 @@ func (m *ListImagesResponse) Reset()         { *m = ListImagesResponse{} }
 func (m *ListImagesResponse) String() string { return proto.CompactTextString(m) }
 func (*ListImagesResponse) ProtoMessage()    {}
 func (*ListImagesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_listImages_5de7a879170bb7e4, []int{2}
+	return fileDescriptor_listImages_9329735295e4d92f, []int{2}
 }
 func (m *ListImagesResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ListImagesResponse.Unmarshal(m, b)
@@ -149,16 +150,23 @@ func (m *ListImagesResponse) GetListResult() string {
 	return ""
 }
 
+func (m *ListImagesResponse) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*ListImagesMsgData)(nil), "protomsgs.ListImagesMsgData")
 	proto.RegisterType((*ListImagesRequest)(nil), "protomsgs.ListImagesRequest")
 	proto.RegisterType((*ListImagesResponse)(nil), "protomsgs.ListImagesResponse")
 }
 
-func init() { proto.RegisterFile("listImages.proto", fileDescriptor_listImages_5de7a879170bb7e4) }
+func init() { proto.RegisterFile("listImages.proto", fileDescriptor_listImages_9329735295e4d92f) }
 
-var fileDescriptor_listImages_5de7a879170bb7e4 = []byte{
-	// 185 bytes of a gzipped FileDescriptorProto
+var fileDescriptor_listImages_9329735295e4d92f = []byte{
+	// 207 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0xc8, 0xc9, 0x2c, 0x2e,
 	0xf1, 0xcc, 0x4d, 0x4c, 0x4f, 0x2d, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x04, 0x53,
 	0xb9, 0xc5, 0xe9, 0xc5, 0x52, 0x3c, 0xc9, 0xf9, 0xb9, 0xb9, 0xf9, 0x79, 0x10, 0x09, 0x25, 0x5f,
@@ -167,8 +175,9 @@ var fileDescriptor_listImages_5de7a879170bb7e4 = []byte{
 	0x4c, 0x0f, 0x6e, 0x86, 0x9e, 0x2f, 0x42, 0x36, 0x08, 0x59, 0xa9, 0x52, 0x39, 0xb2, 0x71, 0x41,
 	0xa9, 0x85, 0xa5, 0xa9, 0xc5, 0x25, 0x42, 0x5e, 0x58, 0xec, 0x80, 0x1a, 0x2a, 0x83, 0x64, 0x28,
 	0x86, 0x9a, 0x20, 0x2c, 0x4e, 0x13, 0xe3, 0x62, 0x2b, 0x28, 0x4d, 0xf2, 0x4e, 0xad, 0x94, 0x60,
-	0x52, 0x60, 0xd4, 0xe0, 0x0c, 0x82, 0xf2, 0x94, 0x1a, 0x18, 0xb9, 0x84, 0x90, 0x6d, 0x2e, 0x2e,
-	0xc8, 0xcf, 0x2b, 0x4e, 0xa5, 0xaa, 0xd5, 0x72, 0x5c, 0x5c, 0xa0, 0x70, 0x0d, 0x4a, 0x2d, 0x2e,
-	0xcd, 0x29, 0x81, 0x5a, 0x8f, 0x24, 0x92, 0xc4, 0x06, 0x36, 0xcf, 0x18, 0x10, 0x00, 0x00, 0xff,
-	0xff, 0x5b, 0xf5, 0x41, 0xbf, 0x7e, 0x01, 0x00, 0x00,
+	0x52, 0x60, 0xd4, 0xe0, 0x0c, 0x82, 0xf2, 0x94, 0x56, 0x30, 0x72, 0x09, 0x21, 0xdb, 0x5c, 0x5c,
+	0x90, 0x9f, 0x57, 0x9c, 0x4a, 0x55, 0xab, 0xe5, 0xb8, 0xb8, 0x40, 0xe1, 0x1a, 0x94, 0x5a, 0x5c,
+	0x9a, 0x53, 0x02, 0xb5, 0x1e, 0x49, 0x44, 0x48, 0x8d, 0x8b, 0x35, 0xb5, 0xa8, 0x28, 0xbf, 0x48,
+	0x82, 0x19, 0x6c, 0xbe, 0x00, 0x92, 0xf9, 0xae, 0x20, 0xf1, 0x20, 0x88, 0x74, 0x12, 0x1b, 0x58,
+	0xdc, 0x18, 0x10, 0x00, 0x00, 0xff, 0xff, 0x91, 0xed, 0x22, 0xd9, 0xa6, 0x01, 0x00, 0x00,
 }
@@ -0,0 +1,101 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: membership.proto
+
+package protomsgs
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// MembershipList holds the peers allowed to connect to a private network. It is signed by the network's admin.
+type MembershipList struct {
+	Members              []string `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	Version              int64    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	AdminPubKey          []byte   `protobuf:"bytes,3,opt,name=adminPubKey,proto3" json:"adminPubKey,omitempty"`
+	Sign                 []byte   `protobuf:"bytes,4,opt,name=sign,proto3" json:"sign,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MembershipList) Reset()         { *m = MembershipList{} }
+func (m *MembershipList) String() string { return proto.CompactTextString(m) }
+func (*MembershipList) ProtoMessage()    {}
+func (*MembershipList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_membership_dc8980d464138d33, []int{0}
+}
+func (m *MembershipList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MembershipList.Unmarshal(m, b)
+}
+func (m *MembershipList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MembershipList.Marshal(b, m, deterministic)
+}
+func (dst *MembershipList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MembershipList.Merge(dst, src)
+}
+func (m *MembershipList) XXX_Size() int {
+	return xxx_messageInfo_MembershipList.Size(m)
+}
+func (m *MembershipList) XXX_DiscardUnknown() {
+	xxx_messageInfo_MembershipList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MembershipList proto.InternalMessageInfo
+
+func (m *MembershipList) GetMembers() []string {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *MembershipList) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *MembershipList) GetAdminPubKey() []byte {
+	if m != nil {
+		return m.AdminPubKey
+	}
+	return nil
+}
+
+func (m *MembershipList) GetSign() []byte {
+	if m != nil {
+		return m.Sign
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*MembershipList)(nil), "protomsgs.MembershipList")
+}
+
+func init() { proto.RegisterFile("membership.proto", fileDescriptor_membership_dc8980d464138d33) }
+
+var fileDescriptor_membership_dc8980d464138d33 = []byte{
+	// 136 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0xc8, 0x4d, 0xcd, 0x4d,
+	0x4a, 0x2d, 0x2a, 0xce, 0xc8, 0x2c, 0xd0, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x04, 0x53,
+	0xb9, 0xc5, 0xe9, 0xc5, 0x4a, 0x55, 0x5c, 0x7c, 0xbe, 0x70, 0x69, 0x9f, 0xcc, 0xe2, 0x12, 0x21,
+	0x09, 0x2e, 0x76, 0xa8, 0x06, 0x09, 0x46, 0x05, 0x66, 0x0d, 0xce, 0x20, 0x18, 0x17, 0x24, 0x53,
+	0x96, 0x5a, 0x54, 0x9c, 0x99, 0x9f, 0x27, 0xc1, 0xa4, 0xc0, 0xa8, 0xc1, 0x1c, 0x04, 0xe3, 0x0a,
+	0x29, 0x70, 0x71, 0x27, 0xa6, 0xe4, 0x66, 0xe6, 0x05, 0x94, 0x26, 0x79, 0xa7, 0x56, 0x4a, 0x30,
+	0x2b, 0x30, 0x6a, 0xf0, 0x04, 0x21, 0x0b, 0x09, 0x09, 0x71, 0xb1, 0x14, 0x67, 0xa6, 0xe7, 0x49,
+	0xb0, 0x80, 0xa5, 0xc0, 0xec, 0x24, 0x36, 0xb0, 0x33, 0x8c, 0x01, 0x01, 0x00, 0x00, 0xff, 0xff,
+	0x82, 0xf7, 0x5d, 0x6d, 0xa1, 0x00, 0x00, 0x00,
+}
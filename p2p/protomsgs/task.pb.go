@@ -29,7 +29,7 @@ func (m *RunImageMsgData) Reset()         { *m = RunImageMsgData{} }
 func (m *RunImageMsgData) String() string { return proto.CompactTextString(m) }
 func (*RunImageMsgData) ProtoMessage()    {}
 func (*RunImageMsgData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_task_041d40b8f09557a3, []int{0}
+	return fileDescriptor_task_e0d263d753e26d41, []int{0}
 }
 func (m *RunImageMsgData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RunImageMsgData.Unmarshal(m, b)
@@ -60,6 +60,8 @@ func (m *RunImageMsgData) GetMessageData() *MessageData {
 type RunRequest struct {
 	RunImageMsgData      *RunImageMsgData `protobuf:"bytes,1,opt,name=RunImageMsgData,proto3" json:"RunImageMsgData,omitempty"`
 	ImageID              string           `protobuf:"bytes,2,opt,name=imageID,proto3" json:"imageID,omitempty"`
+	Spec                 *JobSpec         `protobuf:"bytes,3,opt,name=spec,proto3" json:"spec,omitempty"`
+	Owner                string           `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
 	XXX_sizecache        int32            `json:"-"`
@@ -69,7 +71,7 @@ func (m *RunRequest) Reset()         { *m = RunRequest{} }
 func (m *RunRequest) String() string { return proto.CompactTextString(m) }
 func (*RunRequest) ProtoMessage()    {}
 func (*RunRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_task_041d40b8f09557a3, []int{1}
+	return fileDescriptor_task_e0d263d753e26d41, []int{1}
 }
 func (m *RunRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RunRequest.Unmarshal(m, b)
@@ -103,9 +105,112 @@ func (m *RunRequest) GetImageID() string {
 	return ""
 }
 
+func (m *RunRequest) GetSpec() *JobSpec {
+	if m != nil {
+		return m.Spec
+	}
+	return nil
+}
+
+func (m *RunRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+// JobSpec describes how a job runs in its container.
+// Unset fields keep the defaults of the image.
+type JobSpec struct {
+	Command              []string         `protobuf:"bytes,1,rep,name=command,proto3" json:"command,omitempty"`
+	Args                 []string         `protobuf:"bytes,2,rep,name=args,proto3" json:"args,omitempty"`
+	Env                  []string         `protobuf:"bytes,3,rep,name=env,proto3" json:"env,omitempty"`
+	WorkDir              string           `protobuf:"bytes,4,opt,name=workDir,proto3" json:"workDir,omitempty"`
+	User                 string           `protobuf:"bytes,5,opt,name=user,proto3" json:"user,omitempty"`
+	Labels               []string         `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty"`
+	Requirements         *JobRequirements `protobuf:"bytes,7,opt,name=requirements,proto3" json:"requirements,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *JobSpec) Reset()         { *m = JobSpec{} }
+func (m *JobSpec) String() string { return proto.CompactTextString(m) }
+func (*JobSpec) ProtoMessage()    {}
+func (*JobSpec) Descriptor() ([]byte, []int) {
+	return fileDescriptor_task_e0d263d753e26d41, []int{2}
+}
+func (m *JobSpec) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JobSpec.Unmarshal(m, b)
+}
+func (m *JobSpec) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JobSpec.Marshal(b, m, deterministic)
+}
+func (dst *JobSpec) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JobSpec.Merge(dst, src)
+}
+func (m *JobSpec) XXX_Size() int {
+	return xxx_messageInfo_JobSpec.Size(m)
+}
+func (m *JobSpec) XXX_DiscardUnknown() {
+	xxx_messageInfo_JobSpec.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JobSpec proto.InternalMessageInfo
+
+func (m *JobSpec) GetCommand() []string {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (m *JobSpec) GetArgs() []string {
+	if m != nil {
+		return m.Args
+	}
+	return nil
+}
+
+func (m *JobSpec) GetEnv() []string {
+	if m != nil {
+		return m.Env
+	}
+	return nil
+}
+
+func (m *JobSpec) GetWorkDir() string {
+	if m != nil {
+		return m.WorkDir
+	}
+	return ""
+}
+
+func (m *JobSpec) GetUser() string {
+	if m != nil {
+		return m.User
+	}
+	return ""
+}
+
+func (m *JobSpec) GetLabels() []string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *JobSpec) GetRequirements() *JobRequirements {
+	if m != nil {
+		return m.Requirements
+	}
+	return nil
+}
+
 type RunResponse struct {
 	RunImageMsgData      *RunImageMsgData `protobuf:"bytes,1,opt,name=RunImageMsgData,proto3" json:"RunImageMsgData,omitempty"`
 	ContainerID          string           `protobuf:"bytes,2,opt,name=containerID,proto3" json:"containerID,omitempty"`
+	Error                *Error           `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
 	XXX_unrecognized     []byte           `json:"-"`
 	XXX_sizecache        int32            `json:"-"`
@@ -115,7 +220,7 @@ func (m *RunResponse) Reset()         { *m = RunResponse{} }
 func (m *RunResponse) String() string { return proto.CompactTextString(m) }
 func (*RunResponse) ProtoMessage()    {}
 func (*RunResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_task_041d40b8f09557a3, []int{2}
+	return fileDescriptor_task_e0d263d753e26d41, []int{3}
 }
 func (m *RunResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_RunResponse.Unmarshal(m, b)
@@ -149,26 +254,233 @@ func (m *RunResponse) GetContainerID() string {
 	return ""
 }
 
+func (m *RunResponse) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+// CancelRequest asks the worker of a job to stop it
+type CancelRequest struct {
+	RunImageMsgData      *RunImageMsgData `protobuf:"bytes,1,opt,name=RunImageMsgData,proto3" json:"RunImageMsgData,omitempty"`
+	JobID                string           `protobuf:"bytes,2,opt,name=jobID,proto3" json:"jobID,omitempty"`
+	Owner                string           `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *CancelRequest) Reset()         { *m = CancelRequest{} }
+func (m *CancelRequest) String() string { return proto.CompactTextString(m) }
+func (*CancelRequest) ProtoMessage()    {}
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_task_e0d263d753e26d41, []int{4}
+}
+func (m *CancelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelRequest.Unmarshal(m, b)
+}
+func (m *CancelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelRequest.Marshal(b, m, deterministic)
+}
+func (dst *CancelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelRequest.Merge(dst, src)
+}
+func (m *CancelRequest) XXX_Size() int {
+	return xxx_messageInfo_CancelRequest.Size(m)
+}
+func (m *CancelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelRequest proto.InternalMessageInfo
+
+func (m *CancelRequest) GetRunImageMsgData() *RunImageMsgData {
+	if m != nil {
+		return m.RunImageMsgData
+	}
+	return nil
+}
+
+func (m *CancelRequest) GetJobID() string {
+	if m != nil {
+		return m.JobID
+	}
+	return ""
+}
+
+func (m *CancelRequest) GetOwner() string {
+	if m != nil {
+		return m.Owner
+	}
+	return ""
+}
+
+type CancelResponse struct {
+	RunImageMsgData      *RunImageMsgData `protobuf:"bytes,1,opt,name=RunImageMsgData,proto3" json:"RunImageMsgData,omitempty"`
+	JobID                string           `protobuf:"bytes,2,opt,name=jobID,proto3" json:"jobID,omitempty"`
+	Error                *Error           `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *CancelResponse) Reset()         { *m = CancelResponse{} }
+func (m *CancelResponse) String() string { return proto.CompactTextString(m) }
+func (*CancelResponse) ProtoMessage()    {}
+func (*CancelResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_task_e0d263d753e26d41, []int{5}
+}
+func (m *CancelResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CancelResponse.Unmarshal(m, b)
+}
+func (m *CancelResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CancelResponse.Marshal(b, m, deterministic)
+}
+func (dst *CancelResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CancelResponse.Merge(dst, src)
+}
+func (m *CancelResponse) XXX_Size() int {
+	return xxx_messageInfo_CancelResponse.Size(m)
+}
+func (m *CancelResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CancelResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CancelResponse proto.InternalMessageInfo
+
+func (m *CancelResponse) GetRunImageMsgData() *RunImageMsgData {
+	if m != nil {
+		return m.RunImageMsgData
+	}
+	return nil
+}
+
+func (m *CancelResponse) GetJobID() string {
+	if m != nil {
+		return m.JobID
+	}
+	return ""
+}
+
+func (m *CancelResponse) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
+// JobStatus notifies the requester of a job that the job ended
+type JobStatus struct {
+	RunImageMsgData      *RunImageMsgData `protobuf:"bytes,1,opt,name=RunImageMsgData,proto3" json:"RunImageMsgData,omitempty"`
+	JobID                string           `protobuf:"bytes,2,opt,name=jobID,proto3" json:"jobID,omitempty"`
+	State                string           `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	Error                string           `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	ExitCode             int32            `protobuf:"varint,5,opt,name=exitCode,proto3" json:"exitCode,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *JobStatus) Reset()         { *m = JobStatus{} }
+func (m *JobStatus) String() string { return proto.CompactTextString(m) }
+func (*JobStatus) ProtoMessage()    {}
+func (*JobStatus) Descriptor() ([]byte, []int) {
+	return fileDescriptor_task_e0d263d753e26d41, []int{6}
+}
+func (m *JobStatus) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JobStatus.Unmarshal(m, b)
+}
+func (m *JobStatus) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JobStatus.Marshal(b, m, deterministic)
+}
+func (dst *JobStatus) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JobStatus.Merge(dst, src)
+}
+func (m *JobStatus) XXX_Size() int {
+	return xxx_messageInfo_JobStatus.Size(m)
+}
+func (m *JobStatus) XXX_DiscardUnknown() {
+	xxx_messageInfo_JobStatus.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JobStatus proto.InternalMessageInfo
+
+func (m *JobStatus) GetRunImageMsgData() *RunImageMsgData {
+	if m != nil {
+		return m.RunImageMsgData
+	}
+	return nil
+}
+
+func (m *JobStatus) GetJobID() string {
+	if m != nil {
+		return m.JobID
+	}
+	return ""
+}
+
+func (m *JobStatus) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *JobStatus) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *JobStatus) GetExitCode() int32 {
+	if m != nil {
+		return m.ExitCode
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*RunImageMsgData)(nil), "protomsgs.RunImageMsgData")
 	proto.RegisterType((*RunRequest)(nil), "protomsgs.RunRequest")
+	proto.RegisterType((*JobSpec)(nil), "protomsgs.JobSpec")
 	proto.RegisterType((*RunResponse)(nil), "protomsgs.RunResponse")
+	proto.RegisterType((*CancelRequest)(nil), "protomsgs.CancelRequest")
+	proto.RegisterType((*CancelResponse)(nil), "protomsgs.CancelResponse")
+	proto.RegisterType((*JobStatus)(nil), "protomsgs.JobStatus")
 }
 
-func init() { proto.RegisterFile("task.proto", fileDescriptor_task_041d40b8f09557a3) }
-
-var fileDescriptor_task_041d40b8f09557a3 = []byte{
-	// 185 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2a, 0x49, 0x2c, 0xce,
-	0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x04, 0x53, 0xb9, 0xc5, 0xe9, 0xc5, 0x52, 0x3c,
-	0xc9, 0xf9, 0xb9, 0xb9, 0xf9, 0x79, 0x10, 0x09, 0x25, 0x6f, 0x2e, 0xfe, 0xa0, 0xd2, 0x3c, 0xcf,
-	0xdc, 0xc4, 0xf4, 0x54, 0xdf, 0xe2, 0x74, 0x97, 0xc4, 0x92, 0x44, 0x21, 0x0b, 0x2e, 0xee, 0xdc,
-	0xd4, 0xe2, 0xe2, 0xc4, 0xf4, 0x54, 0x10, 0x57, 0x82, 0x51, 0x81, 0x51, 0x83, 0xdb, 0x48, 0x4c,
-	0x0f, 0x6e, 0x82, 0x9e, 0x2f, 0x42, 0x36, 0x08, 0x59, 0xa9, 0x52, 0x0e, 0x17, 0x57, 0x50, 0x69,
-	0x5e, 0x50, 0x6a, 0x61, 0x69, 0x6a, 0x71, 0x89, 0x90, 0x0b, 0x86, 0xd1, 0x50, 0xb3, 0xa4, 0x90,
-	0xcc, 0x42, 0x53, 0x11, 0x84, 0xe1, 0x1a, 0x09, 0x2e, 0xf6, 0x4c, 0x10, 0xdf, 0xd3, 0x45, 0x82,
-	0x49, 0x81, 0x51, 0x83, 0x33, 0x08, 0xc6, 0x55, 0x2a, 0xe5, 0xe2, 0x06, 0xdb, 0x56, 0x5c, 0x90,
-	0x9f, 0x57, 0x9c, 0x4a, 0x25, 0xeb, 0x14, 0xb8, 0xb8, 0x93, 0xf3, 0xf3, 0x4a, 0x12, 0x33, 0xf3,
-	0x52, 0x8b, 0xe0, 0x56, 0x22, 0x0b, 0x25, 0xb1, 0x81, 0x4d, 0x33, 0x06, 0x04, 0x00, 0x00, 0xff,
-	0xff, 0x78, 0xd3, 0x3a, 0xbe, 0x5f, 0x01, 0x00, 0x00,
+func init() { proto.RegisterFile("task.proto", fileDescriptor_task_e0d263d753e26d41) }
+
+var fileDescriptor_task_e0d263d753e26d41 = []byte{
+	// 437 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x53, 0xbd, 0x72, 0xd4, 0x30,
+	0x10, 0x1e, 0xc5, 0xe7, 0x3b, 0x6e, 0x2f, 0x90, 0x8c, 0x26, 0x93, 0xd1, 0x5c, 0xe5, 0x71, 0x91,
+	0x49, 0x75, 0x05, 0x34, 0x54, 0x34, 0x31, 0x45, 0xc2, 0xa4, 0x11, 0x4f, 0x20, 0xfb, 0x76, 0x3c,
+	0x26, 0x67, 0xc9, 0xd1, 0xca, 0x09, 0x94, 0xbc, 0x03, 0x2d, 0x2f, 0x40, 0xc3, 0xa3, 0xf0, 0x4a,
+	0x8c, 0xe4, 0x9f, 0x18, 0x8e, 0x82, 0xe2, 0x52, 0x59, 0xdf, 0xb7, 0xab, 0x4f, 0xdf, 0x7c, 0xbb,
+	0x06, 0x70, 0x8a, 0xee, 0x36, 0x8d, 0x35, 0xce, 0xf0, 0x65, 0xf8, 0xd4, 0x54, 0xd2, 0xfa, 0xb8,
+	0x30, 0x75, 0x6d, 0x74, 0x57, 0x58, 0x9f, 0x6c, 0x2b, 0x2a, 0xcc, 0x03, 0xda, 0x2f, 0x1d, 0x91,
+	0x7e, 0x80, 0x13, 0xd9, 0xea, 0xeb, 0x5a, 0x95, 0x78, 0x4b, 0x65, 0xa6, 0x9c, 0xe2, 0x6f, 0x61,
+	0x55, 0x23, 0x91, 0x2a, 0xd1, 0x43, 0xc1, 0x12, 0x76, 0xb9, 0x7a, 0x7d, 0xbe, 0x19, 0x25, 0x37,
+	0xb7, 0x4f, 0x55, 0x39, 0x6d, 0x4d, 0x7f, 0x30, 0x00, 0xd9, 0x6a, 0x89, 0xf7, 0x2d, 0x92, 0xe3,
+	0xd9, 0x9e, 0x76, 0x2f, 0xb6, 0x9e, 0x88, 0xfd, 0xd5, 0x21, 0xf7, 0xec, 0x08, 0x58, 0x54, 0x1e,
+	0x5f, 0x67, 0xe2, 0x28, 0x61, 0x97, 0x4b, 0x39, 0x40, 0x7e, 0x01, 0x33, 0x6a, 0xb0, 0x10, 0x51,
+	0x10, 0xe5, 0x13, 0xd1, 0x1b, 0x93, 0x7f, 0x6c, 0xb0, 0x90, 0xa1, 0xce, 0xcf, 0x20, 0x36, 0x8f,
+	0x1a, 0xad, 0x98, 0x85, 0xfb, 0x1d, 0x48, 0x7f, 0x31, 0x58, 0xf4, 0x7d, 0xfe, 0x0d, 0x1f, 0x93,
+	0xd2, 0x5b, 0xc1, 0x92, 0xc8, 0xbf, 0xd1, 0x43, 0xce, 0x61, 0xa6, 0x6c, 0x49, 0xe2, 0x28, 0xd0,
+	0xe1, 0xcc, 0x4f, 0x21, 0x42, 0xfd, 0x20, 0xa2, 0x40, 0xf9, 0xa3, 0xbf, 0xff, 0x68, 0xec, 0x5d,
+	0x56, 0x0d, 0x6f, 0x0c, 0xd0, 0xdf, 0x6f, 0x09, 0xad, 0x88, 0x03, 0x1d, 0xce, 0xfc, 0x1c, 0xe6,
+	0x3b, 0x95, 0xe3, 0x8e, 0xc4, 0x3c, 0x48, 0xf4, 0x88, 0xbf, 0x83, 0x63, 0x8b, 0xf7, 0x6d, 0x65,
+	0xb1, 0x46, 0xed, 0x48, 0x2c, 0xf6, 0xc2, 0xba, 0x31, 0xb9, 0x9c, 0x74, 0xc8, 0x3f, 0xfa, 0xd3,
+	0xef, 0x0c, 0x56, 0x21, 0x7e, 0x6a, 0x8c, 0x26, 0x3c, 0x50, 0xfe, 0x09, 0xac, 0x0a, 0xa3, 0x9d,
+	0xaa, 0x34, 0xda, 0x71, 0x06, 0x53, 0x8a, 0x5f, 0x40, 0x8c, 0xd6, 0x1a, 0xdb, 0x0f, 0xe2, 0x74,
+	0xa2, 0xfe, 0xde, 0xf3, 0xb2, 0x2b, 0xa7, 0x5f, 0x19, 0xbc, 0xbc, 0x52, 0xba, 0xc0, 0xdd, 0x61,
+	0x37, 0xe4, 0x0c, 0xe2, 0x4f, 0x26, 0x1f, 0xbd, 0x75, 0xe0, 0x69, 0xea, 0xd1, 0x74, 0xea, 0xdf,
+	0x18, 0xbc, 0x1a, 0x3c, 0x1c, 0x34, 0xa6, 0x7f, 0x9b, 0xf8, 0xdf, 0x68, 0x7e, 0x32, 0x58, 0xfa,
+	0x65, 0x74, 0xca, 0xb5, 0xf4, 0xdc, 0xb1, 0x90, 0x53, 0x0e, 0x87, 0x58, 0x02, 0xf0, 0x6c, 0xe7,
+	0xb3, 0xff, 0x45, 0x02, 0xe0, 0x6b, 0x78, 0x81, 0x9f, 0x2b, 0x77, 0x65, 0xb6, 0x18, 0x16, 0x38,
+	0x96, 0x23, 0xce, 0xe7, 0xc1, 0xc9, 0x9b, 0xdf, 0x01, 0x00, 0x00, 0xff, 0xff, 0xa8, 0xaf, 0xf8,
+	0x85, 0x77, 0x04, 0x00, 0x00,
 }
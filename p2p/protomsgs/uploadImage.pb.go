@@ -29,7 +29,7 @@ func (m *UploadImageMsgData) Reset()         { *m = UploadImageMsgData{} }
 func (m *UploadImageMsgData) String() string { return proto.CompactTextString(m) }
 func (*UploadImageMsgData) ProtoMessage()    {}
 func (*UploadImageMsgData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_uploadImage_972f658ca41f1c00, []int{0}
+	return fileDescriptor_uploadImage_2127a4ffe3dcef04, []int{0}
 }
 func (m *UploadImageMsgData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UploadImageMsgData.Unmarshal(m, b)
@@ -59,6 +59,7 @@ func (m *UploadImageMsgData) GetMessageData() *MessageData {
 type UploadImageResponse struct {
 	UploadImageMsgData   *UploadImageMsgData `protobuf:"bytes,1,opt,name=uploadImageMsgData,proto3" json:"uploadImageMsgData,omitempty"`
 	ImageID              string              `protobuf:"bytes,2,opt,name=imageID,proto3" json:"imageID,omitempty"`
+	Error                *Error              `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
 	XXX_unrecognized     []byte              `json:"-"`
 	XXX_sizecache        int32               `json:"-"`
@@ -68,7 +69,7 @@ func (m *UploadImageResponse) Reset()         { *m = UploadImageResponse{} }
 func (m *UploadImageResponse) String() string { return proto.CompactTextString(m) }
 func (*UploadImageResponse) ProtoMessage()    {}
 func (*UploadImageResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_uploadImage_972f658ca41f1c00, []int{1}
+	return fileDescriptor_uploadImage_2127a4ffe3dcef04, []int{1}
 }
 func (m *UploadImageResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UploadImageResponse.Unmarshal(m, b)
@@ -102,24 +103,32 @@ func (m *UploadImageResponse) GetImageID() string {
 	return ""
 }
 
+func (m *UploadImageResponse) GetError() *Error {
+	if m != nil {
+		return m.Error
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*UploadImageMsgData)(nil), "protomsgs.UploadImageMsgData")
 	proto.RegisterType((*UploadImageResponse)(nil), "protomsgs.UploadImageResponse")
 }
 
-func init() { proto.RegisterFile("uploadImage.proto", fileDescriptor_uploadImage_972f658ca41f1c00) }
+func init() { proto.RegisterFile("uploadImage.proto", fileDescriptor_uploadImage_2127a4ffe3dcef04) }
 
-var fileDescriptor_uploadImage_972f658ca41f1c00 = []byte{
-	// 161 bytes of a gzipped FileDescriptorProto
+var fileDescriptor_uploadImage_2127a4ffe3dcef04 = []byte{
+	// 184 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x2c, 0x2d, 0xc8, 0xc9,
 	0x4f, 0x4c, 0xf1, 0xcc, 0x4d, 0x4c, 0x4f, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x04,
 	0x53, 0xb9, 0xc5, 0xe9, 0xc5, 0x52, 0x3c, 0xc9, 0xf9, 0xb9, 0xb9, 0xf9, 0x79, 0x10, 0x09, 0x25,
 	0x3f, 0x2e, 0xa1, 0x50, 0x84, 0x6a, 0xdf, 0xe2, 0x74, 0x97, 0xc4, 0x92, 0x44, 0x21, 0x0b, 0x2e,
 	0xee, 0xdc, 0xd4, 0xe2, 0xe2, 0xc4, 0xf4, 0x54, 0x10, 0x57, 0x82, 0x51, 0x81, 0x51, 0x83, 0xdb,
-	0x48, 0x4c, 0x0f, 0x6e, 0x88, 0x9e, 0x2f, 0x42, 0x36, 0x08, 0x59, 0xa9, 0x52, 0x1d, 0x97, 0x30,
-	0x92, 0x79, 0x41, 0xa9, 0xc5, 0x05, 0xf9, 0x79, 0xc5, 0xa9, 0x42, 0xbe, 0x5c, 0x42, 0xa5, 0x18,
-	0xd6, 0x40, 0xcd, 0x95, 0x45, 0x32, 0x17, 0xd3, 0x2d, 0x41, 0x58, 0x34, 0x0a, 0x49, 0x70, 0xb1,
-	0x67, 0x82, 0xf8, 0x9e, 0x2e, 0x12, 0x4c, 0x0a, 0x8c, 0x1a, 0x9c, 0x41, 0x30, 0x6e, 0x12, 0x1b,
-	0xd8, 0x2c, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff, 0x01, 0xee, 0x31, 0xe2, 0x04, 0x01, 0x00,
-	0x00,
+	0x48, 0x4c, 0x0f, 0x6e, 0x88, 0x9e, 0x2f, 0x42, 0x36, 0x08, 0x59, 0xa9, 0xd2, 0x32, 0x46, 0x2e,
+	0x61, 0x24, 0x03, 0x83, 0x52, 0x8b, 0x0b, 0xf2, 0xf3, 0x8a, 0x53, 0x85, 0x7c, 0xb9, 0x84, 0x4a,
+	0x31, 0xec, 0x81, 0x1a, 0x2c, 0x8b, 0x64, 0x30, 0xa6, 0x63, 0x82, 0xb0, 0x68, 0x14, 0x92, 0xe0,
+	0x62, 0xcf, 0x04, 0xf1, 0x3d, 0x5d, 0x24, 0x98, 0x14, 0x18, 0x35, 0x38, 0x83, 0x60, 0x5c, 0x21,
+	0x35, 0x2e, 0xd6, 0xd4, 0xa2, 0xa2, 0xfc, 0x22, 0x09, 0x66, 0xb0, 0xd9, 0x02, 0x48, 0x66, 0xbb,
+	0x82, 0xc4, 0x83, 0x20, 0xd2, 0x49, 0x6c, 0x60, 0x71, 0x63, 0x40, 0x00, 0x00, 0x00, 0xff, 0xff,
+	0x7e, 0x98, 0x31, 0xcd, 0x2d, 0x01, 0x00, 0x00,
 }
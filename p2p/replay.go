@@ -0,0 +1,139 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sync"
+	"time"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+)
+
+const (
+	// defaultMessageFreshness is how old, or how far in the future, a message may be if not configured
+	defaultMessageFreshness = 2 * time.Minute
+	// defaultNonceCacheSize is how many message IDs are remembered per sender if not configured
+	defaultNonceCacheSize = 1024
+	// maxReplaySenders is the number of senders after which the idle ones are forgotten
+	maxReplaySenders = 4096
+)
+
+// ReplayStats counts the messages rejected by the ReplayGuard
+type ReplayStats struct {
+	Stale    uint64 `json:"stale"`    // Older than the freshness window
+	Future   uint64 `json:"future"`   // Further in the future than the freshness window
+	Replayed uint64 `json:"replayed"` // Already seen
+}
+
+// ReplayGuard rejects messages that are not fresh or have been seen before.
+// It is shared by all the protocols of a node.
+type ReplayGuard struct {
+	window    time.Duration
+	cacheSize int
+	senders   map[string]*nonceCache // Seen message IDs by sender node ID
+	stats     ReplayStats
+	mu        sync.Mutex
+}
+
+// nonceCache holds the message IDs recently seen from a sender
+type nonceCache struct {
+	seen  map[string]int64 // Message timestamp by message ID
+	order []string         // Message IDs, oldest first
+}
+
+// NewReplayGuard returns a new ReplayGuard accepting messages at most window old or in the future
+// and remembering up to cacheSize message IDs per sender. Zero values mean the defaults.
+func NewReplayGuard(window time.Duration, cacheSize int) *ReplayGuard {
+	if window <= 0 {
+		window = defaultMessageFreshness
+	}
+	if cacheSize <= 0 {
+		cacheSize = defaultNonceCacheSize
+	}
+	return &ReplayGuard{
+		window:    window,
+		cacheSize: cacheSize,
+		senders:   make(map[string]*nonceCache),
+	}
+}
+
+// Check checks that the message with the data is fresh and hasn't been seen before, and then remembers it.
+// It must be called after the message got authenticated, so that no one can fill a sender's cache.
+func (g *ReplayGuard) Check(data *api.MessageData, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sent := time.Unix(data.Timestamp, 0)
+	if sent.Before(now.Add(-g.window)) {
+		g.stats.Stale++
+		return false
+	}
+	if sent.After(now.Add(g.window)) {
+		g.stats.Future++
+		return false
+	}
+	cache, ok := g.senders[data.NodeId]
+	if !ok {
+		g.forgetIdleSenders(now)
+		cache = &nonceCache{seen: make(map[string]int64)}
+		g.senders[data.NodeId] = cache
+	}
+	if _, seen := cache.seen[data.Id]; seen {
+		g.stats.Replayed++
+		return false
+	}
+	cache.add(data.Id, data.Timestamp, g.cacheSize, now.Add(-g.window).Unix())
+	return true
+}
+
+// Stats returns how many messages have been rejected so far
+func (g *ReplayGuard) Stats() ReplayStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats
+}
+
+// forgetIdleSenders drops the caches of the senders whose messages are all stale,
+// as their messages get rejected anyway. It must be called with the mutex held.
+func (g *ReplayGuard) forgetIdleSenders(now time.Time) {
+	if len(g.senders) < maxReplaySenders {
+		return
+	}
+	oldest := now.Add(-g.window).Unix()
+	for sender, cache := range g.senders {
+		if cache.newest() < oldest {
+			delete(g.senders, sender)
+		}
+	}
+}
+
+// add remembers the message id sent at timestamp. The stale IDs and, if the cache is full, the oldest ones are forgotten.
+func (c *nonceCache) add(id string, timestamp int64, size int, oldest int64) {
+	for len(c.order) > 0 && (len(c.order) >= size || c.seen[c.order[0]] < oldest) {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.seen[id] = timestamp
+	c.order = append(c.order, id)
+}
+
+// newest returns the timestamp of the most recently seen message
+func (c *nonceCache) newest() int64 {
+	if len(c.order) == 0 {
+		return 0
+	}
+	return c.seen[c.order[len(c.order)-1]]
+}
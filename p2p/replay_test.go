@@ -0,0 +1,58 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplayGuardFreshness(t *testing.T) {
+	guard := NewReplayGuard(time.Minute, 10)
+	now := time.Now()
+	assert.True(t, guard.Check(&api.MessageData{NodeId: "a", Id: "1", Timestamp: now.Unix()}, now))
+	assert.False(t, guard.Check(&api.MessageData{NodeId: "a", Id: "2", Timestamp: now.Add(-2 * time.Minute).Unix()}, now))
+	assert.False(t, guard.Check(&api.MessageData{NodeId: "a", Id: "3", Timestamp: now.Add(2 * time.Minute).Unix()}, now))
+	assert.Equal(t, ReplayStats{Stale: 1, Future: 1}, guard.Stats())
+}
+
+func TestReplayGuardReplays(t *testing.T) {
+	guard := NewReplayGuard(time.Minute, 10)
+	now := time.Now()
+	msg := &api.MessageData{NodeId: "a", Id: "1", Timestamp: now.Unix()}
+	assert.True(t, guard.Check(msg, now))
+	assert.False(t, guard.Check(msg, now))
+	// The same message ID from another sender is a different message
+	assert.True(t, guard.Check(&api.MessageData{NodeId: "b", Id: "1", Timestamp: now.Unix()}, now))
+	assert.Equal(t, ReplayStats{Replayed: 1}, guard.Stats())
+}
+
+func TestReplayGuardBounded(t *testing.T) {
+	guard := NewReplayGuard(time.Minute, 2)
+	now := time.Now()
+	for _, id := range []string{"1", "2", "3"} {
+		assert.True(t, guard.Check(&api.MessageData{NodeId: "a", Id: id, Timestamp: now.Unix()}, now))
+	}
+	assert.Equal(t, 2, len(guard.senders["a"].seen))
+	// Stale IDs are forgotten as they get rejected anyway
+	later := now.Add(2 * time.Minute)
+	assert.True(t, guard.Check(&api.MessageData{NodeId: "a", Id: "4", Timestamp: later.Unix()}, later))
+	assert.Equal(t, 1, len(guard.senders["a"].seen))
+}
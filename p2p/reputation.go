@@ -0,0 +1,345 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/database"
+	"github.com/crowdcompute/crowdengine/log"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// Outcome is the outcome of an interaction with a peer
+type Outcome int
+
+// The outcomes of the interactions with peers recorded by the Reputation
+const (
+	JobSucceeded Outcome = iota
+	JobFailed
+	RequestSucceeded
+	RequestFailed
+	RequestTimedOut
+	InvalidResponse
+	AuthFailure
+	DiscoveryResponded
+	DiscoveryUnused
+	LimitExceeded
+)
+
+// outcomeWeights are the score points each outcome adds to a peer
+var outcomeWeights = map[Outcome]float64{
+	JobSucceeded:       5,
+	JobFailed:          -10,
+	RequestSucceeded:   1,
+	RequestFailed:      -2,
+	RequestTimedOut:    -5,
+	InvalidResponse:    -10,
+	AuthFailure:        -20,
+	DiscoveryResponded: 0,
+	DiscoveryUnused:    -1,
+	LimitExceeded:      -5,
+}
+
+const (
+	// BanScore is the score at which a peer gets banned
+	BanScore = -100
+	// BanDuration is how long a peer stays banned
+	BanDuration = time.Hour
+	// latencyPenalty is the score a peer loses per second of average response latency
+	latencyPenalty = 1
+	// latencySmoothing is the weight of a new latency sample in the moving average
+	latencySmoothing = 0.2
+	// discoveryUseTimeout is how long the node waits for a discovery response to lead to work
+	discoveryUseTimeout = 10 * time.Minute
+)
+
+// PeerScore is the reputation of a peer along with its score
+type PeerScore struct {
+	ID     string                   `json:"id"`
+	Score  float64                  `json:"score"`
+	Banned bool                     `json:"banned"`
+	Record *database.PeerReputation `json:"record"`
+}
+
+// Reputation keeps track of the outcomes of the interactions with peers across all protocols.
+// The reputations are stored in the database, unless it is created without one.
+// The recording methods can be called on a nil Reputation, which records nothing.
+type Reputation struct {
+	persist   bool
+	peers     map[peer.ID]*database.PeerReputation
+	responded map[peer.ID]time.Time // Discovery responses that didn't lead to work yet
+	onBan     []func(peer.ID)
+	mu        sync.Mutex
+	storeMu   sync.Mutex // Orders the database writes, which happen without holding mu
+}
+
+// NewReputation creates a Reputation, which is stored in the database if persist is true
+func NewReputation(persist bool) *Reputation {
+	return &Reputation{
+		persist:   persist,
+		peers:     make(map[peer.ID]*database.PeerReputation),
+		responded: make(map[peer.ID]time.Time),
+	}
+}
+
+// OnBan registers a function to be called whenever a peer gets banned
+func (r *Reputation) OnBan(f func(peer.ID)) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.onBan = append(r.onBan, f)
+	r.mu.Unlock()
+}
+
+// Record records an outcome of the interaction with the peer pID, banning it if its score gets too low
+func (r *Reputation) Record(pID peer.ID, outcome Outcome) {
+	r.update(pID, func(rep *database.PeerReputation) {
+		switch outcome {
+		case JobSucceeded:
+			rep.JobsSucceeded++
+		case JobFailed:
+			rep.JobsFailed++
+		case RequestSucceeded:
+			rep.RequestsSucceeded++
+		case RequestFailed:
+			rep.RequestsFailed++
+		case RequestTimedOut:
+			rep.Timeouts++
+		case InvalidResponse:
+			rep.InvalidResponses++
+		case AuthFailure:
+			rep.AuthFailures++
+		case DiscoveryResponded:
+			rep.DiscoveryResponses++
+		case DiscoveryUnused:
+			rep.UnusedResponses++
+		case LimitExceeded:
+			rep.LimitViolations++
+		}
+	})
+}
+
+// RecordLatency records the time the peer pID took to respond to a request
+func (r *Reputation) RecordLatency(pID peer.ID, latency time.Duration) {
+	r.update(pID, func(rep *database.PeerReputation) {
+		ms := float64(latency) / float64(time.Millisecond)
+		if rep.LatencyMs == 0 {
+			rep.LatencyMs = ms
+		} else {
+			rep.LatencyMs += latencySmoothing * (ms - rep.LatencyMs)
+		}
+	})
+}
+
+// Responded records that the peer pID responded to a discovery request.
+// Unless work is sent to it within the discoveryUseTimeout, the response counts as unused.
+func (r *Reputation) Responded(pID peer.ID) {
+	if r == nil {
+		return
+	}
+	r.Record(pID, DiscoveryResponded)
+	r.mu.Lock()
+	if _, ok := r.responded[pID]; !ok {
+		r.responded[pID] = time.Now()
+	}
+	r.mu.Unlock()
+}
+
+// WorkSent records that work was sent to the peer pID, so that its discovery response was used
+func (r *Reputation) WorkSent(pID peer.ID) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	delete(r.responded, pID)
+	r.mu.Unlock()
+}
+
+// ExpireResponses records the discovery responses that didn't lead to work in time as unused
+func (r *Reputation) ExpireResponses(now time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	unused := make([]peer.ID, 0)
+	for pID, respondedAt := range r.responded {
+		if now.Sub(respondedAt) > discoveryUseTimeout {
+			unused = append(unused, pID)
+			delete(r.responded, pID)
+		}
+	}
+	r.mu.Unlock()
+	for _, pID := range unused {
+		r.Record(pID, DiscoveryUnused)
+	}
+}
+
+// Score returns the score of the peer pID. Peers the node never interacted with have a zero score.
+func (r *Reputation) Score(pID peer.ID) float64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return score(r.get(pID))
+}
+
+// Banned checks if the peer pID is banned
+func (r *Reputation) Banned(pID peer.ID) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.get(pID).BannedUntil > time.Now().Unix()
+}
+
+// Rank sorts the peer IDs from the highest to the lowest score
+func (r *Reputation) Rank(peers []peer.ID) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sort.SliceStable(peers, func(i, j int) bool {
+		return score(r.get(peers[i])) > score(r.get(peers[j]))
+	})
+}
+
+// Peer returns the reputation of the peer pID
+func (r *Reputation) Peer(pID peer.ID) PeerScore {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rep := *r.get(pID)
+	return PeerScore{ID: pID.Pretty(), Score: score(&rep), Banned: rep.BannedUntil > time.Now().Unix(), Record: &rep}
+}
+
+// Peers returns the reputations of all the peers the node interacted with, from the highest to the lowest score
+func (r *Reputation) Peers() ([]PeerScore, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.persist {
+		reps, err := database.GetPeerReputationsFromDB()
+		if err != nil {
+			return nil, err
+		}
+		for id, rep := range reps {
+			if pID, err := peer.IDB58Decode(id); err == nil {
+				if _, ok := r.peers[pID]; !ok {
+					r.peers[pID] = rep
+				}
+			}
+		}
+	}
+	now := time.Now().Unix()
+	scores := make([]PeerScore, 0, len(r.peers))
+	for pID := range r.peers {
+		rep := *r.get(pID)
+		scores = append(scores, PeerScore{ID: pID.Pretty(), Score: score(&rep), Banned: rep.BannedUntil > now, Record: &rep})
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores, nil
+}
+
+// update applies the change to the reputation of the peer pID and stores it
+func (r *Reputation) update(pID peer.ID, change func(*database.PeerReputation)) {
+	if r == nil || pID == "" {
+		return
+	}
+	r.mu.Lock()
+	rep := r.get(pID)
+	change(rep)
+	now := time.Now()
+	rep.UpdatedTime = now.Unix()
+	banned := false
+	if score(rep) <= BanScore && rep.BannedUntil <= now.Unix() {
+		rep.BannedUntil = now.Add(BanDuration).Unix()
+		banned = true
+	}
+	onBan, bannedUntil := r.onBan, rep.BannedUntil
+	r.mu.Unlock()
+
+	r.store(pID)
+	if banned {
+		log.Printf("Banning %s until %s", pID.Pretty(), time.Unix(bannedUntil, 0))
+		for _, f := range onBan {
+			f(pID)
+		}
+	}
+}
+
+// store stores the current reputation of the peer pID to the database.
+// Each write copies the reputation when it starts, so the last write stores the latest one.
+func (r *Reputation) store(pID peer.ID) {
+	if !r.persist {
+		return
+	}
+	r.storeMu.Lock()
+	defer r.storeMu.Unlock()
+	r.mu.Lock()
+	rep := *r.get(pID)
+	r.mu.Unlock()
+	if err := database.StorePeerReputationToDB(pID.Pretty(), &rep); err != nil {
+		log.Println("Couldn't store the reputation of ", pID.Pretty(), ". Error: ", err)
+	}
+}
+
+// get returns the reputation of the peer pID, loading it from the database the first time.
+// The mutex must be held.
+func (r *Reputation) get(pID peer.ID) *database.PeerReputation {
+	rep, ok := r.peers[pID]
+	if !ok {
+		rep = &database.PeerReputation{}
+		if r.persist {
+			if stored, err := database.GetPeerReputationFromDB(pID.Pretty()); err == nil {
+				rep = stored
+			}
+		}
+		r.peers[pID] = rep
+	}
+	expireBan(rep, time.Now().Unix())
+	return rep
+}
+
+// expireBan resets the outcomes of a peer whose ban expired. The score never recovers otherwise,
+// so the first outcome recorded after the ban would ban the peer again.
+func expireBan(rep *database.PeerReputation, now int64) {
+	if rep.BannedUntil == 0 || rep.BannedUntil > now {
+		return
+	}
+	*rep = database.PeerReputation{LatencyMs: rep.LatencyMs, UpdatedTime: rep.UpdatedTime}
+}
+
+// score returns the score of a reputation
+func score(rep *database.PeerReputation) float64 {
+	s := float64(rep.JobsSucceeded)*outcomeWeights[JobSucceeded] +
+		float64(rep.JobsFailed)*outcomeWeights[JobFailed] +
+		float64(rep.RequestsSucceeded)*outcomeWeights[RequestSucceeded] +
+		float64(rep.RequestsFailed)*outcomeWeights[RequestFailed] +
+		float64(rep.Timeouts)*outcomeWeights[RequestTimedOut] +
+		float64(rep.InvalidResponses)*outcomeWeights[InvalidResponse] +
+		float64(rep.AuthFailures)*outcomeWeights[AuthFailure] +
+		float64(rep.DiscoveryResponses)*outcomeWeights[DiscoveryResponded] +
+		float64(rep.UnusedResponses)*outcomeWeights[DiscoveryUnused] +
+		float64(rep.LimitViolations)*outcomeWeights[LimitExceeded]
+	return s - latencyPenalty*rep.LatencyMs/1000
+}
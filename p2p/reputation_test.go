@@ -0,0 +1,117 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReputationScore(t *testing.T) {
+	rep := NewReputation(false)
+	good, bad := peer.ID("good"), peer.ID("bad")
+	rep.Record(good, JobSucceeded)
+	rep.Record(good, RequestSucceeded)
+	rep.Record(bad, JobFailed)
+	rep.RecordLatency(bad, 2*time.Second)
+
+	assert.Equal(t, 6.0, rep.Score(good))
+	assert.Equal(t, -12.0, rep.Score(bad))
+	assert.Equal(t, 0.0, rep.Score(peer.ID("unknown")))
+
+	peers := []peer.ID{bad, peer.ID("unknown"), good}
+	rep.Rank(peers)
+	assert.Equal(t, []peer.ID{good, peer.ID("unknown"), bad}, peers)
+}
+
+func TestReputationBan(t *testing.T) {
+	rep := NewReputation(false)
+	banned := make(chan peer.ID, 1)
+	rep.OnBan(func(pID peer.ID) { banned <- pID })
+	liar := peer.ID("liar")
+	for i := 0; i < -BanScore/int(-outcomeWeights[AuthFailure]); i++ {
+		assert.False(t, rep.Banned(liar))
+		rep.Record(liar, AuthFailure)
+	}
+	assert.True(t, rep.Banned(liar))
+	assert.Equal(t, liar, <-banned)
+	scores, err := rep.Peers()
+	assert.NoError(t, err)
+	assert.True(t, scores[0].Banned)
+	assert.Equal(t, uint64(5), scores[0].Record.AuthFailures)
+
+	// The peer starts over once the ban expires
+	rep.mu.Lock()
+	rep.peers[liar].BannedUntil = time.Now().Unix() - 1
+	rep.mu.Unlock()
+	rep.Record(liar, AuthFailure)
+	assert.False(t, rep.Banned(liar))
+	assert.Equal(t, uint64(1), rep.Peer(liar).Record.AuthFailures)
+	assert.Equal(t, outcomeWeights[AuthFailure], rep.Score(liar))
+}
+
+func TestUnusedDiscoveryResponses(t *testing.T) {
+	rep := NewReputation(false)
+	used, unused := peer.ID("used"), peer.ID("unused")
+	rep.Responded(used)
+	rep.Responded(unused)
+	rep.WorkSent(used)
+
+	rep.ExpireResponses(time.Now())
+	assert.Equal(t, uint64(0), rep.Peer(unused).Record.UnusedResponses)
+	rep.ExpireResponses(time.Now().Add(discoveryUseTimeout + time.Second))
+	assert.Equal(t, uint64(1), rep.Peer(unused).Record.UnusedResponses)
+	assert.Equal(t, uint64(0), rep.Peer(used).Record.UnusedResponses)
+	assert.Equal(t, uint64(1), rep.Peer(used).Record.DiscoveryResponses)
+}
+
+func TestFutureRecordsOutcomes(t *testing.T) {
+	rep := NewReputation(false)
+	requests := newPendingRequests(rep)
+	remote := peer.ID("remote")
+	responses := []*api.RunResponse{
+		{ContainerID: "container"},
+		{Error: &api.Error{Code: api.ErrorCode_Internal}},
+		// Rejections aren't failures of the peer
+		{Error: &api.Error{Code: api.ErrorCode_ResourceExhausted, Retryable: true}},
+	}
+	for i, resp := range responses {
+		id := string(rune('a' + i))
+		f := requests.add(id, remote)
+		assert.True(t, requests.resolve(id, remote, resp))
+		f.Wait(context.Background())
+	}
+
+	f := requests.add("timeout", remote)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	f.Wait(ctx)
+
+	record := rep.Peer(remote).Record
+	assert.Equal(t, uint64(1), record.RequestsSucceeded)
+	assert.Equal(t, uint64(1), record.RequestsFailed)
+	assert.Equal(t, uint64(1), record.Timeouts)
+	// Jobs succeed or fail when they end, not when the worker responds
+	assert.Equal(t, uint64(0), record.JobsSucceeded)
+	assert.Equal(t, uint64(0), record.JobsFailed)
+}
@@ -0,0 +1,165 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	"github.com/gogo/protobuf/proto"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// ErrRequestNotSent is returned when a request couldn't be sent to the remote peer
+var ErrRequestNotSent = errors.New("The request couldn't be sent to the remote peer")
+
+// ErrNotAuthenticated is sent back when a request's signature couldn't be verified
+var ErrNotAuthenticated = errors.New("Failed to authenticate the request")
+
+// ErrNotAvailable is sent back when a request arrives out of the node's availability windows
+var ErrNotAvailable = errors.New("The node is out of its availability windows")
+
+// ErrUnexpectedResponse is returned when a response of the wrong type arrives for a request
+var ErrUnexpectedResponse = errors.New("Unexpected response type")
+
+// ResponseError is the error a remote peer responded with, when it failed to serve a request
+type ResponseError struct {
+	Code      api.ErrorCode
+	Message   string
+	Retryable bool
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// newProtoError creates the error envelope of a failed request's response
+func newProtoError(code api.ErrorCode, retryable bool, err error) *api.Error {
+	return &api.Error{Code: code, Message: err.Error(), Retryable: retryable}
+}
+
+// errorResponse is a response that may carry an error envelope
+type errorResponse interface {
+	GetError() *api.Error
+}
+
+// pendingRequests holds the requests sent to remote peers that still wait for a response.
+// Requests are keyed by their MessageData.Id, which the responses copy.
+type pendingRequests struct {
+	requests   map[string]*Future
+	reputation *Reputation // Records whether the remote peers served the requests
+	mu         sync.Mutex
+}
+
+// newPendingRequests returns an empty pending requests table, which records the outcomes to the reputation
+func newPendingRequests(reputation *Reputation) *pendingRequests {
+	return &pendingRequests{requests: make(map[string]*Future), reputation: reputation}
+}
+
+// add registers a request with the message ID id that was sent to the peer to
+func (r *pendingRequests) add(id string, to peer.ID) *Future {
+	f := &Future{
+		id:       id,
+		to:       to,
+		response: make(chan proto.Message, 1),
+		pending:  r,
+		sent:     time.Now(),
+	}
+	r.mu.Lock()
+	r.requests[id] = f
+	r.mu.Unlock()
+	return f
+}
+
+// resolve delivers the response resp of the peer from to the request with the message ID id.
+// It returns false if no such request is pending from this peer.
+func (r *pendingRequests) resolve(id string, from peer.ID, resp proto.Message) bool {
+	r.mu.Lock()
+	f, ok := r.requests[id]
+	if ok && f.to == from {
+		delete(r.requests, id)
+	}
+	r.mu.Unlock()
+	if !ok || f.to != from {
+		return false
+	}
+	f.response <- resp
+	return true
+}
+
+// remove removes the request with the message ID id from the pending requests
+func (r *pendingRequests) remove(id string) {
+	r.mu.Lock()
+	delete(r.requests, id)
+	r.mu.Unlock()
+}
+
+// len returns the number of pending requests
+func (r *pendingRequests) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// Future is the response of a request sent to a remote peer, that will arrive at some point
+type Future struct {
+	id       string
+	to       peer.ID
+	response chan proto.Message
+	pending  *pendingRequests
+	sent     time.Time
+}
+
+// ID returns the message ID of the request
+func (f *Future) ID() string {
+	return f.id
+}
+
+// Wait waits for the response until it arrives or the ctx is done.
+// If the remote peer responded with an error a *ResponseError is returned.
+// The outcome of the request is recorded, but not the outcome of a job, which ends after its response.
+func (f *Future) Wait(ctx context.Context) (proto.Message, error) {
+	defer f.pending.remove(f.id)
+	select {
+	case resp := <-f.response:
+		f.pending.reputation.RecordLatency(f.to, time.Since(f.sent))
+		if errResp, ok := resp.(errorResponse); ok && errResp.GetError() != nil {
+			e := errResp.GetError()
+			// Only internal errors are the peer's failures, the rest are rejections of the request
+			if e.Code == api.ErrorCode_Internal {
+				f.pending.reputation.Record(f.to, RequestFailed)
+			}
+			return nil, &ResponseError{Code: e.Code, Message: e.Message, Retryable: e.Retryable}
+		}
+		f.pending.reputation.Record(f.to, RequestSucceeded)
+		return resp, nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			f.pending.reputation.Record(f.to, RequestTimedOut)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Cancel stops waiting for the response
+func (f *Future) Cancel() {
+	f.pending.remove(f.id)
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestResponsesMatchTheirRequests checks that concurrent requests get their own responses
+func TestResponsesMatchTheirRequests(t *testing.T) {
+	pending := newPendingRequests(nil)
+	remote := peer.ID("remote")
+	future1 := &RunFuture{pending.add("1", remote)}
+	future2 := &RunFuture{pending.add("2", remote)}
+
+	assert.True(t, pending.resolve("2", remote, &api.RunResponse{ContainerID: "container2"}))
+	assert.True(t, pending.resolve("1", remote, &api.RunResponse{ContainerID: "container1"}))
+
+	containerID, err := future1.Wait(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "container1", containerID)
+	containerID, err = future2.Wait(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "container2", containerID)
+	assert.Equal(t, 0, pending.len())
+}
+
+// TestResponseFromOtherPeerDropped checks that only the peer a request was sent to can answer it
+func TestResponseFromOtherPeerDropped(t *testing.T) {
+	pending := newPendingRequests(nil)
+	pending.add("1", peer.ID("remote"))
+	assert.False(t, pending.resolve("1", peer.ID("other"), &api.RunResponse{}))
+	assert.False(t, pending.resolve("unknown", peer.ID("remote"), &api.RunResponse{}))
+	assert.Equal(t, 1, pending.len())
+}
+
+// TestRequestDeadline checks that waiting stops when the context is done and the request gets removed
+func TestRequestDeadline(t *testing.T) {
+	pending := newPendingRequests(nil)
+	future := &InspectFuture{pending.add("1", peer.ID("remote"))}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := future.Wait(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 0, pending.len())
+	// A late response gets dropped
+	assert.False(t, pending.resolve("1", peer.ID("remote"), &api.InspectContResponse{}))
+}
+
+// TestErrorResponse checks that a response carrying an error envelope is returned as a ResponseError
+func TestErrorResponse(t *testing.T) {
+	pending := newPendingRequests(nil)
+	future := &RunFuture{pending.add("1", peer.ID("remote"))}
+	respErr := newProtoError(api.ErrorCode_ResourceExhausted, true, ErrNoFreeSlot)
+	assert.True(t, pending.resolve("1", peer.ID("remote"), &api.RunResponse{Error: respErr}))
+
+	containerID, err := future.Wait(context.Background())
+	assert.Equal(t, "", containerID)
+	assert.Equal(t, &ResponseError{Code: api.ErrorCode_ResourceExhausted, Message: ErrNoFreeSlot.Error(), Retryable: true}, err)
+}
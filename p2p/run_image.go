@@ -17,9 +17,12 @@
 package p2p
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/crowdcompute/crowdengine/common"
+	"github.com/crowdcompute/crowdengine/database"
 
 	"github.com/crowdcompute/crowdengine/log"
 
@@ -33,27 +36,40 @@ import (
 	uuid "github.com/satori/go.uuid"
 )
 
-// pattern: /protocol-name/request-or-response-message/version
-const runRequest = "/task/availreq/0.0.1"
-const runResponse = "/task/availresp/0.0.1"
+// pattern: /protocol-name/request-or-response-message, which gets a /version suffix from protocolVersions
+const runRequest = "/task/availreq"
+const runResponse = "/task/availresp"
 
 // TaskProtocol implements the Notifier interface
 type TaskProtocol struct {
-	p2pHost           host.Host // local host
-	ContainerID       chan string
-	runningContainers map[string]struct{} // The running containers of the node
-	taskObservers     map[Observer]struct{}
+	p2pHost       host.Host            // local host
+	pending       *pendingRequests     // Run requests waiting for a response
+	cancels       *pendingRequests     // Cancel requests waiting for a response
+	capacity      *CapacityTracker     // The node's resources in use
+	availability  *common.Availability // The node's availability windows
+	replay        *ReplayGuard         // Rejects stale and replayed messages
+	reputation    *Reputation          // Records the outcomes of the interactions with peers
+	limiter       *Limiter             // Limits the work each peer can make the node do
+	taskObservers map[Observer]struct{}
 }
 
 // NewTaskProtocol sets the protocol's stream handlers and returns a new TaskProtocol
-func NewTaskProtocol(p2pHost host.Host) *TaskProtocol {
+func NewTaskProtocol(p2pHost host.Host, capacity *CapacityTracker, availability *common.Availability, replay *ReplayGuard, reputation *Reputation, limiter *Limiter) *TaskProtocol {
 	p := &TaskProtocol{p2pHost: p2pHost,
-		ContainerID:       make(chan string, 1),
-		runningContainers: map[string]struct{}{},
-		taskObservers:     map[Observer]struct{}{},
+		pending:       newPendingRequests(reputation),
+		cancels:       newPendingRequests(reputation),
+		capacity:      capacity,
+		availability:  availability,
+		replay:        replay,
+		reputation:    reputation,
+		limiter:       limiter,
+		taskObservers: map[Observer]struct{}{},
 	}
-	p2pHost.SetStreamHandler(runRequest, p.onRunRequest)
-	p2pHost.SetStreamHandler(runResponse, p.onRunResponse)
+	setStreamHandler(p2pHost, runRequest, p.onRunRequest)
+	setStreamHandler(p2pHost, runResponse, p.onRunResponse)
+	setStreamHandler(p2pHost, cancelRequest, p.onCancelRequest)
+	setStreamHandler(p2pHost, cancelResponse, p.onCancelResponse)
+	setStreamHandler(p2pHost, jobStatus, p.onJobStatus)
 	return p
 }
 
@@ -74,53 +90,216 @@ func (p *TaskProtocol) Notify() {
 	}
 }
 
-// RunImage runs an image with imageID to the hostID
-func (p *TaskProtocol) RunImage(hostID peer.ID, imageID string) bool {
+// RunFuture is the pending response of a run image request
+type RunFuture struct {
+	*Future
+}
+
+// Wait waits for the ID of the container that runs the image
+func (f *RunFuture) Wait(ctx context.Context) (string, error) {
+	resp, err := f.Future.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	runResp, ok := resp.(*api.RunResponse)
+	if !ok {
+		return "", ErrUnexpectedResponse
+	}
+	return runResp.ContainerID, nil
+}
+
+// RunImage asks the hostID node to run the image imageID with its defaults.
+// The ID of the container arrives to the returned future.
+func (p *TaskProtocol) RunImage(hostID peer.ID, imageID string) (*RunFuture, error) {
+	return p.RunJob(hostID, "", imageID, nil)
+}
+
+// RunJob asks the hostID node to run the image imageID as the job spec describes, for the account owner.
+// The job is recorded under the ID of the request, and the ID of the container arrives to the returned future.
+func (p *TaskProtocol) RunJob(hostID peer.ID, owner, imageID string, spec *api.JobSpec) (*RunFuture, error) {
 	log.Printf("%s: Asking running image. Sending request to: %s....", p.p2pHost.ID(), hostID)
 	// create message data
 	req := &api.RunRequest{RunImageMsgData: NewRunImageMsgData(uuid.Must(uuid.NewV4(), nil).String(), true, p.p2pHost),
-		ImageID: imageID}
+		ImageID: imageID,
+		Spec:    spec,
+		Owner:   owner}
 
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
 	req.RunImageMsgData.MessageData.Sign = signProtoMsg(req, key)
 
+	jobID := req.RunImageMsgData.MessageData.Id
+	job := &database.Job{ID: jobID, Owner: owner, Requester: p.p2pHost.ID().Pretty(), Worker: hostID.Pretty(), Image: imageID, Spec: spec}
+	if err := manager.CreateJob(job); err != nil {
+		return nil, err
+	}
+	future := p.pending.add(jobID, hostID)
 	if !sendMsg(p.p2pHost, hostID, req, protocol.ID(runRequest)) {
-		return false
+		future.Cancel()
+		manager.FailJob(jobID, ErrRequestNotSent)
+		return nil, ErrRequestNotSent
 	}
 
+	p.reputation.WorkSent(hostID)
 	log.Printf("%s: Ask running image to: %s was sent. Message Id: %s", p.p2pHost.ID(), peer.ID(hostID), req.RunImageMsgData.MessageData.Id)
-	return true
+	return &RunFuture{future}, nil
 }
 
 // remote peer requests handler
 func (p *TaskProtocol) onRunRequest(s inet.Stream) {
 	log.Printf("%s: Received run container request from %s.", s.Conn().LocalPeer(), s.Conn().RemotePeer())
-	// get request data
+	// Requests over the limits are rejected without being handled, so that the requester can retry them later
 	data := &api.RunRequest{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), runRequest)
+	if err != nil {
+		log.Println("Rejecting run container request. Error: ", err)
+		if p.limiter.Decode(s.Conn().RemotePeer(), data, s) == nil && data.RunImageMsgData != nil {
+			p.createSendResponse(s.Conn().RemotePeer(), data.RunImageMsgData.MessageData.Id, "", newProtoError(api.ErrorCode_ResourceExhausted, true, err))
+		}
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.RunImageMsgData == nil {
+		log.Println("Failed to decode run container request")
+		return
+	}
+	requestID := data.RunImageMsgData.MessageData.Id
 
-	if valid := authenticateProtoMsg(data, data.RunImageMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.RunImageMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Unauthenticated, false, ErrNotAuthenticated))
+		return
+	}
+	if !p.availability.Available(time.Now()) {
+		log.Println("Rejecting run container request. I am out of my availability windows")
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Unavailable, true, ErrNotAvailable))
 		return
 	}
-	containerID, err := manager.GetInstance().CreateRunContainer(data.ImageID)
+	if err := validateJobSpec(data.Spec); err != nil {
+		log.Println("Rejecting run container request. Error: ", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_InvalidRequest, false, err))
+		return
+	}
+	job, err := p.newJob(requestID, data.Owner, s.Conn().RemotePeer(), data.ImageID, data.Spec)
+	if err != nil {
+		log.Println("Rejecting run container request. Error: ", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_InvalidRequest, false, err))
+		return
+	}
+	// Reserve the resources before running the job, so that no other job can take them
+	if err := p.reserve(job); err != nil {
+		log.Errorf("Rejecting run container request. Error: %s", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", capacityProtoError(err))
+		return
+	}
+	containerID, err := p.startJob(job)
 	if err != nil {
 		log.Errorf("Error crating a container. Error: %s", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Internal, false, err))
 		return
 	}
-	p.createSendResponse(s.Conn().RemotePeer(), containerID)
-	p.runningContainers[containerID] = struct{}{}
+	p.createSendResponse(s.Conn().RemotePeer(), requestID, containerID, nil)
+}
+
+// RunLocalJob runs the image imageID on this node as the job spec describes, for the account owner.
+// It returns the ID of the container.
+func (p *TaskProtocol) RunLocalJob(owner, imageID string, spec *api.JobSpec) (string, error) {
+	if err := validateJobSpec(spec); err != nil {
+		return "", err
+	}
+	job, err := p.newJob(uuid.Must(uuid.NewV4(), nil).String(), owner, p.p2pHost.ID(), imageID, spec)
+	if err != nil {
+		return "", err
+	}
+	if err := p.reserve(job); err != nil {
+		return "", err
+	}
+	return p.startJob(job)
+}
+
+// ReconcileJobs brings the jobs this node ran before it stopped up to date with their containers,
+// and tracks the jobs that still run again
+func (p *TaskProtocol) ReconcileJobs() error {
+	running, err := manager.GetInstance().ReconcileJobs(p.p2pHost.ID().Pretty())
+	if err != nil {
+		return err
+	}
+	for _, job := range running {
+		if err := p.capacity.Reserve(job.ID, job.Spec.GetRequirements()); err != nil {
+			log.Errorf("Running job %s is over the capacity of the node. Error: %s", job.ID, err)
+		}
+		p.capacity.Assign(job.ID, job.ContainerID)
+		go p.waitForJobToFinish(job.ID, job.ContainerID, jobDeadline(job, time.Unix(job.StartedTime, 0)))
+	}
+	log.Printf("Reconciled the jobs of the node, %d still running", len(running))
+	return nil
+}
+
+// validateJobSpec checks that the job spec is well formed and that the sandbox of this node allows it
+func validateJobSpec(spec *api.JobSpec) error {
+	if err := manager.ValidateJobSpec(spec); err != nil {
+		return err
+	}
+	return manager.CheckSandbox(spec)
+}
+
+// newJob records the queued job jobID this node runs for the account owner of the requester,
+// with the maximum runtime the spec asks for within the limits of the node
+func (p *TaskProtocol) newJob(jobID, owner string, requester peer.ID, imageID string, spec *api.JobSpec) (*database.Job, error) {
+	timeout, err := manager.JobTimeout(spec)
+	if err != nil {
+		return nil, err
+	}
+	job := &database.Job{ID: jobID,
+		Owner:     owner,
+		Requester: requester.Pretty(),
+		Worker:    p.p2pHost.ID().Pretty(),
+		Image:     imageID,
+		Spec:      spec,
+		Timeout:   int64(timeout / time.Second)}
+	return job, manager.CreateJob(job)
+}
+
+// jobDeadline returns the time the job that started at the time started runs out of time,
+// or the zero time if the job has no timeout
+func jobDeadline(job *database.Job, started time.Time) time.Time {
+	if job.Timeout == 0 {
+		return time.Time{}
+	}
+	return started.Add(time.Duration(job.Timeout) * time.Second)
+}
+
+// reserve reserves the resources of the queued job, and fails the job if it doesn't get them
+func (p *TaskProtocol) reserve(job *database.Job) error {
+	if err := p.capacity.Reserve(job.ID, job.Spec.GetRequirements()); err != nil {
+		manager.FailJob(job.ID, err)
+		return err
+	}
+	return nil
+}
+
+// startJob runs the job with the resources reserved for it, and tracks it until it's done.
+// It returns the ID of the container.
+func (p *TaskProtocol) startJob(job *database.Job) (string, error) {
+	containerID, err := manager.GetInstance().RunJob(job.ID)
+	if err != nil {
+		p.capacity.Release(job.ID)
+		return "", err
+	}
+	p.capacity.Assign(job.ID, containerID)
 	log.Println("Start tracking job's status...")
 
-	go p.waitForJobToFinish(containerID)
+	go p.waitForJobToFinish(job.ID, containerID, jobDeadline(job, time.Now()))
+	return containerID, nil
 }
 
-// Create and send a response to the toPeer note
-func (p *TaskProtocol) createSendResponse(toPeer peer.ID, response string) bool {
+// Create and send a response to the request requestID of the toPeer node
+// respErr is set if the request failed
+func (p *TaskProtocol) createSendResponse(toPeer peer.ID, requestID string, response string, respErr *api.Error) bool {
 	log.Printf("%s: Sending run image response to %s.", p.p2pHost.ID(), toPeer)
 
-	resp := &api.RunResponse{RunImageMsgData: NewRunImageMsgData(uuid.Must(uuid.NewV4(), nil).String(), false, p.p2pHost),
-		ContainerID: response}
+	resp := &api.RunResponse{RunImageMsgData: NewRunImageMsgData(requestID, false, p.p2pHost),
+		ContainerID: response,
+		Error:       respErr}
 
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
 	resp.RunImageMsgData.MessageData.Sign = signProtoMsg(resp, key)
@@ -133,17 +312,30 @@ func (p *TaskProtocol) createSendResponse(toPeer peer.ID, response string) bool
 	return sentOK
 }
 
-// Start tracking jobs' status
-func (p *TaskProtocol) waitForJobToFinish(containerID string) {
+// Start tracking the status of the job jobID, until its container containerID stops.
+// The job expires at the deadline, unless it's zero.
+func (p *TaskProtocol) waitForJobToFinish(jobID, containerID string, deadline time.Time) {
 	ticker := time.NewTicker(common.ContainerCheckInterval)
 	defer ticker.Stop()
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
 
 	for {
 		select {
+		case <-expired:
+			log.Printf("Job %s ran out of time. Stopping it...", jobID)
+			if _, err := manager.GetInstance().StopJob(jobID, database.JobExpired, ErrJobExpired); err != nil {
+				log.Errorf("Error stopping job %s. Error: %s", jobID, err)
+			}
 		case <-ticker.C:
 			log.Println("Checking if job's done...")
 			if !containerRunning(containerID) {
-				delete(p.runningContainers, containerID)
+				p.capacity.Release(containerID)
+				p.notifyRequester(finishJob(jobID))
 				log.Println("Job's done checking pending requests...")
 				p.Notify()
 				return
@@ -152,12 +344,37 @@ func (p *TaskProtocol) waitForJobToFinish(containerID string) {
 	}
 }
 
+// finishJob records the end of the job jobID whose container stopped, unless it was cancelled or expired,
+// and returns the job
+func finishJob(jobID string) *database.Job {
+	job, err := manager.GetInstance().FinishJob(jobID)
+	if err == database.ErrInvalidTransition {
+		job, err = database.GetJobFromDB(jobID)
+	}
+	if err != nil {
+		log.Errorf("Error recording the end of job %s. Error: %s", jobID, err)
+		return nil
+	}
+	log.Printf("Job %s is %s", jobID, job.State)
+	return job
+}
+
 // remote ping response handler
 func (p *TaskProtocol) onRunResponse(s inet.Stream) {
 	data := &api.RunResponse{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), runResponse)
+	if err != nil {
+		log.Println("Dropping run container response. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.RunImageMsgData == nil {
+		log.Println("Failed to decode run container response")
+		p.reputation.Record(s.Conn().RemotePeer(), InvalidResponse)
+		return
+	}
 
-	valid := authenticateProtoMsg(data, data.RunImageMsgData.MessageData)
+	valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.RunImageMsgData.MessageData, p.replay, p.reputation)
 
 	if !valid {
 		log.Println("Failed to authenticate message")
@@ -165,5 +382,28 @@ func (p *TaskProtocol) onRunResponse(s inet.Stream) {
 	}
 
 	log.Printf("%s: Received running image response from %s. Message id:%s.", s.Conn().LocalPeer(), s.Conn().RemotePeer(), data.RunImageMsgData.MessageData.Id)
-	p.ContainerID <- data.ContainerID
+	// Record the response even if it's late, the worker may run the job anyway
+	p.recordResponse(s.Conn().RemotePeer(), data.RunImageMsgData.MessageData.Id, data)
+	if !p.pending.resolve(data.RunImageMsgData.MessageData.Id, s.Conn().RemotePeer(), data) {
+		log.Println("No pending run request for this response. Dropping it...")
+	}
+}
+
+// recordResponse records in the job jobID that its worker runs it, or why it doesn't
+func (p *TaskProtocol) recordResponse(worker peer.ID, jobID string, resp *api.RunResponse) {
+	job, err := database.GetJobFromDB(jobID)
+	if err != nil || job.Worker != worker.Pretty() {
+		log.Println("No job was requested from this peer for this response")
+		return
+	}
+	if resp.Error != nil {
+		_, err = manager.FailJob(jobID, errors.New(resp.Error.Message))
+	} else {
+		_, err = manager.UpdateJob(jobID, database.JobRunning, func(job *database.Job) {
+			job.ContainerID = resp.ContainerID
+		})
+	}
+	if err != nil {
+		log.Errorf("Error recording the response to job %s. Error: %s", jobID, err)
+	}
 }
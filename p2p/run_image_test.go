@@ -0,0 +1,99 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+	"github.com/crowdcompute/crowdengine/database"
+	"github.com/crowdcompute/crowdengine/manager"
+	api "github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	runTestRequester, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10392, ListenAddress: "127.0.0.1"},
+	})
+	runTestWorker, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10393, ListenAddress: "127.0.0.1"},
+	})
+)
+
+func TestRunJobInvalidSpec(t *testing.T) {
+	err := runTestRequester.P2PHost.Connect(context.Background(), peerInfo(runTestWorker))
+	assert.NoError(t, err)
+	spec := &api.JobSpec{Command: []string{"python", "sweep.py"}, Env: []string{"LEARNING_RATE"}}
+	future, err := runTestRequester.RunJob(runTestWorker.P2PHost.ID(), "owner", "image", spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = future.Wait(ctx)
+	if assert.IsType(t, &ResponseError{}, err) {
+		respErr := err.(*ResponseError)
+		assert.Equal(t, api.ErrorCode_InvalidRequest, respErr.Code)
+		assert.Equal(t, manager.ErrInvalidEnv.Error(), respErr.Message)
+	}
+	// The rejected job holds no resources
+	assert.Empty(t, runTestWorker.Capacity.reservations)
+
+	// The requester records why the job failed
+	job, err := database.GetJobFromDB(future.ID())
+	if assert.NoError(t, err) {
+		assert.Equal(t, database.JobFailed, job.State)
+		assert.Equal(t, manager.ErrInvalidEnv.Error(), job.Error)
+		assert.Equal(t, "owner", job.Owner)
+		assert.Equal(t, runTestWorker.P2PHost.ID().Pretty(), job.Worker)
+	}
+}
+
+func TestRunJobOverLimits(t *testing.T) {
+	err := runTestRequester.P2PHost.Connect(context.Background(), peerInfo(runTestWorker))
+	assert.NoError(t, err)
+	// Hold every slot the requester has on the worker
+	for i := 0; i < defaultMaxConcurrent; i++ {
+		release, err := runTestWorker.Limiter.Acquire(runTestRequester.P2PHost.ID(), runRequest)
+		assert.NoError(t, err)
+		defer release()
+	}
+	spec := &api.JobSpec{Command: []string{"python", "sweep.py"}}
+	future, err := runTestRequester.RunJob(runTestWorker.P2PHost.ID(), "owner", "image", spec)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = future.Wait(ctx)
+	if assert.IsType(t, &ResponseError{}, err) {
+		respErr := err.(*ResponseError)
+		assert.Equal(t, api.ErrorCode_ResourceExhausted, respErr.Code)
+		assert.Equal(t, ErrTooManyConcurrent.Error(), respErr.Message)
+		assert.True(t, respErr.Retryable)
+	}
+	// The worker rejected the job in time, so it isn't blamed for a timeout
+	if record := runTestRequester.Reputation.Peer(runTestWorker.P2PHost.ID()).Record; record != nil {
+		assert.Zero(t, record.Timeouts)
+	}
+}
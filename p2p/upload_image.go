@@ -18,9 +18,10 @@ package p2p
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -39,14 +40,19 @@ import (
 	protocol "github.com/libp2p/go-libp2p-protocol"
 )
 
-const imageUploadRequest = "/image/uploadreq/0.0.1"
-const imageUploadResponse = "/image/uploadresp/0.0.1"
+const imageUploadRequest = "/image/uploadreq"
+const imageUploadResponse = "/image/uploadresp"
+
+// ErrInvalidUpload is returned when an upload has an invalid file name or size
+var ErrInvalidUpload = errors.New("The upload has an invalid file name or size")
 
 // UploadImageProtocol type
 type UploadImageProtocol struct {
-	p2pHost     host.Host // local host
-	ImageIDchan chan string
-	sWriter     *binStreamWriter // libp2p stream writter
+	p2pHost    host.Host        // local host
+	pending    *pendingRequests // Uploads waiting for a response
+	replay     *ReplayGuard     // Rejects stale and replayed messages
+	reputation *Reputation      // Records the outcomes of the interactions with peers
+	limiter    *Limiter         // Limits the work each peer can make the node do
 }
 
 // binStreamWriter represents the libp2p stream writter
@@ -65,34 +71,75 @@ func (w *binStreamWriter) write(chunk []byte) {
 }
 
 // NewUploadImageProtocol sets the protocol's stream handlers and returns a new UploadImageProtocol
-func NewUploadImageProtocol(p2pHost host.Host) *UploadImageProtocol {
+func NewUploadImageProtocol(p2pHost host.Host, replay *ReplayGuard, reputation *Reputation, limiter *Limiter) *UploadImageProtocol {
 	p := &UploadImageProtocol{p2pHost: p2pHost,
-		ImageIDchan: make(chan string, 1),
-		sWriter:     &binStreamWriter{},
+		pending:    newPendingRequests(reputation),
+		replay:     replay,
+		reputation: reputation,
+		limiter:    limiter,
 	}
-	p2pHost.SetStreamHandler(imageUploadRequest, p.onUploadRequest)
-	p2pHost.SetStreamHandler(imageUploadResponse, p.onUploadResponse)
+	setStreamHandler(p2pHost, imageUploadRequest, p.onUploadRequest)
+	setStreamHandler(p2pHost, imageUploadResponse, p.onUploadResponse)
 	return p
 }
 
-// SetConsistentStream sets a new stream to accept data
-func (p *UploadImageProtocol) SetConsistentStream(hostID peer.ID) error {
+// ImageUpload is an image upload to a remote peer through its own stream
+type ImageUpload struct {
+	sWriter *binStreamWriter // libp2p stream writter
+	future  *UploadFuture
+}
+
+// UploadFuture is the pending response of an image upload
+type UploadFuture struct {
+	*Future
+}
+
+// Wait waits for the ID of the image loaded by the remote peer
+func (f *UploadFuture) Wait(ctx context.Context) (string, error) {
+	resp, err := f.Future.Wait(ctx)
+	if err != nil {
+		return "", err
+	}
+	uploadResp, ok := resp.(*api.UploadImageResponse)
+	if !ok {
+		return "", ErrUnexpectedResponse
+	}
+	return uploadResp.ImageID, nil
+}
+
+// OpenUpload opens a new stream to hostID for uploading an image.
+// The request ID of the upload has to be sent along with the metadata, so that the response can be matched to the upload.
+func (p *UploadImageProtocol) OpenUpload(ctx context.Context, hostID peer.ID) (*ImageUpload, error) {
 	log.Printf("%s: Uploading image. Sending request to: %s....", p.p2pHost.ID(), hostID)
-	stream, err := p.p2pHost.NewStream(context.Background(), hostID, imageUploadRequest)
-	p.sWriter.s = stream
-	return err
+	stream, err := p.p2pHost.NewStream(ctx, hostID, protocolIDs(imageUploadRequest)...)
+	if err != nil {
+		return nil, err
+	}
+	future := p.pending.add(uuid.Must(uuid.NewV4(), nil).String(), hostID)
+	p.reputation.WorkSent(hostID)
+	return &ImageUpload{sWriter: &binStreamWriter{s: stream}, future: &UploadFuture{future}}, nil
+}
+
+// RequestID returns the request ID of the upload
+func (u *ImageUpload) RequestID() string {
+	return u.future.ID()
 }
 
 // WriteChunk writes the chunk of bytes to the stream
 // You can call this function multiple times without worrying about handling the error throughout the uploads
-// Call GetWriterError() at the end to get the error
-func (p *UploadImageProtocol) WriteChunk(chunk []byte) {
-	p.sWriter.write(chunk)
+// Call Err() at the end to get the error
+func (u *ImageUpload) WriteChunk(chunk []byte) {
+	u.sWriter.write(chunk)
+}
+
+// Err returns the error of the binary Stream Writer
+func (u *ImageUpload) Err() error {
+	return u.sWriter.err
 }
 
-// GetWriterError returns the error of the binary Stream Writer
-func (p *UploadImageProtocol) GetWriterError() error {
-	return p.sWriter.err
+// Response returns the future of the upload's response
+func (u *ImageUpload) Response() *UploadFuture {
+	return u.future
 }
 
 // remote peer requests handler
@@ -100,35 +147,60 @@ func (p *UploadImageProtocol) onUploadRequest(s inet.Stream) {
 	log.Printf("%s: Received upload request from: %s.", p.p2pHost.ID(), s.Conn().RemotePeer())
 	defer s.Reset()
 
+	// Uploads over the limits are rejected before the file is read, so that the uploader can retry them later
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), imageUploadRequest)
+	if err != nil {
+		log.Println("Rejecting upload request. Error: ", err)
+		requestID, _, _, _, _ := readMetadataFromStream(s)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_ResourceExhausted, true, err))
+		return
+	}
+	defer release()
+
 	log.Println("Start receiving the file name and file size")
 
-	fileSize, fileName, signature, hash := readMetadataFromStream(s)
+	requestID, fileSize, fileName, signature, hash := readMetadataFromStream(s)
+	if err := p.limiter.CheckUpload(s.Conn().RemotePeer(), fileSize); err != nil {
+		log.Println("Rejected upload request. Error: ", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_ResourceExhausted, false, err))
+		return
+	}
+	// The file name must not lead out of the images directory
+	if fileSize < 0 || fileName == "" || fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." {
+		log.Println("Rejected upload request. Error: ", ErrInvalidUpload)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_InvalidRequest, false, ErrInvalidUpload))
+		return
+	}
 	filePath := common.ImagesDest + fileName
-	err := createFileFromStream(s, filePath, fileSize)
-	common.FatalIfErr(err, "Couldn't read from stream when uploading a file")
+	if err := createFileFromStream(s, filePath, fileSize); err != nil {
+		log.Printf("Couldn't read from stream when uploading a file. Error: %s\n", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_Internal, true, err))
+		return
+	}
 
 	imageID, err := dockerutil.LoadImgToDockerAndStoreDB(filePath, hash, signature)
 	if errRemove := common.RemoveFile(filePath); errRemove != nil {
-		p.ImageIDchan <- errRemove.Error()
-		return
+		log.Println(errRemove)
 	}
 	if err != nil {
-		errmsg := fmt.Sprintf("There was an error loading the image. Error: %s\n", err)
-		log.Printf(errmsg)
-		// if the error is that file does not exist then no need to do anything with the error
-		p.ImageIDchan <- errmsg
+		log.Printf("There was an error loading the image. Error: %s\n", err)
+		p.createSendResponse(s.Conn().RemotePeer(), requestID, "", newProtoError(api.ErrorCode_InvalidRequest, false, err))
 		return
 	}
-	p.createSendResponse(s.Conn().RemotePeer(), imageID)
+	p.createSendResponse(s.Conn().RemotePeer(), requestID, imageID, nil)
 }
 
 // readMetadataFromStream reads the metadata from the stream s
-func readMetadataFromStream(s inet.Stream) (int64, string, string, string) {
+func readMetadataFromStream(s inet.Stream) (string, int64, string, string, string) {
+	bufferRequestID := make([]byte, common.RequestIDLength)
 	bufferFileName := make([]byte, common.FileNameLength)
 	bufferFileSize := make([]byte, common.FileSizeLength)
 	bufferSignature := make([]byte, common.SignatureLength)
 	bufferHash := make([]byte, common.HashLength)
 
+	s.Read(bufferRequestID)
+	requestID := strings.Trim(string(bufferRequestID), ":")
+
 	s.Read(bufferFileSize)
 	fileSize, _ := strconv.ParseInt(strings.Trim(string(bufferFileSize), ":"), 10, 64)
 
@@ -141,7 +213,7 @@ func readMetadataFromStream(s inet.Stream) (int64, string, string, string) {
 	s.Read(bufferHash)
 	hash := strings.Trim(string(bufferHash), ":")
 
-	return fileSize, fileName, signature, hash
+	return requestID, fileSize, fileName, signature, hash
 }
 
 // createFileFromStream reads a file's data from the stream s
@@ -183,10 +255,12 @@ func (p *UploadImageProtocol) storeImageToDB(imageID string, hash string, signat
 	return database.GetDB().Model(image).Put([]byte(imageID))
 }
 
-// createSendResponse creates and sends a response to the toPeer note
-func (p *UploadImageProtocol) createSendResponse(toPeer peer.ID, response string) bool {
-	resp := &api.UploadImageResponse{UploadImageMsgData: NewUploadImageMsgData(uuid.Must(uuid.NewV4(), nil).String(), false, p.p2pHost),
-		ImageID: response}
+// createSendResponse creates and sends a response to the upload requestID of the toPeer node
+// respErr is set if the upload failed
+func (p *UploadImageProtocol) createSendResponse(toPeer peer.ID, requestID string, response string, respErr *api.Error) bool {
+	resp := &api.UploadImageResponse{UploadImageMsgData: NewUploadImageMsgData(requestID, false, p.p2pHost),
+		ImageID: response,
+		Error:   respErr}
 
 	// sign the data
 	key := p.p2pHost.Peerstore().PrivKey(p.p2pHost.ID())
@@ -199,14 +273,26 @@ func (p *UploadImageProtocol) createSendResponse(toPeer peer.ID, response string
 // onUploadResponse is an upload response stream handler
 func (p *UploadImageProtocol) onUploadResponse(s inet.Stream) {
 	data := &api.UploadImageResponse{}
-	decodeProtoMessage(data, s)
+	release, err := p.limiter.Acquire(s.Conn().RemotePeer(), imageUploadResponse)
+	if err != nil {
+		log.Println("Dropping upload response. Error: ", err)
+		return
+	}
+	defer release()
+	if err := p.limiter.Decode(s.Conn().RemotePeer(), data, s); err != nil || data.UploadImageMsgData == nil {
+		log.Println("Failed to decode upload response")
+		p.reputation.Record(s.Conn().RemotePeer(), InvalidResponse)
+		return
+	}
 
 	// Authenticate integrity and authenticity of the message
-	if valid := authenticateProtoMsg(data, data.UploadImageMsgData.MessageData); !valid {
+	if valid := authenticatePeerMsg(s.Conn().RemotePeer(), data, data.UploadImageMsgData.MessageData, p.replay, p.reputation); !valid {
 		log.Println("Failed to authenticate message")
 		return
 	}
 	log.Printf("%s: Received upload image response from %s.", s.Conn().LocalPeer(), s.Conn().RemotePeer())
 
-	p.ImageIDchan <- data.ImageID
+	if !p.pending.resolve(data.UploadImageMsgData.MessageData.Id, s.Conn().RemotePeer(), data) {
+		log.Println("No pending upload for this response. Dropping it...")
+	}
 }
@@ -0,0 +1,184 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	host "github.com/libp2p/go-libp2p-host"
+	inet "github.com/libp2p/go-libp2p-net"
+	peer "github.com/libp2p/go-libp2p-peer"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	identify "github.com/libp2p/go-libp2p/p2p/protocol/identify"
+)
+
+// protocolVersions are the versions of each protocol the node serves, newest first.
+// A new version of a protocol is added in front of the older ones, which are only removed
+// once no node of the network depends on them anymore.
+var protocolVersions = map[protocol.ID][]string{
+	discoveryRequest:         {"0.0.1"},
+	discoveryResponse:        {"0.0.1"},
+	discoveryRelay:           {"0.0.1"},
+	runRequest:               {"0.0.1"},
+	runResponse:              {"0.0.1"},
+	cancelRequest:            {"0.0.1"},
+	cancelResponse:           {"0.0.1"},
+	jobStatus:                {"0.0.1"},
+	imageUploadRequest:       {"0.0.1"},
+	imageUploadResponse:      {"0.0.1"},
+	inspectContainerRequest:  {"0.0.1"},
+	inspectContainerResponse: {"0.0.1"},
+	imageListRequest:         {"0.0.1"},
+	imageListResponse:        {"0.0.1"},
+	containersListRequest:    {"0.0.1"},
+	containersListResponse:   {"0.0.1"},
+	joinReq:                  {"0.0.1"},
+	joinResOK:                {"0.0.1"},
+	joinReqToken:             {"0.0.1"},
+	joinResJoined:            {"0.0.1"},
+	leaveReq:                 {"0.0.1"},
+	leaveResOK:               {"0.0.1"},
+	cantLeaveRes:             {"0.0.1"},
+}
+
+func init() {
+	// Peers learn the node's client version through identify, along with the protocol versions it serves
+	identify.ClientVersion = clientVersion
+}
+
+// PeerVersions are the client version and the protocol versions of a peer, as advertised through identify
+type PeerVersions struct {
+	ID            string              `json:"id"`
+	ClientVersion string              `json:"clientVersion"`
+	Protocols     map[string][]string `json:"protocols"`  // The versions of the node's protocols the peer serves, newest first
+	Negotiated    map[string]string   `json:"negotiated"` // The highest version of each protocol both nodes serve
+}
+
+// protocolIDs returns the IDs of all the versions of the protocol name, newest first.
+// Opening a stream with them selects the highest version both nodes serve.
+func protocolIDs(name protocol.ID) []protocol.ID {
+	versions := protocolVersions[name]
+	ids := make([]protocol.ID, len(versions))
+	for i, version := range versions {
+		ids[i] = protocol.ID(string(name) + "/" + version)
+	}
+	return ids
+}
+
+// setStreamHandler sets the handler of all the versions of the protocol name.
+// The handler gets the negotiated version from the stream's protocol.
+func setStreamHandler(p2pHost host.Host, name protocol.ID, handler inet.StreamHandler) {
+	for _, id := range protocolIDs(name) {
+		p2pHost.SetStreamHandler(id, handler)
+	}
+}
+
+// SupportedVersions returns the versions of each protocol the node serves, newest first
+func SupportedVersions() map[string][]string {
+	supported := make(map[string][]string, len(protocolVersions))
+	for name, versions := range protocolVersions {
+		supported[string(name)] = append([]string{}, versions...)
+	}
+	return supported
+}
+
+// PeerVersions returns the versions the peer pID advertised through identify.
+// They are only known after the node connected to the peer.
+func (h *Host) PeerVersions(pID peer.ID) (*PeerVersions, error) {
+	protocols, err := h.P2PHost.Peerstore().GetProtocols(pID)
+	if err != nil {
+		return nil, err
+	}
+	versions := &PeerVersions{
+		ID:         pID.Pretty(),
+		Protocols:  make(map[string][]string),
+		Negotiated: make(map[string]string),
+	}
+	if agent, err := h.P2PHost.Peerstore().Get(pID, "AgentVersion"); err == nil {
+		versions.ClientVersion, _ = agent.(string)
+	}
+	for _, id := range protocols {
+		i := strings.LastIndex(id, "/")
+		if i < 0 {
+			continue
+		}
+		name := protocol.ID(id[:i])
+		if _, ok := protocolVersions[name]; ok {
+			versions.Protocols[string(name)] = append(versions.Protocols[string(name)], id[i+1:])
+		}
+	}
+	for name, theirs := range versions.Protocols {
+		sortVersions(theirs)
+		if version := highestCommonVersion(protocolVersions[protocol.ID(name)], theirs); version != "" {
+			versions.Negotiated[name] = version
+		}
+	}
+	return versions, nil
+}
+
+// ConnectedPeerVersions returns the versions of all the peers the node is connected to
+func (h *Host) ConnectedPeerVersions() []*PeerVersions {
+	peers := h.P2PHost.Network().Peers()
+	all := make([]*PeerVersions, 0, len(peers))
+	for _, pID := range peers {
+		versions, err := h.PeerVersions(pID)
+		if err != nil {
+			continue
+		}
+		all = append(all, versions)
+	}
+	return all
+}
+
+// highestCommonVersion returns the highest of the versions ours and theirs have in common, or "" if none
+func highestCommonVersion(ours, theirs []string) string {
+	highest := ""
+	for _, our := range ours {
+		for _, their := range theirs {
+			if our == their && (highest == "" || compareVersions(our, highest) > 0) {
+				highest = our
+			}
+		}
+	}
+	return highest
+}
+
+// sortVersions sorts the versions newest first
+func sortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) > 0 })
+}
+
+// compareVersions compares the dotted versions a and b number by number.
+// It returns a positive number if a is newer, negative if b is newer and 0 if they are the same.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var an, bn int
+		if i < len(as) {
+			an, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bn, _ = strconv.Atoi(bs[i])
+		}
+		if an != bn {
+			return an - bn
+		}
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crowdcompute/crowdengine/cmd/gocc/config"
+
+	inet "github.com/libp2p/go-libp2p-net"
+	protocol "github.com/libp2p/go-libp2p-protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	versionTestHost1, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10341, ListenAddress: "127.0.0.1"},
+	})
+	versionTestHost2, _ = NewHost(&config.GlobalConfig{
+		P2P: config.P2P{ListenPort: 10342, ListenAddress: "127.0.0.1"},
+	})
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.True(t, compareVersions("0.0.2", "0.0.1") > 0)
+	assert.True(t, compareVersions("0.1.0", "0.0.9") > 0)
+	assert.True(t, compareVersions("0.0.10", "0.0.9") > 0)
+	assert.True(t, compareVersions("1.0", "1.0.1") < 0)
+	assert.Equal(t, 0, compareVersions("1.0", "1.0.0"))
+
+	versions := []string{"0.0.1", "0.1.0", "0.0.2"}
+	sortVersions(versions)
+	assert.Equal(t, []string{"0.1.0", "0.0.2", "0.0.1"}, versions)
+
+	assert.Equal(t, "0.0.2", highestCommonVersion([]string{"0.1.0", "0.0.2", "0.0.1"}, []string{"0.0.2", "0.0.1"}))
+	assert.Equal(t, "", highestCommonVersion([]string{"0.1.0"}, []string{"0.0.2", "0.0.1"}))
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	const name = protocol.ID("/test/versionreq")
+	protocolVersions[name] = []string{"0.0.3", "0.0.2", "0.0.1"}
+	defer delete(protocolVersions, name)
+
+	// The remote node serves only the older versions
+	negotiated := make(chan protocol.ID, 1)
+	for _, version := range []string{"0.0.2", "0.0.1"} {
+		versionTestHost2.P2PHost.SetStreamHandler(name+"/"+protocol.ID(version), func(s inet.Stream) {
+			negotiated <- s.Protocol()
+			s.Close()
+		})
+	}
+	err := versionTestHost1.P2PHost.Connect(context.Background(), peerInfo(versionTestHost2))
+	assert.NoError(t, err)
+	s, err := versionTestHost1.P2PHost.NewStream(context.Background(), versionTestHost2.P2PHost.ID(), protocolIDs(name)...)
+	assert.NoError(t, err)
+	assert.Equal(t, name+"/0.0.2", s.Protocol())
+	s.Write([]byte{0})
+	assert.Equal(t, name+"/0.0.2", <-negotiated)
+	s.Close()
+}
+
+func TestPeerVersions(t *testing.T) {
+	err := versionTestHost1.P2PHost.Connect(context.Background(), peerInfo(versionTestHost2))
+	assert.NoError(t, err)
+
+	// Identify runs in the background after connecting
+	var versions *PeerVersions
+	for i := 0; i < 50; i++ {
+		if versions, err = versionTestHost1.PeerVersions(versionTestHost2.P2PHost.ID()); err == nil && len(versions.Negotiated) > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, clientVersion, versions.ClientVersion)
+	assert.Equal(t, []string{"0.0.1"}, versions.Protocols[runRequest])
+	assert.Equal(t, "0.0.1", versions.Negotiated[runRequest])
+	assert.Len(t, versions.Negotiated, len(protocolVersions))
+
+	all := versionTestHost1.ConnectedPeerVersions()
+	assert.Len(t, all, 1)
+	assert.Equal(t, versionTestHost2.P2PHost.ID().Pretty(), all[0].ID)
+}
@@ -41,7 +41,7 @@ var (
 // TestSetAndGetBootnodes asserts GetBootnodes returns the same no. of nodes as set by the SetBootnodes method
 func TestSetAndGetBootnodes(t *testing.T) {
 	ctx := context.Background()
-	nodeIDs := []string{testHost1.FullAddr, testHost2.FullAddr}
+	nodeIDs := []string{testHost1.FullAddr(), testHost2.FullAddr()}
 	api.SetBootnodes(ctx, nodeIDs)
 	assert.True(t, len(nodeIDs) == len(api.GetBootnodes(ctx)))
 }
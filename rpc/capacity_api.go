@@ -0,0 +1,38 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+
+	"github.com/crowdcompute/crowdengine/p2p"
+)
+
+// CapacityAPI represents the capacity RPC API
+type CapacityAPI struct {
+	host *p2p.Host
+}
+
+// NewCapacityAPI creates a new CapacityAPI
+func NewCapacityAPI(h *p2p.Host) *CapacityAPI {
+	return &CapacityAPI{host: h}
+}
+
+// GetCapacity returns the node's total resources and the resources reserved by its running jobs
+func (api *CapacityAPI) GetCapacity(ctx context.Context) p2p.Capacity {
+	return api.host.Capacity.Capacity()
+}
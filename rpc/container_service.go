@@ -70,7 +70,7 @@ func (s *ContainerService) Logs(ctx context.Context, containerid string, sinceti
 
 // Create creates a container from an image
 func (s *ContainerService) Create(ctx context.Context, imageid string) (container.ContainerCreateCreatedBody, error) {
-	container, err := manager.GetInstance().CreateContainer(imageid)
+	container, err := manager.GetInstance().CreateContainer(imageid, nil)
 	if err != nil {
 		return container, err
 	}
@@ -19,9 +19,14 @@ package rpc
 import (
 	"context"
 
+	"github.com/crowdcompute/crowdengine/common"
 	"github.com/crowdcompute/crowdengine/p2p"
+	"github.com/crowdcompute/crowdengine/p2p/protomsgs"
 )
 
+// defaultProvidersCount is the number of providers looked up when no count is given
+const defaultProvidersCount = 20
+
 // DiscoveryAPI represents the discovery RPC API
 type DiscoveryAPI struct {
 	host *p2p.Host
@@ -33,15 +38,32 @@ func NewDiscoveryAPI(h *p2p.Host) *DiscoveryAPI {
 }
 
 // Discover returns a slice of node IDs in the number of the given numberOfNodes
-func (api *DiscoveryAPI) Discover(ctx context.Context, numberOfNodes int) (string, error) {
-	initialRequest, err := api.host.GetInitialDiscoveryReq()
+// Only nodes that can fit a job with the given requirements will reply. Requirements are optional.
+func (api *DiscoveryAPI) Discover(ctx context.Context, numberOfNodes int, requirements *protomsgs.JobRequirements) (string, error) {
+	initialRequest, err := api.host.GetInitialDiscoveryReq(requirements)
 	if err != nil {
 		return "Couldn't get initial discovery request", err
 	}
 	// TODO: InitHash is a temporary solution. Should be the public key instead.
 	api.host.InitializeDiscovery(initialRequest.DiscoveryMsgData.InitHash, numberOfNodes)
-	// No neighbour sent me this message, that's why the empty string as a second parameter
-	api.host.ForwardMsgToPeers(initialRequest, "")
+	api.host.SendDiscoveryReq(initialRequest)
 
 	return "Discovering nodes...", nil
 }
+
+// FindProviders looks up in the DHT up to count nodes of a capability class and returns their capability records.
+// Classes are like "arch/amd64", "cpu/4", "memory/2048", "storage/4096", "label/gpu=nvidia" or "price/8".
+func (api *DiscoveryAPI) FindProviders(ctx context.Context, capability string, count int) ([]*protomsgs.CapabilityRecord, error) {
+	if count <= 0 {
+		count = defaultProvidersCount
+	}
+	ctx, cancel := context.WithTimeout(ctx, common.RequestTimeout)
+	defer cancel()
+	return api.host.FindProviders(ctx, capability, count)
+}
+
+// GetStats returns the discovery mode of the node, the number of discovery messages it sent and received
+// and the average latency of the responses to its requests
+func (api *DiscoveryAPI) GetStats(ctx context.Context) p2p.DiscoveryStats {
+	return api.host.DiscoveryStats()
+}
@@ -16,14 +16,14 @@ var (
 	discTestHost2, _ = p2p.NewHost(&config.GlobalConfig{
 		P2P: config.P2P{ListenPort: 10218, ListenAddress: "127.0.0.1",
 			Bootstraper: config.Bootstraper{
-				Nodes: []string{discTestHost1.FullAddr},
+				Nodes: []string{discTestHost1.FullAddr()},
 			},
 		},
 	})
 	discTestHost3, _ = p2p.NewHost(&config.GlobalConfig{
 		P2P: config.P2P{ListenPort: 10219, ListenAddress: "127.0.0.1",
 			Bootstraper: config.Bootstraper{
-				Nodes: []string{discTestHost2.FullAddr},
+				Nodes: []string{discTestHost2.FullAddr()},
 			},
 		},
 	})
@@ -33,7 +33,7 @@ var (
 // discTestHost1 added discTestHost2 because it received a message from it and
 // added discTestHost3 as part of the DHT process (dhtFindAddrAndStore method)
 func TestHostAddedPeersToPeerstore(t *testing.T) {
-	req, err := discTestHost3.GetInitialDiscoveryReq()
+	req, err := discTestHost3.GetInitialDiscoveryReq(nil)
 	if err != nil {
 		t.Errorf("Couldn't get initial discovery request")
 	}
@@ -0,0 +1,135 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/crowdcompute/crowdengine/p2p"
+	"github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// JSON-RPC error codes of the requests a remote peer failed to serve.
+// They are in the -32000 to -32099 range of the implementation defined server errors.
+const (
+	errCodeInternal          = -32010
+	errCodeInvalidRequest    = -32011
+	errCodeUnauthenticated   = -32012
+	errCodeNotFound          = -32013
+	errCodeUnavailable       = -32014
+	errCodeResourceExhausted = -32015
+	errCodeTimeout           = -32016
+)
+
+var peerErrorCodes = map[protomsgs.ErrorCode]int{
+	protomsgs.ErrorCode_Internal:          errCodeInternal,
+	protomsgs.ErrorCode_InvalidRequest:    errCodeInvalidRequest,
+	protomsgs.ErrorCode_Unauthenticated:   errCodeUnauthenticated,
+	protomsgs.ErrorCode_NotFound:          errCodeNotFound,
+	protomsgs.ErrorCode_Unavailable:       errCodeUnavailable,
+	protomsgs.ErrorCode_ResourceExhausted: errCodeResourceExhausted,
+}
+
+// PeerError is the JSON-RPC error of a request that a remote peer failed to serve
+type PeerError struct {
+	Code      int    `json:"code"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message"`
+	Retryable bool   `json:"retryable"`
+}
+
+// Error returns the reason and the message of the error, along with whether the request can be retried
+func (e *PeerError) Error() string {
+	if e.Retryable {
+		return fmt.Sprintf("%s (retryable): %s", e.Reason, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+// ErrorCode returns the JSON-RPC error code
+func (e *PeerError) ErrorCode() int {
+	return e.Code
+}
+
+// ErrorData returns the error as the data of the JSON-RPC error
+func (e *PeerError) ErrorData() interface{} {
+	return e
+}
+
+// toPeerError converts the errors of waiting for a remote peer's response to JSON-RPC errors
+// and adds them to the peer errors of the request of ctx, so that they are sent with their own code.
+// Any other error is returned as is.
+func toPeerError(ctx context.Context, err error) error {
+	var peerErr *PeerError
+	if e, ok := err.(*p2p.ResponseError); ok {
+		code, ok := peerErrorCodes[e.Code]
+		if !ok {
+			code = errCodeInternal
+		}
+		peerErr = &PeerError{Code: code, Reason: e.Code.String(), Message: e.Message, Retryable: e.Retryable}
+	} else if err == context.DeadlineExceeded {
+		peerErr = &PeerError{Code: errCodeTimeout, Reason: "Timeout", Message: "The remote peer didn't respond in time", Retryable: true}
+	} else {
+		return err
+	}
+	if errs, ok := ctx.Value(peerErrorsKey{}).(*peerErrors); ok {
+		errs.add(peerErr)
+	}
+	return peerErr
+}
+
+// peerErrorsKey is the context key of the peer errors of a JSON-RPC request
+type peerErrorsKey struct{}
+
+// peerErrors are the peer errors the API methods of a JSON-RPC request returned.
+// The RPC server only passes the messages of the errors to the codec.
+type peerErrors struct {
+	mu   sync.Mutex
+	errs map[string]*PeerError // by message
+}
+
+// add adds the peer error err
+func (p *peerErrors) add(err *PeerError) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errs[err.Error()] = err
+}
+
+// get returns the peer error with the message msg, or nil if there is none
+func (p *peerErrors) get(msg string) *PeerError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.errs[msg]
+}
+
+// peerErrorCodec sends the peer errors with their JSON-RPC code and data, instead of the generic server error
+type peerErrorCodec struct {
+	rpc.ServerCodec
+	errs *peerErrors
+}
+
+// CreateErrorResponse creates the response of the error err, which is a peer error if an API method returned one
+func (c *peerErrorCodec) CreateErrorResponse(id interface{}, err rpc.Error) interface{} {
+	if peerErr := c.errs.get(err.Error()); peerErr != nil {
+		return c.ServerCodec.CreateErrorResponseWithInfo(id, peerErr, peerErr)
+	}
+	return c.ServerCodec.CreateErrorResponse(id, err)
+}
@@ -0,0 +1,85 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/crowdcompute/crowdengine/p2p"
+	"github.com/crowdcompute/crowdengine/p2p/protomsgs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToPeerError(t *testing.T) {
+	err := toPeerError(context.Background(), &p2p.ResponseError{Code: protomsgs.ErrorCode_Unavailable, Message: "Out of availability windows", Retryable: true})
+	peerErr, ok := err.(*PeerError)
+	assert.True(t, ok)
+	assert.Equal(t, errCodeUnavailable, peerErr.ErrorCode())
+	assert.Equal(t, "Unavailable (retryable): Out of availability windows", peerErr.Error())
+
+	err = toPeerError(context.Background(), context.DeadlineExceeded)
+	assert.Equal(t, errCodeTimeout, err.(*PeerError).ErrorCode())
+
+	otherErr := errors.New("other")
+	assert.Equal(t, otherErr, toPeerError(context.Background(), otherErr))
+}
+
+type PeerErrorService struct{}
+
+func (s *PeerErrorService) Run(ctx context.Context) (string, error) {
+	return "", toPeerError(ctx, &p2p.ResponseError{Code: protomsgs.ErrorCode_ResourceExhausted, Message: "Too many requests", Retryable: true})
+}
+
+func (s *PeerErrorService) Fail(ctx context.Context) (string, error) {
+	return "", toPeerError(ctx, errors.New("other"))
+}
+
+func serveTestRequest(t *testing.T, method string) map[string]interface{} {
+	apis := []API{{Namespace: "peer", Service: &PeerErrorService{}, Public: true}}
+	body := []byte(`{"jsonrpc":"2.0","method":"` + method + `","params":[],"id":1}`)
+	req := httptest.NewRequest("POST", "/", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	ServeHTTP(apis, nil).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp struct {
+		Error map[string]interface{} `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	return resp.Error
+}
+
+func TestServePeerError(t *testing.T) {
+	rpcErr := serveTestRequest(t, "peer_run")
+	assert.Equal(t, float64(errCodeResourceExhausted), rpcErr["code"])
+	assert.Equal(t, "ResourceExhausted (retryable): Too many requests", rpcErr["message"])
+	data := rpcErr["data"].(map[string]interface{})
+	assert.Equal(t, true, data["retryable"])
+	assert.Equal(t, "ResourceExhausted", data["reason"])
+
+	// Other errors are sent as generic server errors
+	rpcErr = serveTestRequest(t, "peer_fail")
+	assert.Equal(t, float64(-32000), rpcErr["code"])
+	assert.Nil(t, rpcErr["data"])
+}
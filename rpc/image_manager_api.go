@@ -32,6 +32,7 @@ import (
 
 	"github.com/crowdcompute/crowdengine/common"
 	"github.com/crowdcompute/crowdengine/p2p"
+	"github.com/crowdcompute/crowdengine/p2p/protomsgs"
 	peer "github.com/libp2p/go-libp2p-peer"
 )
 
@@ -48,35 +49,60 @@ func NewImageManagerAPI(h *p2p.Host) *ImageManagerAPI {
 }
 
 // PushImage is the API call to push an image to the peer peerID
-func (api *ImageManagerAPI) PushImage(ctx context.Context, peerID string, imageHash string) string {
+func (api *ImageManagerAPI) PushImage(ctx context.Context, peerID string, imageHash string) (string, error) {
 	log.Println("Pushing an image to the peer : ", peerID)
 
 	file, filepath, fileSize, fileName, signature, err := api.getFileData(imageHash)
 	defer removeImage(filepath, imageHash)
 	if err != nil {
-		msg := fmt.Sprintf("Error getting file data. Error: %s", err)
-		log.Println(msg)
-		return msg
+		log.Printf("Error getting file data. Error: %s\n", err)
+		return "", err
 	}
 
 	pID, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		log.Printf("Error decoding the peer ID. Error: %s\n", err)
+		return "", err
+	}
 
 	if api.isCurrentNode(pID) {
 		// Loading the image to the current node
 		log.Println("The Peer ID given is me, I will load the image locally!")
 		log.Println(filepath)
 		imgID, err := dockerutil.LoadImgToDockerAndStoreDB(filepath, imageHash, signature)
-		common.FatalIfErr(err, "Error loading this image to the current node.")
-		return imgID
+		if err != nil {
+			log.Printf("Error loading the image to the current node. Error: %s\n", err)
+			return "", err
+		}
+		return imgID, nil
 	}
 
 	// Sending the image to a remote node
-	common.FatalIfErr(err, "Error decoding the peerID")
-	common.FatalIfErr(api.host.SetConsistentStream(pID), "Error setting a consistent steam with the remote peer")
-	api.sendFileMetadata(fillMetadata(fileSize, fileName, signature, imageHash))
-	common.FatalIfErr(api.sendFile(file), "Error sending the file to the remote peer")
+	upload, err := api.host.OpenUpload(ctx, pID)
+	if err != nil {
+		log.Printf("Error opening a stream to the remote peer. Error: %s\n", err)
+		return "", toPeerError(ctx, err)
+	}
+	requestID, fileSize, fileName, signature, hash := fillMetadata(upload.RequestID(), fileSize, fileName, signature, imageHash)
+	if err := api.sendFileMetadata(upload, requestID, fileSize, fileName, signature, hash); err != nil {
+		upload.Response().Cancel()
+		log.Printf("Error sending the file metadata to the remote peer. Error: %s\n", err)
+		return "", toPeerError(ctx, err)
+	}
+	if err := api.sendFile(upload, file); err != nil {
+		upload.Response().Cancel()
+		log.Printf("Error sending the file to the remote peer. Error: %s\n", err)
+		return "", toPeerError(ctx, err)
+	}
 
-	return <-api.host.ImageIDchan
+	ctx, cancel := context.WithTimeout(ctx, common.ImageLoadTimeout)
+	defer cancel()
+	imgID, err := upload.Response().Wait(ctx)
+	if err != nil {
+		log.Printf("Error waiting for the remote peer to load the image. Error: %s\n", err)
+		return "", toPeerError(ctx, err)
+	}
+	return imgID, nil
 }
 
 // isCurrentNode checks if the given peer ID is the current node
@@ -112,25 +138,27 @@ func (api *ImageManagerAPI) getFileData(imageHash string) (*os.File, string, str
 	return file, img.Path, strconv.FormatInt(fileInfo.Size(), 10), fileInfo.Name(), img.Signature, nil
 }
 
-func fillMetadata(fileSize, fileName, signature, imageHash string) (string, string, string, string){
+func fillMetadata(requestID, fileSize, fileName, signature, imageHash string) (string, string, string, string, string) {
+	requestIDFilled := common.FillString(requestID, common.RequestIDLength)
 	fileSizeFilled := common.FillString(fileSize, common.FileSizeLength)
 	fileNameFilled := common.FillString(fileName, common.FileNameLength)
 	signatureFilled := common.FillString(signature, common.SignatureLength)
 	hashFilled := common.FillString(imageHash, common.HashLength)
-	return fileSizeFilled, fileNameFilled, signatureFilled, hashFilled
+	return requestIDFilled, fileSizeFilled, fileNameFilled, signatureFilled, hashFilled
 }
 
-// sendFileMetadata sends the size, name, hash and signature to the peer through the opened stream
-func (api *ImageManagerAPI) sendFileMetadata(fileSize, fileName, signature, hash string) error {
-	api.host.WriteChunk([]byte(fileSize))
-	api.host.WriteChunk([]byte(fileName))
-	api.host.WriteChunk([]byte(signature))
-	api.host.WriteChunk([]byte(hash))
-	return api.host.GetWriterError()
+// sendFileMetadata sends the request ID, size, name, hash and signature to the peer through the upload's stream
+func (api *ImageManagerAPI) sendFileMetadata(upload *p2p.ImageUpload, requestID, fileSize, fileName, signature, hash string) error {
+	upload.WriteChunk([]byte(requestID))
+	upload.WriteChunk([]byte(fileSize))
+	upload.WriteChunk([]byte(fileName))
+	upload.WriteChunk([]byte(signature))
+	upload.WriteChunk([]byte(hash))
+	return upload.Err()
 }
 
-// sendFile sends the file's data to the peer through the opened stream
-func (api *ImageManagerAPI) sendFile(file *os.File) error {
+// sendFile sends the file's data to the peer through the upload's stream
+func (api *ImageManagerAPI) sendFile(upload *p2p.ImageUpload, file *os.File) error {
 	sendBuffer := make([]byte, common.FileChunk)
 	log.Println("Start sending file!")
 	for {
@@ -140,23 +168,52 @@ func (api *ImageManagerAPI) sendFile(file *os.File) error {
 		} else if err != nil {
 			return err
 		}
-		api.host.WriteChunk(sendBuffer)
+		upload.WriteChunk(sendBuffer)
 	}
 	log.Println("File has been sent, closing connection!")
-	return api.host.GetWriterError()
+	return upload.Err()
 }
 
-// RunImage is the API call to run an imageID to the peerID node
+// RunImage is the API call to run an imageID to the peerID node.
+// The job belongs to the caller's account if there is one, or to no account otherwise.
 func (api *ImageManagerAPI) RunImage(ctx context.Context, peerID, imageID string) (string, error) {
+	owner, _ := getAccountFromContext(ctx)
+	return api.runJob(ctx, owner, peerID, imageID, nil)
+}
+
+// RunJob is the API call to run an imageID to the peerID node as the job spec describes:
+// the command and its arguments, the environment, the working directory, the user, the labels
+// and the resources of the job.
+// The job belongs to the caller's account. Only the format of the spec is checked here, the node that runs
+// the job checks it against its sandbox.
+func (api *ImageManagerAPI) RunJob(ctx context.Context, peerID, imageID string, spec *protomsgs.JobSpec) (string, error) {
+	owner, err := getAccountFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return api.runJob(ctx, owner, peerID, imageID, spec)
+}
+
+// runJob runs an imageID to the peerID node as the job spec describes, for the account owner
+func (api *ImageManagerAPI) runJob(ctx context.Context, owner, peerID, imageID string, spec *protomsgs.JobSpec) (string, error) {
 	pID, _ := peer.IDB58Decode(peerID)
 	var containerID string
 	var err error
+	if err = manager.ValidateJobSpec(spec); err != nil {
+		return "", err
+	}
 	if api.isCurrentNode(pID) {
-		containerID, err = manager.GetInstance().CreateRunContainer(imageID)
+		containerID, err = api.host.RunLocalJob(owner, imageID, spec)
 	} else {
-		api.host.RunImage(pID, imageID)
-		// Check if there are any pending requests to run
-		containerID = <-api.host.ContainerID
+		var future *p2p.RunFuture
+		if future, err = api.host.RunJob(pID, owner, imageID, spec); err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(ctx, common.RequestTimeout)
+		defer cancel()
+		if containerID, err = future.Wait(ctx); err != nil {
+			return "", toPeerError(ctx, err)
+		}
 	}
 	log.Println("Image is running. Container ID: ", containerID)
 	return containerID, err
@@ -173,8 +230,15 @@ func (api *ImageManagerAPI) InspectContainer(ctx context.Context, peerID, contai
 		rawInspectionBytes, err = dockerutil.InspectContainerRaw(containerID)
 		rawInspection = string(rawInspectionBytes)
 	} else {
-		api.host.InitiateInspectRequest(pID, containerID)
-		rawInspection = <-api.host.InspectChan
+		var future *p2p.InspectFuture
+		if future, err = api.host.InitiateInspectRequest(pID, containerID); err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(ctx, common.RequestTimeout)
+		defer cancel()
+		if rawInspection, err = future.Wait(ctx); err != nil {
+			return "", toPeerError(ctx, err)
+		}
 	}
 	log.Println("Result of inspecting container: ", rawInspection)
 	return rawInspection, err
@@ -192,10 +256,17 @@ func (api *ImageManagerAPI) ListImages(ctx context.Context, peerID string) (stri
 	if api.isCurrentNode(pID) {
 		listImages, err = dockerutil.GetRawImagesForUser(hex.EncodeToString(pubBytes))
 	} else {
-		api.host.InitiateListImgRequest(pID, hex.EncodeToString(pubBytes))
-		listImages = <-api.host.ListImgChan
+		var future *p2p.ListImagesFuture
+		if future, err = api.host.InitiateListImgRequest(pID, hex.EncodeToString(pubBytes)); err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(ctx, common.RequestTimeout)
+		defer cancel()
+		if listImages, err = future.Wait(ctx); err != nil {
+			return "", toPeerError(ctx, err)
+		}
 	}
-	return listImages, nil
+	return listImages, err
 }
 
 // ListContainers gets a list of containers from a given <peerID> using the caller's publickey
@@ -211,12 +282,28 @@ func (api *ImageManagerAPI) ListContainers(ctx context.Context, peerID string) (
 	if api.isCurrentNode(pID) {
 		listCont, err = dockerutil.GetRawContainersForUser(hex.EncodeToString(pubBytes))
 	} else {
-		api.host.InitiateListContRequest(pID, hex.EncodeToString(pubBytes))
-		listCont = <-api.host.ListContChan
+		var future *p2p.ListContainersFuture
+		if future, err = api.host.InitiateListContRequest(pID, hex.EncodeToString(pubBytes)); err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(ctx, common.RequestTimeout)
+		defer cancel()
+		if listCont, err = future.Wait(ctx); err != nil {
+			return "", toPeerError(ctx, err)
+		}
 	}
 	return listCont, err
 }
 
+// getAccountFromContext returns the account of the caller, the hex public key of its key
+func getAccountFromContext(ctx context.Context) (string, error) {
+	pubBytes, err := getKeyFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(pubBytes), nil
+}
+
 func getKeyFromContext(ctx context.Context) ([]byte, error) {
 	key, ok := ctx.Value(common.ContextKeyPair).(*keystore.Key)
 	if !ok {
@@ -0,0 +1,105 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/crowdcompute/crowdengine/common"
+	"github.com/crowdcompute/crowdengine/database"
+	"github.com/crowdcompute/crowdengine/p2p"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// ErrJobNotFound is returned when the caller's account has no job with the given ID
+var ErrJobNotFound = errors.New("The job was not found")
+
+// JobAPI represents the job RPC API
+type JobAPI struct {
+	host *p2p.Host
+}
+
+// NewJobAPI creates a new RPC service with methods to follow and cancel the jobs of the caller's account
+func NewJobAPI(h *p2p.Host) *JobAPI {
+	return &JobAPI{host: h}
+}
+
+// Get returns the job jobID, if it belongs to the caller's account
+func (api *JobAPI) Get(ctx context.Context, jobID string) (*database.Job, error) {
+	owner, err := getAccountFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, err := database.GetJobFromDB(jobID)
+	if err != nil || job.Owner != owner {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Cancel cancels the job jobID of the caller's account, on the node that runs it.
+// The job ends in the cancelled state.
+func (api *JobAPI) Cancel(ctx context.Context, jobID string) error {
+	job, err := api.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Worker == api.host.P2PHost.ID().Pretty() {
+		return api.host.CancelLocalJob(jobID)
+	}
+	worker, err := peer.IDB58Decode(job.Worker)
+	if err != nil {
+		return err
+	}
+	future, err := api.host.CancelJob(worker, job.Owner, jobID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, common.RequestTimeout)
+	defer cancel()
+	return toPeerError(ctx, future.Wait(ctx))
+}
+
+// List returns the jobs of the caller's account, the most recent first
+func (api *JobAPI) List(ctx context.Context) ([]*database.Job, error) {
+	owner, err := getAccountFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := database.GetJobsFromDB()
+	if err != nil {
+		return nil, err
+	}
+	return jobsOf(jobs, owner), nil
+}
+
+// jobsOf returns the jobs of the owner, the most recent first
+func jobsOf(jobs []*database.Job, owner string) []*database.Job {
+	owned := make([]*database.Job, 0)
+	for _, job := range jobs {
+		if job.Owner == owner {
+			owned = append(owned, job)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].CreatedTime > owned[j].CreatedTime
+	})
+	return owned
+}
@@ -0,0 +1,39 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"testing"
+
+	"github.com/crowdcompute/crowdengine/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobsOf(t *testing.T) {
+	jobs := []*database.Job{
+		{ID: "old", Owner: "alice", CreatedTime: 1},
+		{ID: "other", Owner: "bob", CreatedTime: 2},
+		{ID: "new", Owner: "alice", CreatedTime: 3},
+	}
+	owned := jobsOf(jobs, "alice")
+	if assert.Len(t, owned, 2) {
+		assert.Equal(t, "new", owned[0].ID)
+		assert.Equal(t, "old", owned[1].ID)
+	}
+	assert.Empty(t, jobsOf(jobs, "carol"))
+}
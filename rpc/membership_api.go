@@ -0,0 +1,103 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+
+	"github.com/crowdcompute/crowdengine/p2p"
+	"github.com/crowdcompute/crowdengine/p2p/protomsgs"
+
+	"github.com/gogo/protobuf/proto"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// ErrNoMembership is returned when managing the members of a node that isn't in a private network with a membership list
+var ErrNoMembership = errors.New("The node has no membership list, no membership admin is configured")
+
+// MembershipAPI represents the RPC API managing the members of a private network
+type MembershipAPI struct {
+	host *p2p.Host
+}
+
+// NewMembershipAPI creates a new MembershipAPI
+func NewMembershipAPI(h *p2p.Host) *MembershipAPI {
+	return &MembershipAPI{host: h}
+}
+
+// Members returns the peer IDs of the members
+func (api *MembershipAPI) Members(ctx context.Context) ([]string, error) {
+	if api.host.Membership == nil {
+		return nil, ErrNoMembership
+	}
+	return api.host.Membership.Members(), nil
+}
+
+// AddMember adds the peer peerID to the members. Only the admin node can add members.
+func (api *MembershipAPI) AddMember(ctx context.Context, peerID string) error {
+	return api.edit(peerID, true)
+}
+
+// RemoveMember removes the peer peerID from the members and disconnects from it. Only the admin node can remove members.
+func (api *MembershipAPI) RemoveMember(ctx context.Context, peerID string) error {
+	return api.edit(peerID, false)
+}
+
+// ExportList returns the hex encoded signed membership list, to be imported by the other members
+func (api *MembershipAPI) ExportList(ctx context.Context) (string, error) {
+	if api.host.Membership == nil {
+		return "", ErrNoMembership
+	}
+	data, err := proto.Marshal(api.host.Membership.List())
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(data), nil
+}
+
+// ImportList replaces the membership list with the newer hex encoded list signed by the admin
+func (api *MembershipAPI) ImportList(ctx context.Context, list string) error {
+	if api.host.Membership == nil {
+		return ErrNoMembership
+	}
+	data, err := hex.DecodeString(list)
+	if err != nil {
+		return err
+	}
+	membershipList := &protomsgs.MembershipList{}
+	if err := proto.Unmarshal(data, membershipList); err != nil {
+		return err
+	}
+	return api.host.Membership.Update(membershipList)
+}
+
+func (api *MembershipAPI) edit(peerID string, add bool) error {
+	if api.host.Membership == nil {
+		return ErrNoMembership
+	}
+	pID, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		return err
+	}
+	priv := api.host.P2PHost.Peerstore().PrivKey(api.host.P2PHost.ID())
+	if add {
+		return api.host.Membership.Edit(priv, []peer.ID{pID}, nil)
+	}
+	return api.host.Membership.Edit(priv, nil, []peer.ID{pID})
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 The crowdcompute:crowdengine Authors
+// This file is part of the crowdcompute:crowdengine library.
+//
+// The crowdcompute:crowdengine library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The crowdcompute:crowdengine library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the crowdcompute:crowdengine library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+
+	"github.com/crowdcompute/crowdengine/p2p"
+	peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// NetworkAPI represents the p2p network RPC API
+type NetworkAPI struct {
+	host *p2p.Host
+}
+
+// NewNetworkAPI creates a new NetworkAPI
+func NewNetworkAPI(h *p2p.Host) *NetworkAPI {
+	return &NetworkAPI{host: h}
+}
+
+// GetReplayStats returns how many stale, future and replayed messages the node has rejected
+func (api *NetworkAPI) GetReplayStats(ctx context.Context) p2p.ReplayStats {
+	return api.host.Replay.Stats()
+}
+
+// GetConnections returns the peers the node is connected to along with the connection limits
+func (api *NetworkAPI) GetConnections(ctx context.Context) p2p.ConnState {
+	return api.host.ConnManager.State()
+}
+
+// GetReputations returns the reputations of all the peers the node interacted with, from the highest to the lowest score
+func (api *NetworkAPI) GetReputations(ctx context.Context) ([]p2p.PeerScore, error) {
+	return api.host.Reputation.Peers()
+}
+
+// GetReputation returns the reputation of the peer peerID
+func (api *NetworkAPI) GetReputation(ctx context.Context, peerID string) (p2p.PeerScore, error) {
+	pID, err := peer.IDB58Decode(peerID)
+	if err != nil {
+		return p2p.PeerScore{}, err
+	}
+	return api.host.Reputation.Peer(pID), nil
+}
+
+// GetProtocolVersions returns the versions of each protocol the node serves, newest first
+func (api *NetworkAPI) GetProtocolVersions(ctx context.Context) map[string][]string {
+	return p2p.SupportedVersions()
+}
+
+// GetPeerVersions returns the client and protocol versions of all the peers the node is connected to,
+// along with the protocol versions negotiated with each of them
+func (api *NetworkAPI) GetPeerVersions(ctx context.Context) []*p2p.PeerVersions {
+	return api.host.ConnectedPeerVersions()
+}
+
+// GetAddresses returns every address the node can be dialed at
+func (api *NetworkAPI) GetAddresses(ctx context.Context) []string {
+	return api.host.FullAddrs()
+}
+
+// GetStats returns the statistics of the node's network and memory usage
+func (api *NetworkAPI) GetStats(ctx context.Context) p2p.NetworkStats {
+	return api.host.Stats()
+}
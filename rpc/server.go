@@ -19,7 +19,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"strings"
 
@@ -36,7 +38,65 @@ func ServeHTTP(apis []API, ks *keystore.KeyStore) http.HandlerFunc {
 		err := server.RegisterName(api.Namespace, api.Service)
 		common.FatalIfErr(err, "Ethereum RPC could not register name.")
 	}
-	return authRequired(apis, ks, server)
+	return authRequired(apis, ks, serveJSONRPC(server))
+}
+
+// maxRequestContentLength is the maximum size of a JSON-RPC request, as in the ethereum RPC server
+const maxRequestContentLength = 1024 * 512
+
+// acceptedContentTypes are the content types of the JSON-RPC requests
+var acceptedContentTypes = []string{"application/json", "application/json-rpc", "application/jsonrequest"}
+
+// serveJSONRPC serves the JSON-RPC requests over HTTP like the ethereum RPC server does,
+// except that the peer errors the API methods return are sent with their own code and data
+func serveJSONRPC(server *rpc.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if code, err := validateRequest(r); err != nil {
+			http.Error(w, err.Error(), code)
+			return
+		}
+		errs := &peerErrors{errs: make(map[string]*PeerError)}
+		ctx := context.WithValue(r.Context(), peerErrorsKey{}, errs)
+		body := io.LimitReader(r.Body, maxRequestContentLength)
+		codec := rpc.NewJSONCodec(&httpReadWriteNopCloser{body, w})
+		defer codec.Close()
+
+		w.Header().Set("content-type", "application/json")
+		server.ServeSingleRequest(ctx, &peerErrorCodec{codec, errs}, rpc.OptionMethodInvocation)
+	}
+}
+
+// validateRequest checks the method, the size and the content type of a JSON-RPC request.
+// Returns the HTTP status code to reply with if the request is invalid
+func validateRequest(r *http.Request) (int, error) {
+	if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+		return http.StatusMethodNotAllowed, fmt.Errorf("Method not allowed")
+	}
+	if r.ContentLength > maxRequestContentLength {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("Content length too large (%d>%d)", r.ContentLength, maxRequestContentLength)
+	}
+	if r.Method == http.MethodOptions {
+		return 0, nil
+	}
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("content-type")); err == nil {
+		for _, accepted := range acceptedContentTypes {
+			if accepted == mt {
+				return 0, nil
+			}
+		}
+	}
+	return http.StatusUnsupportedMediaType, fmt.Errorf("Invalid content type, only application/json is supported")
+}
+
+// httpReadWriteNopCloser reads the request body and writes the response of a JSON-RPC codec
+type httpReadWriteNopCloser struct {
+	io.Reader
+	io.Writer
+}
+
+// Close does nothing, the HTTP server closes the request
+func (t *httpReadWriteNopCloser) Close() error {
+	return nil
 }
 
 // authRequired is a middleware for the HTTP server.